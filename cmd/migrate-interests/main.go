@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/config"
+	"github.com/smnzlnsk/routing-manager/internal/db/mongodb"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/logger"
+	mongoRepo "github.com/smnzlnsk/routing-manager/internal/repository/mongodb"
+)
+
+// migrate-interests reconciles the routing.interests collection into the jobs collection,
+// so that the routing-manager can be switched to the "jobs" interest source.
+func main() {
+	// Parse command line flags
+	configFile := flag.String("config", "", "Path to configuration file (YAML)")
+	envFile := flag.String("env-file", "", "Path to .env file")
+	dryRun := flag.Bool("dry-run", false, "Report the changes without writing them")
+	timeout := flag.Duration("timeout", time.Minute, "Timeout for the whole migration")
+	flag.Parse()
+
+	logger.Init(logger.DefaultConfig())
+	defer logger.Sync()
+
+	// Load configuration based on flags
+	var cfg *config.Config
+	var err error
+
+	configFactory := config.NewConfigLoaderFactory()
+	if *configFile != "" {
+		cfg, err = configFactory.CreateWithPath(config.YamlLoader, *configFile).Load()
+	} else {
+		cfg, err = configFactory.CreateWithPath(config.EnvLoader, *envFile).Load()
+	}
+	if err != nil {
+		logger.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	mongoClient, err := mongodb.NewClient(&cfg.MongoDB, logger.Get().Desugar())
+	if err != nil {
+		logger.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	defer mongoClient.Close(ctx)
+	// The interests of every namespace are migrated
+	ctx = domain.WithNamespace(ctx, domain.AllNamespaces)
+
+	source := mongoRepo.NewInterestRepository(mongoClient.GetDatabase("routing"), "interests", logger.Get().Desugar())
+
+	report, err := mongoRepo.ReconcileInterests(ctx, source, mongoClient.GetDatabase("jobs"), "jobs", *dryRun, logger.Get().Desugar())
+	if err != nil {
+		logger.Fatalf("Failed to migrate interests: %v", err)
+	}
+
+	logger.Infof("Interest migration finished: %d migrated, %d unchanged, %d orphaned",
+		len(report.Migrated), len(report.Unchanged), len(report.Orphaned))
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logger.Fatalf("Failed to write migration report: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
@@ -13,12 +14,21 @@ import (
 	"github.com/smnzlnsk/routing-manager/config"
 	"github.com/smnzlnsk/routing-manager/internal/api/v1/router"
 	"github.com/smnzlnsk/routing-manager/internal/db/mongodb"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
 	"github.com/smnzlnsk/routing-manager/internal/executor"
+	"github.com/smnzlnsk/routing-manager/internal/ingest"
 	"github.com/smnzlnsk/routing-manager/internal/logger"
+	"github.com/smnzlnsk/routing-manager/internal/mqtt"
+	"github.com/smnzlnsk/routing-manager/internal/normalization"
+	"github.com/smnzlnsk/routing-manager/internal/notification"
 	"github.com/smnzlnsk/routing-manager/internal/observer/implementations"
 	mongoRepo "github.com/smnzlnsk/routing-manager/internal/repository/mongodb"
+	"github.com/smnzlnsk/routing-manager/internal/rollout"
 	"github.com/smnzlnsk/routing-manager/internal/service"
+	"github.com/smnzlnsk/routing-manager/internal/smoothing"
+	"github.com/smnzlnsk/routing-manager/internal/storage"
 	"github.com/smnzlnsk/routing-manager/internal/storage/memory"
+	mongoStorage "github.com/smnzlnsk/routing-manager/internal/storage/mongodb"
 	"go.uber.org/zap"
 )
 
@@ -83,15 +93,40 @@ func main() {
 	defer cancel()
 	defer mongoClient.Close(ctx)
 
+	// Create storage for the performance metrics of the services
+	store, snapshotter := performanceStore(cfg, mongoClient, logger.Get().Desugar())
+	defer store.Close()
+
 	// Setup HTTP server and services
-	services, server := httpServerSetup(cfg, mongoClient)
+	services, server := httpServerSetup(cfg, mongoClient, store, snapshotter)
 
 	// Initialize observers for the interest state changes
 	setupObservers(cfg, services, logger.Get().Desugar())
 
+	// Connect to the MQTT broker shared by the notification channels and the metric ingestion
+	mqttClient := mqttBrokerClient(cfg, logger.Get().Desugar())
+	if mqttClient != nil {
+		defer mqttClient.Close()
+	}
+
+	// Send raised and resolved alerts to the notification channels
+	dispatcher := notificationDispatcher(cfg, mqttClient, logger.Get().Desugar())
+	services.AlertSubject.Register(dispatcher)
+
 	// Restart the services (more specifically the external task executors), if we restarted or crashed
 	services.Restart(ctx, logger.Get().Desugar())
 
+	// Follow interest changes made outside of the routing-manager
+	watchCtx, stopWatchers := context.WithCancel(context.Background())
+	defer stopWatchers()
+	services.WatchInterests(watchCtx, logger.Get().Desugar())
+	services.ExpireOverrides(watchCtx, logger.Get().Desugar())
+	services.RunResolver(watchCtx, logger.Get().Desugar())
+	services.RunReconciler(watchCtx, logger.Get().Desugar())
+	services.RunAlertRules(watchCtx, logger.Get().Desugar())
+	services.RunMetricSnapshots(watchCtx, logger.Get().Desugar())
+	runMetricSubscriber(watchCtx, cfg, mqttClient, services, logger.Get().Desugar())
+
 	go func() {
 		logger.Infof("Starting server on port %d", cfg.HTTPServer.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -99,10 +134,6 @@ func main() {
 		}
 	}()
 
-	// Create storage
-	store := memory.NewMemoryStore()
-	defer store.Close()
-
 	// Set up signal handling for graceful shutdown
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -111,9 +142,18 @@ func main() {
 	sig := <-sigCh
 	logger.Infof("Received signal %v, shutting down...", sig)
 
+	// Stop watching for interest changes before shutting down the schedulers
+	stopWatchers()
+
 	// Perform graceful shutdown of services
 	services.GracefulShutdown(ctx, logger.Get().Desugar())
 
+	// Send the notifications still waiting for their group
+	dispatcher.Stop()
+
+	// Keep the metrics of the memory store for the next start
+	services.SnapshotMetrics(context.Background(), logger.Get().Desugar())
+
 	// Shutdown server
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Fatalf("Failed to shutdown server: %v", err)
@@ -130,6 +170,7 @@ func setupObservers(cfg *config.Config, services *service.Services, logger *zap.
 		fmt.Sprintf("http://%s:%d", cfg.MonitoringManager.Host, cfg.MonitoringManager.Port),
 		5*time.Second,       // Timeout
 		services.JobService, // Pass the JobService to the executor
+		services.PerformanceStore,
 		logger,
 	)
 
@@ -138,18 +179,64 @@ func setupObservers(cfg *config.Config, services *service.Services, logger *zap.
 		logger,
 		taskExecutor,
 		1*time.Second, // Task execution interval - adjust as needed
-	)
+	).WithNamespaceQuotas(namespaceQuotas(cfg))
 
 	// Register observers with the subject
 	services.InterestSubject.Register(taskSchedulerObserver)
+	services.InterestSubject.Register(services.Smoother)
+	services.InterestSubject.Register(services.Rollout)
 
 	logger.Info("Interest observers registered successfully")
 
+	// Alerts execute the task of their app right away instead of on the next tick
+	services.AlertSubject.Register(service.NewAlertTaskObserver(services.InterestService, taskExecutor, logger))
+
 	// Store the task scheduler observer for graceful shutdown
 	services.TaskSchedulerObserver = taskSchedulerObserver
 }
 
-func httpServerSetup(cfg *config.Config, mongoClient *mongodb.Client) (*service.Services, *http.Server) {
+// performanceStore creates the configured store of the performance metrics. The memory store is restored
+// from its snapshot file and returned as snapshotter, which is nil for the other backends.
+func performanceStore(cfg *config.Config, mongoClient *mongodb.Client, log *zap.Logger) (storage.PerformanceStore, storage.Snapshotter) {
+	switch cfg.Metrics.Backend {
+	case config.MetricsBackendMongoDB:
+		store, err := mongoStorage.NewMongoStore(mongoClient.GetDatabase("routing"), "metrics", cfg.Metrics.Retention, log)
+		if err != nil {
+			logger.Fatalf("Failed to create MongoDB metrics store: %v", err)
+		}
+		return storage.NewPerformanceStore(store), nil
+	default:
+		memoryCfg := memory.Config{
+			MaxSamples:   cfg.Metrics.Memory.MaxSamples,
+			MaxAge:       cfg.Metrics.Memory.MaxAge,
+			Downsampling: make([]memory.Resolution, 0, len(cfg.Metrics.Memory.Downsampling)),
+		}
+		for _, resolution := range cfg.Metrics.Memory.Downsampling {
+			memoryCfg.Downsampling = append(memoryCfg.Downsampling, memory.Resolution{
+				Interval: resolution.Interval,
+				MaxAge:   resolution.MaxAge,
+			})
+		}
+		if err := memoryCfg.Validate(); err != nil {
+			logger.Fatalf("Invalid memory metrics store configuration: %v", err)
+		}
+		store := memory.NewMemoryStore(memoryCfg)
+
+		if path := cfg.Metrics.Memory.Snapshot.Path; path != "" {
+			if err := storage.LoadSnapshot(store, path); errors.Is(err, os.ErrNotExist) {
+				log.Info("No metrics snapshot to restore", zap.String("path", path))
+			} else if err != nil {
+				// Starting without history is better than not starting at all
+				log.Error("Failed to restore metrics snapshot", zap.String("path", path), zap.Error(err))
+			} else {
+				log.Info("Restored metrics snapshot", zap.String("path", path))
+			}
+		}
+		return storage.NewPerformanceStore(store), store
+	}
+}
+
+func httpServerSetup(cfg *config.Config, mongoClient *mongodb.Client, store storage.PerformanceStore, snapshotter storage.Snapshotter) (*service.Services, *http.Server) {
 	// Create repositories
 	repositories := mongoRepo.New(
 		&cfg.MongoDB,
@@ -158,7 +245,22 @@ func httpServerSetup(cfg *config.Config, mongoClient *mongodb.Client) (*service.
 	)
 
 	// Create services
-	services := service.New(repositories, logger.Get().Desugar())
+	services := service.New(repositories, store, service.Options{
+		NamespaceQuotas: namespaceQuotas(cfg),
+		Normalization:   normalizationConfig(cfg),
+		Smoothing:       smoothingConfig(cfg),
+		Rollout:         rolloutConfig(cfg),
+		Reconciler:      reconcilerConfig(cfg),
+		Alerts: service.AlertConfig{
+			DedupWindow: cfg.Alerts.DedupWindow,
+		},
+		AlertRules: alertRulesConfig(cfg),
+		MetricSnapshots: service.MetricSnapshotConfig{
+			Store:    snapshotter,
+			Path:     cfg.Metrics.Memory.Snapshot.Path,
+			Interval: cfg.Metrics.Memory.Snapshot.Interval,
+		},
+	}, logger.Get().Desugar())
 
 	r := router.Setup(services, logger.Get().Desugar())
 
@@ -169,3 +271,279 @@ func httpServerSetup(cfg *config.Config, mongoClient *mongodb.Client) (*service.
 
 	return services, server
 }
+
+// namespaceQuotas converts the configured namespace quotas
+func namespaceQuotas(cfg *config.Config) domain.NamespaceQuotas {
+	quotas := domain.NamespaceQuotas{
+		Default: domain.NamespaceQuota{
+			MaxInterests: cfg.Namespaces.DefaultQuota.MaxInterests,
+			MinInterval:  cfg.Namespaces.DefaultQuota.MinInterval,
+		},
+		Namespaces: make(map[string]domain.NamespaceQuota, len(cfg.Namespaces.Quotas)),
+	}
+
+	for namespace, quota := range cfg.Namespaces.Quotas {
+		quotas.Namespaces[namespace] = domain.NamespaceQuota{
+			MaxInterests: quota.MaxInterests,
+			MinInterval:  quota.MinInterval,
+		}
+	}
+
+	return quotas
+}
+
+// normalizationConfig converts the configured priority normalization strategies
+func normalizationConfig(cfg *config.Config) normalization.Config {
+	convert := func(strategy config.NormalizationStrategyConfig) normalization.StrategyConfig {
+		return normalization.StrategyConfig{
+			Strategy:         normalization.Strategy(strategy.Strategy),
+			OutlierThreshold: strategy.OutlierThreshold,
+			Temperature:      strategy.Temperature,
+		}
+	}
+
+	normalizationCfg := normalization.Config{
+		Default: convert(cfg.Routing.Normalization.Default),
+		IpTypes: make(map[domain.ServiceIpType]normalization.StrategyConfig, len(cfg.Routing.Normalization.IpTypes)),
+	}
+	for ipType, strategy := range cfg.Routing.Normalization.IpTypes {
+		normalizationCfg.IpTypes[domain.ServiceIpType(ipType)] = convert(strategy)
+	}
+
+	if err := normalizationCfg.Validate(); err != nil {
+		logger.Fatalf("Invalid normalization configuration: %v", err)
+	}
+
+	return normalizationCfg
+}
+
+// smoothingConfig converts the configured routing priority smoothing parameters
+func smoothingConfig(cfg *config.Config) smoothing.Config {
+	convert := func(params config.SmoothingParamsConfig) smoothing.Params {
+		return smoothing.Params{
+			Alpha:        params.Alpha,
+			MinChange:    params.MinChange,
+			StableCycles: params.StableCycles,
+		}
+	}
+	convertIpTypes := func(ipTypes map[string]config.SmoothingParamsConfig) map[domain.ServiceIpType]smoothing.Params {
+		result := make(map[domain.ServiceIpType]smoothing.Params, len(ipTypes))
+		for ipType, params := range ipTypes {
+			result[domain.ServiceIpType(ipType)] = convert(params)
+		}
+		return result
+	}
+
+	smoothingCfg := smoothing.Config{
+		Default: convert(cfg.Routing.Smoothing.Default),
+		IpTypes: convertIpTypes(cfg.Routing.Smoothing.IpTypes),
+		Apps:    make(map[string]smoothing.AppConfig, len(cfg.Routing.Smoothing.Apps)),
+	}
+	for appName, app := range cfg.Routing.Smoothing.Apps {
+		appCfg := smoothing.AppConfig{
+			IpTypes: convertIpTypes(app.IpTypes),
+		}
+		if app.Default != nil {
+			params := convert(*app.Default)
+			appCfg.Default = &params
+		}
+		smoothingCfg.Apps[appName] = appCfg
+	}
+
+	if err := smoothingCfg.Validate(); err != nil {
+		logger.Fatalf("Invalid smoothing configuration: %v", err)
+	}
+
+	return smoothingCfg
+}
+
+// rolloutConfig converts the configured gradual rollout parameters
+func rolloutConfig(cfg *config.Config) rollout.Config {
+	convert := func(params config.RolloutParamsConfig) rollout.Params {
+		return rollout.Params{
+			Steps:   params.Steps,
+			MaxStep: params.MaxStep,
+		}
+	}
+
+	rolloutCfg := rollout.Config{
+		Default:   convert(cfg.Routing.Rollout.Default),
+		IpTypes:   make(map[domain.ServiceIpType]rollout.Params, len(cfg.Routing.Rollout.IpTypes)),
+		AbortHold: cfg.Routing.Rollout.AbortHold,
+	}
+	for ipType, params := range cfg.Routing.Rollout.IpTypes {
+		rolloutCfg.IpTypes[domain.ServiceIpType(ipType)] = convert(params)
+	}
+
+	if err := rolloutCfg.Validate(); err != nil {
+		logger.Fatalf("Invalid rollout configuration: %v", err)
+	}
+
+	return rolloutCfg
+}
+
+// reconcilerConfig converts the configured reconciliation of interests, schedulers and jobs
+func reconcilerConfig(cfg *config.Config) service.ReconcilerConfig {
+	reconcilerCfg := service.ReconcilerConfig{
+		Interval:        cfg.Reconciler.Interval,
+		OrphanAction:    domain.OrphanAction(cfg.Reconciler.OrphanAction),
+		OrphanGrace:     cfg.Reconciler.OrphanGrace,
+		SchedulerAction: domain.SchedulerAction(cfg.Reconciler.SchedulerAction),
+	}
+
+	if err := reconcilerCfg.Validate(); err != nil {
+		logger.Fatalf("Invalid reconciler configuration: %v", err)
+	}
+
+	return reconcilerCfg
+}
+
+// alertRulesConfig converts the configured alert rules
+func alertRulesConfig(cfg *config.Config) service.AlertRulesConfig {
+	alertRulesCfg := service.AlertRulesConfig{
+		Interval: cfg.Alerts.RuleInterval,
+		Rules:    make([]service.ConfiguredAlertRule, 0, len(cfg.Alerts.Rules)),
+	}
+	for _, rule := range cfg.Alerts.Rules {
+		namespace := rule.Namespace
+		if namespace == "" {
+			namespace = domain.DefaultNamespace
+		}
+		duration := ""
+		if rule.Duration > 0 {
+			duration = rule.Duration.String()
+		}
+		alertRulesCfg.Rules = append(alertRulesCfg.Rules, service.ConfiguredAlertRule{
+			Namespace: namespace,
+			Rule: domain.AlertRuleRequest{
+				Name:       rule.Name,
+				Metric:     rule.Metric,
+				Comparator: domain.Comparator(rule.Comparator),
+				Threshold:  rule.Threshold,
+				Duration:   duration,
+				Severity:   domain.AlertSeverity(rule.Severity),
+				Apps:       rule.Apps,
+			},
+		})
+	}
+
+	if err := alertRulesCfg.Validate(); err != nil {
+		logger.Fatalf("Invalid alert rule configuration: %v", err)
+	}
+
+	return alertRulesCfg
+}
+
+// mqttBrokerClient connects to the MQTT broker if a notification channel or the metric ingestion uses it, it returns nil otherwise
+func mqttBrokerClient(cfg *config.Config, log *zap.Logger) mqtt.Client {
+	required := len(cfg.Metrics.MQTT.Topics) > 0
+	for _, channel := range cfg.Notifications.Channels {
+		if channel.Type == config.NotificationChannelMQTT {
+			required = true
+		}
+	}
+	if !required {
+		return nil
+	}
+
+	if cfg.MQTT.Broker == "" {
+		logger.Fatalf("Invalid configuration: mqtt notification channels and metric topics require an mqtt broker")
+	}
+	client, err := mqtt.NewClient(mqtt.Config{
+		Broker:   cfg.MQTT.Broker,
+		ClientID: cfg.MQTT.ClientID,
+		Username: cfg.MQTT.Username,
+		Password: cfg.MQTT.Password,
+		QoS:      byte(cfg.MQTT.QoS),
+	}, log)
+	if err != nil {
+		logger.Fatalf("Failed to connect to MQTT broker: %v", err)
+	}
+	return client
+}
+
+// runMetricSubscriber stores the metric samples published by the worker nodes until the context is done,
+// it does nothing if no metric topics are configured
+func runMetricSubscriber(ctx context.Context, cfg *config.Config, mqttClient mqtt.Client, services *service.Services, log *zap.Logger) {
+	if len(cfg.Metrics.MQTT.Topics) == 0 {
+		return
+	}
+
+	subscriber, err := ingest.NewMQTTSubscriber(mqttClient, services.MetricService, ingest.Config{
+		Topics:    cfg.Metrics.MQTT.Topics,
+		QueueSize: cfg.Metrics.MQTT.QueueSize,
+	}, log)
+	if err != nil {
+		logger.Fatalf("Invalid metric ingestion configuration: %v", err)
+	}
+	subscriber.Run(ctx)
+}
+
+// notificationDispatcher creates the configured notification channels and the dispatcher routing alerts to them.
+// The MQTT client is nil if no channel publishes to MQTT.
+func notificationDispatcher(cfg *config.Config, mqttClient mqtt.Client, log *zap.Logger) *notification.Dispatcher {
+	notifiers := make(map[string]notification.Notifier, len(cfg.Notifications.Channels))
+	for _, channel := range cfg.Notifications.Channels {
+		if channel.Name == "" {
+			logger.Fatalf("Invalid notification configuration: channel name is required")
+		}
+		if _, ok := notifiers[channel.Name]; ok {
+			logger.Fatalf("Invalid notification configuration: duplicate channel %s", channel.Name)
+		}
+
+		switch channel.Type {
+		case config.NotificationChannelWebhook:
+			if channel.URL == "" {
+				logger.Fatalf("Invalid notification configuration: channel %s requires a url", channel.Name)
+			}
+			timeout := channel.Timeout
+			if timeout <= 0 {
+				timeout = 5 * time.Second
+			}
+			notifiers[channel.Name] = notification.NewWebhookNotifier(channel.URL, channel.Headers, timeout)
+		case config.NotificationChannelMQTT:
+			if channel.Topic == "" {
+				logger.Fatalf("Invalid notification configuration: channel %s requires a topic", channel.Name)
+			}
+			notifiers[channel.Name] = notification.NewMQTTNotifier(mqttClient, channel.Topic)
+		case config.NotificationChannelFile:
+			notifier, err := notification.OpenFileNotifier(channel.Path)
+			if err != nil {
+				logger.Fatalf("Invalid notification configuration: channel %s: %v", channel.Name, err)
+			}
+			notifiers[channel.Name] = notifier
+		default:
+			logger.Fatalf("Invalid notification configuration: channel %s has invalid type %q", channel.Name, channel.Type)
+		}
+	}
+
+	notificationCfg := notification.Config{
+		GroupWait:     cfg.Notifications.GroupWait,
+		GroupInterval: cfg.Notifications.GroupInterval,
+		GroupBy:       make([]notification.GroupBy, 0, len(cfg.Notifications.GroupBy)),
+		Routes:        make([]notification.Route, 0, len(cfg.Notifications.Routes)),
+	}
+	for _, field := range cfg.Notifications.GroupBy {
+		notificationCfg.GroupBy = append(notificationCfg.GroupBy, notification.GroupBy(field))
+	}
+	for _, route := range cfg.Notifications.Routes {
+		severities := make([]domain.AlertSeverity, 0, len(route.Severities))
+		for _, severity := range route.Severities {
+			severities = append(severities, domain.AlertSeverity(severity))
+		}
+		notificationCfg.Routes = append(notificationCfg.Routes, notification.Route{
+			Channels:   route.Channels,
+			Namespaces: route.Namespaces,
+			Apps:       route.Apps,
+			Sources:    route.Sources,
+			Severities: severities,
+		})
+	}
+
+	dispatcher, err := notification.NewDispatcher(notifiers, notificationCfg, log)
+	if err != nil {
+		logger.Fatalf("Invalid notification configuration: %v", err)
+	}
+
+	return dispatcher
+}
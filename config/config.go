@@ -31,6 +31,13 @@ type Config struct {
 	ServiceManager    ServiceManagerConfig    `yaml:"service_manager"`
 	MongoDB           MongoDBConfig           `yaml:"mongodb"`
 	HTTPServer        HTTPServerConfig        `yaml:"http_server"`
+	Namespaces        NamespacesConfig        `yaml:"namespaces"`
+	Routing           RoutingConfig           `yaml:"routing"`
+	Reconciler        ReconcilerConfig        `yaml:"reconciler"`
+	Alerts            AlertsConfig            `yaml:"alerts"`
+	Notifications     NotificationsConfig     `yaml:"notifications"`
+	MQTT              MQTTConfig              `yaml:"mqtt"`
+	Metrics           MetricsConfig           `yaml:"metrics"`
 }
 
 type HTTPServerConfig struct {
@@ -49,13 +56,250 @@ type ServiceManagerConfig struct {
 	Port int    `yaml:"port"`
 }
 
+// Interest sources
+const (
+	// InterestSourceCollection keeps interests in the routing.interests collection
+	InterestSourceCollection = "collection"
+	// InterestSourceJobs derives interests from the interested_nodes of the jobs.jobs collection
+	InterestSourceJobs = "jobs"
+)
+
 // MongoDBConfig holds MongoDB configuration
 type MongoDBConfig struct {
-	Host     string        `yaml:"host"`
-	Port     int           `yaml:"port"`
-	Username string        `yaml:"username"`
-	Password string        `yaml:"password"`
-	Timeout  time.Duration `yaml:"timeout"`
+	Host           string        `yaml:"host"`
+	Port           int           `yaml:"port"`
+	Username       string        `yaml:"username"`
+	Password       string        `yaml:"password"`
+	Timeout        time.Duration `yaml:"timeout"`
+	InterestSource string        `yaml:"interest_source"`
+	// AlertRetention is how long resolved alerts are kept before they are removed
+	AlertRetention time.Duration `yaml:"alert_retention"`
+	// RoutingHistoryVersions is the number of routing snapshots kept per app, older ones are removed
+	RoutingHistoryVersions int `yaml:"routing_history_versions"`
+}
+
+// NamespacesConfig holds the per-namespace quotas
+type NamespacesConfig struct {
+	// DefaultQuota applies to every namespace without an explicit quota
+	DefaultQuota NamespaceQuotaConfig            `yaml:"default_quota"`
+	Quotas       map[string]NamespaceQuotaConfig `yaml:"quotas"`
+}
+
+// NamespaceQuotaConfig holds the quota of a single namespace
+type NamespaceQuotaConfig struct {
+	// MaxInterests caps the number of interests, 0 means unlimited. The cap is enforced per routing-manager
+	// process: replicas sharing a database check it independently, so concurrent creates on different
+	// replicas can exceed it. Run a single replica where the cap must hold strictly.
+	MaxInterests int `yaml:"max_interests"`
+	// MinInterval is the shortest scheduling interval of the namespace's interests
+	MinInterval time.Duration `yaml:"min_interval"`
+}
+
+// RoutingConfig holds the configuration of the routing priority processing
+type RoutingConfig struct {
+	Normalization NormalizationConfig `yaml:"normalization"`
+	Smoothing     SmoothingConfig     `yaml:"smoothing"`
+	Rollout       RolloutConfig       `yaml:"rollout"`
+}
+
+// NormalizationConfig holds the priority normalization strategies
+type NormalizationConfig struct {
+	// Default applies to every IpType without an explicit strategy
+	Default NormalizationStrategyConfig            `yaml:"default"`
+	IpTypes map[string]NormalizationStrategyConfig `yaml:"ip_types"`
+}
+
+// NormalizationStrategyConfig configures the normalization of a single IpType
+type NormalizationStrategyConfig struct {
+	// Strategy is one of "sum_to_one", "min_max", "softmax" or "rank"
+	Strategy string `yaml:"strategy"`
+	// OutlierThreshold clamps priorities further than this many standard deviations from the median, 0 disables it
+	OutlierThreshold float64 `yaml:"outlier_threshold"`
+	// Temperature of the softmax strategy
+	Temperature float64 `yaml:"temperature"`
+}
+
+// SmoothingConfig holds the smoothing parameters applied to normalized routing priorities.
+// Parameters are resolved from the most specific match: app and IpType, app, IpType, default.
+type SmoothingConfig struct {
+	Default SmoothingParamsConfig            `yaml:"default"`
+	IpTypes map[string]SmoothingParamsConfig `yaml:"ip_types"`
+	Apps    map[string]AppSmoothingConfig    `yaml:"apps"`
+}
+
+// AppSmoothingConfig overrides the smoothing parameters of a single app
+type AppSmoothingConfig struct {
+	Default *SmoothingParamsConfig           `yaml:"default"`
+	IpTypes map[string]SmoothingParamsConfig `yaml:"ip_types"`
+}
+
+// SmoothingParamsConfig configures the smoothing of a single series of priorities
+type SmoothingParamsConfig struct {
+	// Alpha is the EWMA weight of the newest result, 1 disables smoothing
+	Alpha float64 `yaml:"alpha"`
+	// MinChange is the smallest change of any priority that is published
+	MinChange float64 `yaml:"min_change"`
+	// StableCycles is the number of cycles a new ranking must persist before it is published
+	StableCycles int `yaml:"stable_cycles"`
+}
+
+// RolloutConfig holds the gradual rollout parameters applied to published routing priorities
+type RolloutConfig struct {
+	Default RolloutParamsConfig            `yaml:"default"`
+	IpTypes map[string]RolloutParamsConfig `yaml:"ip_types"`
+	// AbortHold is how long computed changes are ignored after a rollout was aborted by an alert
+	AbortHold time.Duration `yaml:"abort_hold"`
+}
+
+// RolloutParamsConfig configures the rollout of a single IpType
+type RolloutParamsConfig struct {
+	// Steps is the number of cycles a new target is spread over, 0 or 1 applies it at once
+	Steps int `yaml:"steps"`
+	// MaxStep caps the largest change of an instance's priority per cycle, 0 disables the cap
+	MaxStep float64 `yaml:"max_step"`
+}
+
+// ReconcilerConfig holds the configuration of the reconciliation of interests, schedulers and jobs
+type ReconcilerConfig struct {
+	// Interval between reconciliation runs
+	Interval time.Duration `yaml:"interval"`
+	// OrphanAction is applied to interests whose job is gone: "report", "suspend" or "delete"
+	OrphanAction string `yaml:"orphan_action"`
+	// OrphanGrace is how long an interest must be without its job before the orphan action is applied
+	OrphanGrace time.Duration `yaml:"orphan_grace"`
+	// SchedulerAction is applied to schedulers out of sync with the interests: "report" or "repair"
+	SchedulerAction string `yaml:"scheduler_action"`
+}
+
+// AlertsConfig holds the configuration of the alert handling
+type AlertsConfig struct {
+	// DedupWindow is how long after its last occurrence an alert collapses duplicates, a negative window disables deduplication
+	DedupWindow time.Duration `yaml:"dedup_window"`
+	// RuleInterval is how often the alert rules are evaluated
+	RuleInterval time.Duration     `yaml:"rule_interval"`
+	Rules        []AlertRuleConfig `yaml:"rules"`
+}
+
+// AlertRuleConfig defines a threshold rule raising alerts from the stored metrics
+type AlertRuleConfig struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+	Metric    string `yaml:"metric"`
+	// Comparator is one of "gt", "gte", "lt" or "lte"
+	Comparator string  `yaml:"comparator"`
+	Threshold  float64 `yaml:"threshold"`
+	// Duration is how long the metric must breach the threshold before the rule fires
+	Duration time.Duration `yaml:"duration"`
+	Severity string        `yaml:"severity"`
+	// Apps is a glob pattern selecting the apps the rule applies to, empty selects all apps
+	Apps string `yaml:"apps"`
+}
+
+// NotificationsConfig holds the channels alert notifications are sent to and the routes selecting them
+type NotificationsConfig struct {
+	// GroupBy lists the alert fields notifications are grouped by: "namespace", "app", "severity" or "source"
+	GroupBy []string `yaml:"group_by"`
+	// GroupWait is how long alerts are collected before the first notification of a group is sent
+	GroupWait time.Duration `yaml:"group_wait"`
+	// GroupInterval is the minimum time between two notifications of a group to the same channel
+	GroupInterval time.Duration               `yaml:"group_interval"`
+	Channels      []NotificationChannelConfig `yaml:"channels"`
+	Routes        []NotificationRouteConfig   `yaml:"routes"`
+}
+
+// Notification channel types
+const (
+	NotificationChannelWebhook = "webhook"
+	NotificationChannelMQTT    = "mqtt"
+	NotificationChannelFile    = "file"
+)
+
+// NotificationChannelConfig configures a single notification channel
+type NotificationChannelConfig struct {
+	Name string `yaml:"name"`
+	// Type is one of "webhook", "mqtt" or "file"
+	Type string `yaml:"type"`
+	// URL, Headers and Timeout configure webhook channels
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+	// Topic configures mqtt channels
+	Topic string `yaml:"topic"`
+	// Path configures file channels, "-" writes to stdout
+	Path string `yaml:"path"`
+}
+
+// NotificationRouteConfig sends the alerts it matches to its channels, empty matchers match every alert
+type NotificationRouteConfig struct {
+	Channels   []string `yaml:"channels"`
+	Namespaces []string `yaml:"namespaces"`
+	// Apps and Sources are glob patterns
+	Apps       string   `yaml:"apps"`
+	Sources    string   `yaml:"sources"`
+	Severities []string `yaml:"severities"`
+}
+
+// MQTTConfig holds the connection to the MQTT broker
+type MQTTConfig struct {
+	// Broker is the URL of the broker, e.g. tcp://mqtt:1883
+	Broker   string `yaml:"broker"`
+	ClientID string `yaml:"client_id"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	QoS      int    `yaml:"qos"`
+}
+
+// Metrics backends
+const (
+	// MetricsBackendMemory keeps the metrics in memory, they are lost on restart unless snapshots are configured
+	MetricsBackendMemory = "memory"
+	// MetricsBackendMongoDB keeps the metrics in the routing.metrics time-series collection
+	MetricsBackendMongoDB = "mongodb"
+)
+
+// MetricsConfig holds the configuration of the performance metrics store
+type MetricsConfig struct {
+	// Backend is one of "memory" or "mongodb"
+	Backend string `yaml:"backend"`
+	// Retention is how long samples are kept by the mongodb backend
+	Retention time.Duration       `yaml:"retention"`
+	Memory    MemoryMetricsConfig `yaml:"memory"`
+	MQTT      MQTTMetricsConfig   `yaml:"mqtt"`
+}
+
+// MQTTMetricsConfig configures the ingestion of metric samples published by the worker nodes to the MQTT broker
+type MQTTMetricsConfig struct {
+	// Topics may contain the MQTT wildcards + and #, ingestion is disabled without topics
+	Topics []string `yaml:"topics"`
+	// QueueSize bounds the messages waiting to be stored, messages arriving while the queue is full are dropped
+	QueueSize int `yaml:"queue_size"`
+}
+
+// MemoryMetricsConfig bounds the history kept per series by the memory backend
+type MemoryMetricsConfig struct {
+	// MaxSamples caps the number of raw samples kept per series
+	MaxSamples int `yaml:"max_samples"`
+	// MaxAge is how long raw samples are kept
+	MaxAge time.Duration `yaml:"max_age"`
+	// Downsampling lists the coarser resolutions older samples are aggregated into, from finest to coarsest
+	Downsampling []DownsamplingConfig `yaml:"downsampling"`
+	Snapshot     SnapshotConfig       `yaml:"snapshot"`
+}
+
+// SnapshotConfig configures the snapshots carrying the metrics of the memory backend over restarts
+type SnapshotConfig struct {
+	// Path is the gzip compressed snapshot file, it is restored on startup. Snapshots are disabled if it is empty.
+	Path string `yaml:"path"`
+	// Interval between periodic snapshots, a final snapshot is written on shutdown
+	Interval time.Duration `yaml:"interval"`
+}
+
+// DownsamplingConfig configures a resolution samples are downsampled into
+type DownsamplingConfig struct {
+	// Interval is the width of the buckets holding the minimum, maximum and average of their samples
+	Interval time.Duration `yaml:"interval"`
+	// MaxAge is how long the buckets are kept
+	MaxAge time.Duration `yaml:"max_age"`
 }
 
 type MongoDBDatabaseHandle struct {
@@ -98,6 +342,46 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("monitoring manager host is required")
 	}
 
+	for namespace, quota := range cfg.Namespaces.Quotas {
+		if quota.MaxInterests < 0 || quota.MinInterval < 0 {
+			return fmt.Errorf("invalid quota for namespace %s", namespace)
+		}
+	}
+
+	switch cfg.MongoDB.InterestSource {
+	case InterestSourceCollection, InterestSourceJobs:
+	default:
+		return fmt.Errorf("invalid interest source: %s", cfg.MongoDB.InterestSource)
+	}
+
+	if cfg.MongoDB.AlertRetention < time.Second {
+		return fmt.Errorf("alert retention must be at least one second")
+	}
+	if cfg.MongoDB.RoutingHistoryVersions < 1 {
+		return fmt.Errorf("routing history versions must be positive")
+	}
+
+	switch cfg.Metrics.Backend {
+	case MetricsBackendMemory, MetricsBackendMongoDB:
+	default:
+		return fmt.Errorf("invalid metrics backend: %s", cfg.Metrics.Backend)
+	}
+	if cfg.Metrics.Retention < time.Second {
+		return fmt.Errorf("metrics retention must be at least one second")
+	}
+
+	if cfg.Metrics.Memory.Snapshot.Path != "" && cfg.Metrics.Memory.Snapshot.Interval < time.Second {
+		return fmt.Errorf("metrics snapshot interval must be at least one second")
+	}
+
+	if len(cfg.Metrics.MQTT.Topics) > 0 && cfg.MQTT.Broker == "" {
+		return fmt.Errorf("metrics mqtt topics require an mqtt broker")
+	}
+
+	if cfg.MQTT.QoS < 0 || cfg.MQTT.QoS > 2 {
+		return fmt.Errorf("mqtt qos must be 0, 1 or 2")
+	}
+
 	return nil
 }
 
@@ -135,4 +419,82 @@ func setDefaults(cfg *Config) {
 	if cfg.MongoDB.Timeout == 0 {
 		cfg.MongoDB.Timeout = 30 * time.Second
 	}
+	if cfg.MongoDB.InterestSource == "" {
+		cfg.MongoDB.InterestSource = InterestSourceCollection
+	}
+	if cfg.MongoDB.AlertRetention == 0 {
+		cfg.MongoDB.AlertRetention = 7 * 24 * time.Hour
+	}
+	if cfg.MongoDB.RoutingHistoryVersions == 0 {
+		cfg.MongoDB.RoutingHistoryVersions = 1000
+	}
+
+	// Routing defaults
+	if cfg.Routing.Normalization.Default.Strategy == "" {
+		cfg.Routing.Normalization.Default.Strategy = "sum_to_one"
+	}
+	if cfg.Routing.Smoothing.Default.Alpha == 0 {
+		cfg.Routing.Smoothing.Default.Alpha = 1
+	}
+	if cfg.Routing.Rollout.AbortHold == 0 {
+		cfg.Routing.Rollout.AbortHold = 5 * time.Minute
+	}
+
+	// Reconciler defaults
+	if cfg.Reconciler.Interval == 0 {
+		cfg.Reconciler.Interval = 30 * time.Second
+	}
+	if cfg.Reconciler.OrphanAction == "" {
+		cfg.Reconciler.OrphanAction = "suspend"
+	}
+	if cfg.Reconciler.OrphanGrace == 0 {
+		cfg.Reconciler.OrphanGrace = time.Minute
+	}
+	if cfg.Reconciler.SchedulerAction == "" {
+		cfg.Reconciler.SchedulerAction = "repair"
+	}
+
+	// Alerts defaults
+	if cfg.Alerts.DedupWindow == 0 {
+		cfg.Alerts.DedupWindow = time.Minute
+	}
+	if cfg.Alerts.RuleInterval == 0 {
+		cfg.Alerts.RuleInterval = 15 * time.Second
+	}
+
+	// Notifications defaults
+	if cfg.Notifications.GroupWait == 0 {
+		cfg.Notifications.GroupWait = 10 * time.Second
+	}
+	if cfg.Notifications.GroupInterval == 0 {
+		cfg.Notifications.GroupInterval = time.Minute
+	}
+
+	// MQTT defaults
+	if cfg.MQTT.ClientID == "" {
+		cfg.MQTT.ClientID = "routing-manager"
+	}
+
+	// Metrics defaults
+	if cfg.Metrics.Backend == "" {
+		cfg.Metrics.Backend = MetricsBackendMemory
+	}
+	if cfg.Metrics.Retention == 0 {
+		cfg.Metrics.Retention = 24 * time.Hour
+	}
+	if cfg.Metrics.Memory.MaxSamples == 0 {
+		cfg.Metrics.Memory.MaxSamples = 3600
+	}
+	if cfg.Metrics.Memory.MaxAge == 0 {
+		cfg.Metrics.Memory.MaxAge = time.Hour
+	}
+	if cfg.Metrics.Memory.Downsampling == nil {
+		cfg.Metrics.Memory.Downsampling = []DownsamplingConfig{{Interval: time.Minute, MaxAge: 24 * time.Hour}}
+	}
+	if cfg.Metrics.Memory.Snapshot.Interval == 0 {
+		cfg.Metrics.Memory.Snapshot.Interval = 5 * time.Minute
+	}
+	if cfg.Metrics.MQTT.QueueSize == 0 {
+		cfg.Metrics.MQTT.QueueSize = 1000
+	}
 }
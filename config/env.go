@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -42,12 +43,106 @@ func (e *EnvConfigLoader) Load() (*Config, error) {
 			Port: getEnvAsInt("SERVICE_MANAGER_PORT", 10110),
 		},
 		MongoDB: MongoDBConfig{
-			Host:     getEnv("MONGODB_HOST", "cluster_mongo_net"),
-			Port:     getEnvAsInt("MONGODB_PORT", 10108),
-			Username: getEnv("MONGODB_USERNAME", ""),
-			Password: getEnv("MONGODB_PASSWORD", ""),
-			Timeout:  getEnvAsDuration("MONGODB_TIMEOUT", 10*time.Second),
+			Host:                   getEnv("MONGODB_HOST", "cluster_mongo_net"),
+			Port:                   getEnvAsInt("MONGODB_PORT", 10108),
+			Username:               getEnv("MONGODB_USERNAME", ""),
+			Password:               getEnv("MONGODB_PASSWORD", ""),
+			Timeout:                getEnvAsDuration("MONGODB_TIMEOUT", 10*time.Second),
+			InterestSource:         getEnv("MONGODB_INTEREST_SOURCE", InterestSourceCollection),
+			AlertRetention:         getEnvAsDuration("MONGODB_ALERT_RETENTION", 7*24*time.Hour),
+			RoutingHistoryVersions: getEnvAsInt("MONGODB_ROUTING_HISTORY_VERSIONS", 1000),
 		},
+		Routing: RoutingConfig{
+			Normalization: NormalizationConfig{
+				Default: NormalizationStrategyConfig{
+					Strategy:         getEnv("ROUTING_NORMALIZATION_STRATEGY", "sum_to_one"),
+					OutlierThreshold: getEnvAsFloat("ROUTING_NORMALIZATION_OUTLIER_THRESHOLD", 0),
+					Temperature:      getEnvAsFloat("ROUTING_NORMALIZATION_TEMPERATURE", 1),
+				},
+			},
+			Smoothing: SmoothingConfig{
+				Default: SmoothingParamsConfig{
+					Alpha:        getEnvAsFloat("ROUTING_SMOOTHING_ALPHA", 1),
+					MinChange:    getEnvAsFloat("ROUTING_SMOOTHING_MIN_CHANGE", 0),
+					StableCycles: getEnvAsInt("ROUTING_SMOOTHING_STABLE_CYCLES", 1),
+				},
+			},
+			Rollout: RolloutConfig{
+				Default: RolloutParamsConfig{
+					Steps:   getEnvAsInt("ROUTING_ROLLOUT_STEPS", 1),
+					MaxStep: getEnvAsFloat("ROUTING_ROLLOUT_MAX_STEP", 0),
+				},
+				AbortHold: getEnvAsDuration("ROUTING_ROLLOUT_ABORT_HOLD", 5*time.Minute),
+			},
+		},
+		Reconciler: ReconcilerConfig{
+			Interval:        getEnvAsDuration("RECONCILER_INTERVAL", 30*time.Second),
+			OrphanAction:    getEnv("RECONCILER_ORPHAN_ACTION", "suspend"),
+			OrphanGrace:     getEnvAsDuration("RECONCILER_ORPHAN_GRACE", time.Minute),
+			SchedulerAction: getEnv("RECONCILER_SCHEDULER_ACTION", "repair"),
+		},
+		Alerts: AlertsConfig{
+			DedupWindow:  getEnvAsDuration("ALERTS_DEDUP_WINDOW", time.Minute),
+			RuleInterval: getEnvAsDuration("ALERTS_RULE_INTERVAL", 15*time.Second),
+		},
+		Notifications: NotificationsConfig{
+			GroupWait:     getEnvAsDuration("NOTIFICATIONS_GROUP_WAIT", 10*time.Second),
+			GroupInterval: getEnvAsDuration("NOTIFICATIONS_GROUP_INTERVAL", time.Minute),
+		},
+		MQTT: MQTTConfig{
+			Broker:   getEnv("MQTT_BROKER", ""),
+			ClientID: getEnv("MQTT_CLIENT_ID", "routing-manager"),
+			Username: getEnv("MQTT_USERNAME", ""),
+			Password: getEnv("MQTT_PASSWORD", ""),
+			QoS:      getEnvAsInt("MQTT_QOS", 0),
+		},
+		Metrics: MetricsConfig{
+			Backend:   getEnv("METRICS_BACKEND", MetricsBackendMemory),
+			Retention: getEnvAsDuration("METRICS_RETENTION", 24*time.Hour),
+			Memory: MemoryMetricsConfig{
+				MaxSamples: getEnvAsInt("METRICS_MEMORY_MAX_SAMPLES", 3600),
+				MaxAge:     getEnvAsDuration("METRICS_MEMORY_MAX_AGE", time.Hour),
+				Snapshot: SnapshotConfig{
+					Path:     getEnv("METRICS_MEMORY_SNAPSHOT_PATH", ""),
+					Interval: getEnvAsDuration("METRICS_MEMORY_SNAPSHOT_INTERVAL", 5*time.Minute),
+				},
+			},
+			MQTT: MQTTMetricsConfig{
+				QueueSize: getEnvAsInt("METRICS_MQTT_QUEUE_SIZE", 1000),
+			},
+		},
+		Namespaces: NamespacesConfig{
+			DefaultQuota: NamespaceQuotaConfig{
+				MaxInterests: getEnvAsInt("NAMESPACE_MAX_INTERESTS", 0),
+				MinInterval:  getEnvAsDuration("NAMESPACE_MIN_INTERVAL", 0),
+			},
+		},
+	}
+
+	// A single downsampling resolution can be configured from the environment, a zero interval disables downsampling
+	if interval := getEnvAsDuration("METRICS_MEMORY_DOWNSAMPLING_INTERVAL", time.Minute); interval > 0 {
+		cfg.Metrics.Memory.Downsampling = []DownsamplingConfig{{
+			Interval: interval,
+			MaxAge:   getEnvAsDuration("METRICS_MEMORY_DOWNSAMPLING_MAX_AGE", 24*time.Hour),
+		}}
+	}
+
+	// Metric topics are separated by commas
+	for _, topic := range strings.Split(getEnv("METRICS_MQTT_TOPICS", ""), ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			cfg.Metrics.MQTT.Topics = append(cfg.Metrics.MQTT.Topics, topic)
+		}
+	}
+
+	// A single webhook receiving every alert is the only channel configurable from the environment
+	if url := getEnv("NOTIFICATIONS_WEBHOOK_URL", ""); url != "" {
+		cfg.Notifications.Channels = []NotificationChannelConfig{{
+			Name:    "webhook",
+			Type:    NotificationChannelWebhook,
+			URL:     url,
+			Timeout: getEnvAsDuration("NOTIFICATIONS_WEBHOOK_TIMEOUT", 5*time.Second),
+		}}
+		cfg.Notifications.Routes = []NotificationRouteConfig{{Channels: []string{"webhook"}}}
 	}
 
 	// Validate configuration
@@ -82,6 +177,21 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsFloat gets an environment variable as a float or returns a default value
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
 // getEnvAsBool gets an environment variable as a boolean or returns a default value
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := getEnv(key, "")
@@ -1,9 +1,9 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
 	"github.com/smnzlnsk/routing-manager/internal/domain"
 	"github.com/smnzlnsk/routing-manager/internal/service"
@@ -23,17 +23,22 @@ func NewAlertHandler(service service.AlertService, logger *zap.Logger) *AlertHan
 }
 
 func (h *AlertHandler) HandleAlert(w http.ResponseWriter, r *http.Request) {
-	var req domain.Alert
+	var req domain.AlertRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeAndValidate(w, r, &req); err != nil {
 		response.Error(w, err, http.StatusBadRequest)
 		return
 	}
 
 	h.logger.Info("Handling alert", zap.Any("request", req))
 
-	err := h.service.HandleAlert(r.Context(), &domain.Alert{
-		AppName: req.AppName,
+	alert, err := h.service.HandleAlert(r.Context(), &domain.Alert{
+		AppName:        req.AppName,
+		Severity:       req.Severity,
+		Source:         req.Source,
+		InstanceNumber: req.InstanceNumber,
+		IpType:         req.IpType,
+		Message:        req.Message,
 	})
 	if err != nil {
 		h.logger.Error("Error handling alert", zap.Error(err))
@@ -41,5 +46,90 @@ func (h *AlertHandler) HandleAlert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.JSON(w, nil, http.StatusOK)
+	// Duplicates are collapsed into the alert they repeat
+	status := http.StatusCreated
+	if alert.Count > 1 {
+		status = http.StatusOK
+	}
+	response.JSON(w, alert, status)
+}
+
+// List returns the alerts matching the appName, state, severity, since and until query parameters
+func (h *AlertHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.AlertFilter{
+		AppName:  query.Get("appName"),
+		State:    domain.AlertState(query.Get("state")),
+		Severity: domain.AlertSeverity(query.Get("severity")),
+	}
+	var err error
+	if filter.Since, err = optionalTimeValue("since", query.Get("since")); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = optionalTimeValue("until", query.Get("until")); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := filter.Validate(); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	alerts, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("Error listing alerts", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, alerts, http.StatusOK)
+}
+
+func (h *AlertHandler) Get(w http.ResponseWriter, r *http.Request) {
+	alert, err := h.service.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Debug("Error getting alert", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, alert, http.StatusOK)
+}
+
+// Acknowledge marks a firing alert as acknowledged by the requester
+func (h *AlertHandler) Acknowledge(w http.ResponseWriter, r *http.Request) {
+	var req domain.AlertTransitionRequest
+	if err := decodeAndValidate(w, r, &req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	alert, err := h.service.Acknowledge(r.Context(), chi.URLParam(r, "id"), req.By)
+	if err != nil {
+		h.logger.Debug("Error acknowledging alert", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, alert, http.StatusOK)
+}
+
+// Resolve marks a firing or acknowledged alert as resolved by the requester
+func (h *AlertHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	var req domain.AlertTransitionRequest
+	if err := decodeAndValidate(w, r, &req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	alert, err := h.service.Resolve(r.Context(), chi.URLParam(r, "id"), req.By)
+	if err != nil {
+		h.logger.Debug("Error resolving alert", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, alert, http.StatusOK)
 }
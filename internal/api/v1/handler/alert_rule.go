@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/service"
+	"go.uber.org/zap"
+)
+
+type AlertRuleHandler struct {
+	service service.AlertRuleService
+	logger  *zap.Logger
+}
+
+func NewAlertRuleHandler(service service.AlertRuleService, logger *zap.Logger) *AlertRuleHandler {
+	return &AlertRuleHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *AlertRuleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req domain.AlertRuleRequest
+	if err := decodeAndValidate(w, r, &req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.Create(r.Context(), &req)
+	if err != nil {
+		h.logger.Error("Error creating alert rule", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, rule, http.StatusCreated)
+}
+
+func (h *AlertRuleHandler) List(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.List(r.Context())
+	if err != nil {
+		h.logger.Error("Error listing alert rules", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, rules, http.StatusOK)
+}
+
+func (h *AlertRuleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.service.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Debug("Error getting alert rule", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, rule, http.StatusOK)
+}
+
+func (h *AlertRuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		h.logger.Debug("Error deleting alert rule", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, nil, http.StatusOK)
+}
+
+// State returns the evaluation state of the rules for every app they apply to
+func (h *AlertRuleHandler) State(w http.ResponseWriter, r *http.Request) {
+	states, err := h.service.State(r.Context())
+	if err != nil {
+		h.logger.Error("Error getting alert rule state", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, states, http.StatusOK)
+}
@@ -1,10 +1,8 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
 	"github.com/smnzlnsk/routing-manager/internal/domain"
 	"github.com/smnzlnsk/routing-manager/internal/service"
@@ -26,7 +24,7 @@ func NewInterestHandler(service service.InterestService, logger *zap.Logger) *In
 func (h *InterestHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req domain.InterestRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeAndValidate(w, r, &req); err != nil {
 		response.Error(w, err, http.StatusBadRequest)
 		return
 	}
@@ -36,6 +34,7 @@ func (h *InterestHandler) Create(w http.ResponseWriter, r *http.Request) {
 	interest, err := h.service.Create(r.Context(), &domain.Interest{
 		AppName:   req.AppName,
 		ServiceIp: req.ServiceIp,
+		Policies:  req.Policies,
 	})
 	if err != nil {
 		h.logger.Error("Error creating interest", zap.Error(err))
@@ -43,11 +42,16 @@ func (h *InterestHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", etag(interest.Version))
 	response.JSON(w, interest, http.StatusCreated)
 }
 
 func (h *InterestHandler) GetByAppName(w http.ResponseWriter, r *http.Request) {
-	appName := chi.URLParam(r, "appName")
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
 
 	interest, err := h.service.GetByAppName(r.Context(), appName)
 	if err != nil {
@@ -55,11 +59,16 @@ func (h *InterestHandler) GetByAppName(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", etag(interest.Version))
 	response.JSON(w, interest, http.StatusOK)
 }
 
 func (h *InterestHandler) GetByServiceIp(w http.ResponseWriter, r *http.Request) {
-	serviceIp := chi.URLParam(r, "serviceIp")
+	serviceIp, err := serviceIpParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
 
 	interest, err := h.service.GetByServiceIp(r.Context(), serviceIp)
 	if err != nil {
@@ -67,6 +76,47 @@ func (h *InterestHandler) GetByServiceIp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	w.Header().Set("ETag", etag(interest.Version))
+	response.JSON(w, interest, http.StatusOK)
+}
+
+func (h *InterestHandler) Update(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req domain.InterestRequest
+	if err := decodeAndValidate(w, r, &req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.AppName != appName {
+		response.Error(w, domain.NewValidationError("appname", "must match the app name in the path"), http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("Updating interest", zap.Any("request", req), zap.Int64("expectedVersion", expectedVersion))
+
+	interest, err := h.service.Update(r.Context(), &domain.Interest{
+		AppName:   req.AppName,
+		ServiceIp: req.ServiceIp,
+		Policies:  req.Policies,
+	}, expectedVersion)
+	if err != nil {
+		h.logger.Error("Error updating interest", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag(interest.Version))
 	response.JSON(w, interest, http.StatusOK)
 }
 
@@ -81,9 +131,19 @@ func (h *InterestHandler) List(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *InterestHandler) DeleteByAppName(w http.ResponseWriter, r *http.Request) {
-	appName := chi.URLParam(r, "appName")
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
 
-	err := h.service.DeleteByAppName(r.Context(), appName)
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DeleteByAppName(r.Context(), appName, expectedVersion)
 	if err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
@@ -93,9 +153,19 @@ func (h *InterestHandler) DeleteByAppName(w http.ResponseWriter, r *http.Request
 }
 
 func (h *InterestHandler) DeleteByServiceIp(w http.ResponseWriter, r *http.Request) {
-	serviceIp := chi.URLParam(r, "serviceIp")
+	serviceIp, err := serviceIpParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
 
-	err := h.service.DeleteByServiceIp(r.Context(), serviceIp)
+	err = h.service.DeleteByServiceIp(r.Context(), serviceIp, expectedVersion)
 	if err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
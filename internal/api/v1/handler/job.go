@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/service"
+	"go.uber.org/zap"
+)
+
+type JobHandler struct {
+	service service.JobService
+	logger  *zap.Logger
+}
+
+func NewJobHandler(service service.JobService, logger *zap.Logger) *JobHandler {
+	return &JobHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// List returns the jobs matching the ipType, minInstances and maxInstances query parameters.
+// With watch=true the job changes are streamed as newline delimited JSON events instead.
+func (h *JobHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.JobFilter{IpType: domain.ServiceIpType(query.Get("ipType"))}
+	var err error
+	if filter.MinInstances, err = optionalIntValue("minInstances", query.Get("minInstances")); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if filter.MaxInstances, err = optionalIntValue("maxInstances", query.Get("maxInstances")); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := filter.Validate(); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	watch := false
+	if value := query.Get("watch"); value != "" {
+		if watch, err = strconv.ParseBool(value); err != nil {
+			response.Error(w, domain.NewValidationError("watch", "must be a boolean"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if watch {
+		h.watch(w, r, filter)
+		return
+	}
+
+	jobs, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("Error listing jobs", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, jobs, http.StatusOK)
+}
+
+func (h *JobHandler) GetByJobName(w http.ResponseWriter, r *http.Request) {
+	jobName := chi.URLParam(r, "name")
+	if err := domain.ValidateAppName(jobName); err != nil {
+		response.Error(w, domain.NewValidationError("name", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.service.GetByJobName(r.Context(), jobName)
+	if err != nil {
+		h.logger.Error("Error getting job", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, job, http.StatusOK)
+}
+
+// watch streams the job events passing the filter until the client disconnects
+func (h *JobHandler) watch(w http.ResponseWriter, r *http.Request, filter domain.JobFilter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.Error(w, domain.NewValidationError("watch", "streaming is not supported"), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	for event := range h.service.Watch(r.Context()) {
+		if !filter.Matches(event.Job) {
+			continue
+		}
+		if err := encoder.Encode(event); err != nil {
+			h.logger.Debug("Job watcher disconnected", zap.Error(err))
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/service"
+	"go.uber.org/zap"
+)
+
+type MetricHandler struct {
+	service service.MetricService
+	logger  *zap.Logger
+}
+
+func NewMetricHandler(service service.MetricService, logger *zap.Logger) *MetricHandler {
+	return &MetricHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Ingest stores a single sample or an array of samples
+func (h *MetricHandler) Ingest(w http.ResponseWriter, r *http.Request) {
+	var samples domain.MetricSamples
+	if err := decodeAndValidate(w, r, &samples); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Ingest(r.Context(), samples); err != nil {
+		h.logger.Error("Error ingesting metric samples", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, nil, http.StatusAccepted)
+}
+
+// List returns the latest value of every series selected by the metric, appName and match query parameters
+func (h *MetricHandler) List(w http.ResponseWriter, r *http.Request) {
+	selector, err := metricSelector(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	values, err := h.service.Latest(r.Context(), selector)
+	if err != nil {
+		h.logger.Error("Error listing metrics", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, values, http.StatusOK)
+}
+
+// SelectHistory returns the recorded values of every series selected by the metric, appName and match query parameters
+func (h *MetricHandler) SelectHistory(w http.ResponseWriter, r *http.Request) {
+	selector, err := metricSelector(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	histories, err := h.service.History(r.Context(), selector)
+	if err != nil {
+		h.logger.Error("Error selecting metric history", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, histories, http.StatusOK)
+}
+
+// Aggregate aggregates the values of the series selected by the metric, appName and match query parameters
+// within the window. The func, window, by and alpha query parameters define the aggregation, by may be repeated.
+func (h *MetricHandler) Aggregate(w http.ResponseWriter, r *http.Request) {
+	selector, err := metricSelector(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	values := r.URL.Query()
+	aggregation := &domain.MetricAggregation{
+		Selector: *selector,
+		Func:     domain.MetricAggregateFunc(values.Get("func")),
+		GroupBy:  values["by"],
+	}
+	if window := values.Get("window"); window != "" {
+		if aggregation.Window, err = time.ParseDuration(window); err != nil {
+			response.Error(w, domain.NewValidationError("window", "must be a duration"), http.StatusBadRequest)
+			return
+		}
+	}
+	if alpha := values.Get("alpha"); alpha != "" {
+		if aggregation.Alpha, err = strconv.ParseFloat(alpha, 64); err != nil {
+			response.Error(w, domain.NewValidationError("alpha", "must be a number"), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := aggregation.Validate(); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	aggregates, err := h.service.Aggregate(r.Context(), aggregation)
+	if err != nil {
+		h.logger.Error("Error aggregating metrics", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, aggregates, http.StatusOK)
+}
+
+// Get returns the latest value of a metric of an app or one of its instances without further labels
+func (h *MetricHandler) Get(w http.ResponseWriter, r *http.Request) {
+	query, err := metricQuery(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	value, err := h.service.Get(r.Context(), query)
+	if err != nil {
+		h.logger.Debug("Error getting metric", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, value, http.StatusOK)
+}
+
+// History returns the recorded values of a metric of an app or one of its instances without further labels, newest first
+func (h *MetricHandler) History(w http.ResponseWriter, r *http.Request) {
+	query, err := metricQuery(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.GetHistory(r.Context(), query)
+	if err != nil {
+		h.logger.Debug("Error getting metric history", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, history, http.StatusOK)
+}
+
+// metricSelector reads the series and range of a metric request from the metric, appName, match, since and limit
+// query parameters. The match parameter may be repeated.
+func metricSelector(r *http.Request) (*domain.MetricSelector, error) {
+	values := r.URL.Query()
+
+	selector := &domain.MetricSelector{
+		Metric:   values.Get("metric"),
+		AppName:  values.Get("appName"),
+		Matchers: values["match"],
+	}
+	var err error
+	if selector.Since, err = optionalTimeValue("since", values.Get("since")); err != nil {
+		return nil, err
+	}
+	limit, err := optionalIntValue("limit", values.Get("limit"))
+	if err != nil {
+		return nil, err
+	}
+	if limit != nil {
+		selector.Limit = *limit
+	}
+
+	return selector, selector.Validate()
+}
+
+// metricQuery reads the series and range of a metric request from the appName URL parameter
+// and the metric, instance, since and limit query parameters
+func metricQuery(r *http.Request) (*domain.MetricQuery, error) {
+	values := r.URL.Query()
+
+	query := &domain.MetricQuery{
+		AppName: chi.URLParam(r, "appName"),
+		Metric:  values.Get("metric"),
+	}
+	var err error
+	if query.InstanceNumber, err = optionalIntValue("instance", values.Get("instance")); err != nil {
+		return nil, err
+	}
+	if query.Since, err = optionalTimeValue("since", values.Get("since")); err != nil {
+		return nil, err
+	}
+	limit, err := optionalIntValue("limit", values.Get("limit"))
+	if err != nil {
+		return nil, err
+	}
+	if limit != nil {
+		query.Limit = *limit
+	}
+
+	return query, query.Validate()
+}
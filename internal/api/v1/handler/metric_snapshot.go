@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/service"
+	"go.uber.org/zap"
+)
+
+// maxSnapshotBodyBytes limits the size of uploaded snapshots, their decompressed size is limited by the store
+const maxSnapshotBodyBytes = 256 << 20
+
+type MetricSnapshotHandler struct {
+	service service.MetricSnapshotService
+	logger  *zap.Logger
+}
+
+func NewMetricSnapshotHandler(service service.MetricSnapshotService, logger *zap.Logger) *MetricSnapshotHandler {
+	return &MetricSnapshotHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Snapshot writes the metrics kept in memory to the snapshot file
+func (h *MetricSnapshotHandler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.service.Snapshot(r.Context())
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, snapshot, http.StatusOK)
+}
+
+// Restore replaces the metrics kept in memory with those of the snapshot file,
+// or with those of the exported snapshot sent as request body
+func (h *MetricSnapshotHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	var snapshot *domain.MetricSnapshot
+	var err error
+	if r.ContentLength != 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSnapshotBodyBytes)
+		snapshot, err = h.service.Import(r.Context(), r.Body)
+	} else {
+		snapshot, err = h.service.Restore(r.Context())
+	}
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, snapshot, http.StatusOK)
+}
+
+// Export sends a snapshot of the metrics kept in memory as gzip compressed JSON
+func (h *MetricSnapshotHandler) Export(w http.ResponseWriter, r *http.Request) {
+	// The snapshot is buffered so that failures can still be reported as error response
+	var buf bytes.Buffer
+	if err := h.service.Export(r.Context(), &buf); err != nil {
+		h.logger.Error("Error exporting metrics snapshot", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("metrics-%s.json.gz", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		h.logger.Debug("Error sending metrics snapshot", zap.Error(err))
+	}
+}
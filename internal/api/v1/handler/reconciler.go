@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
+	"github.com/smnzlnsk/routing-manager/internal/service"
+	"go.uber.org/zap"
+)
+
+type ReconcilerHandler struct {
+	service service.ReconcilerService
+	logger  *zap.Logger
+}
+
+func NewReconcilerHandler(service service.ReconcilerService, logger *zap.Logger) *ReconcilerHandler {
+	return &ReconcilerHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Report returns the drift found by the latest reconciliation of interests, schedulers and jobs
+func (h *ReconcilerHandler) Report(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.Report(r.Context())
+	if err != nil {
+		h.logger.Debug("Error getting reconciliation report", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, report, http.StatusOK)
+}
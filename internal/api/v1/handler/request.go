@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+// maxRequestBodyBytes limits the size of accepted request bodies
+const maxRequestBodyBytes = 1 << 20
+
+// validatable is implemented by request DTOs that can check themselves
+type validatable interface {
+	Validate() error
+}
+
+// decodeAndValidate strictly decodes the JSON request body into dst and validates it.
+// Unknown fields, trailing data and oversized bodies are rejected.
+// All returned errors are *domain.ValidationError.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, dst validatable) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return domain.NewValidationError("body", "must contain a single JSON object")
+	}
+
+	return dst.Validate()
+}
+
+// decodeError translates a JSON decoding error into a validation error
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return domain.NewValidationError("body", fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		return domain.NewValidationError(typeErr.Field, fmt.Sprintf("must be of type %s", typeErr.Type))
+	case errors.As(err, &maxBytesErr):
+		return domain.NewValidationError("body", fmt.Sprintf("must not exceed %d bytes", maxBytesErr.Limit))
+	case errors.Is(err, io.EOF):
+		return domain.NewValidationError("body", "must not be empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return domain.NewValidationError("body", "malformed JSON")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return domain.NewValidationError(field, "unknown field")
+	default:
+		return domain.NewValidationError("body", err.Error())
+	}
+}
+
+// appNameParam returns the validated appName URL parameter
+func appNameParam(r *http.Request) (string, error) {
+	appName := chi.URLParam(r, "appName")
+	if err := domain.ValidateAppName(appName); err != nil {
+		return "", domain.NewValidationError("appName", err.Error())
+	}
+	return appName, nil
+}
+
+// serviceIpParam returns the validated serviceIp URL parameter
+func serviceIpParam(r *http.Request) (string, error) {
+	serviceIp := chi.URLParam(r, "serviceIp")
+	if _, err := domain.ParseServiceIp(serviceIp); err != nil {
+		return "", domain.NewValidationError("serviceIp", err.Error())
+	}
+	return serviceIp, nil
+}
+
+// etag formats an interest version as a strong entity tag
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ifMatchVersion returns the version expected by the If-Match header.
+// A missing header or "*" yields domain.AnyVersion. Only a single entity tag is supported,
+// tags that do not denote a version can never match and yield domain.ErrPreconditionFailed.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return domain.AnyVersion, nil
+	}
+	if strings.Contains(ifMatch, ",") {
+		return 0, domain.NewValidationError("If-Match", "must contain a single entity tag")
+	}
+
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(ifMatch, "W/"))
+	if err != nil {
+		return 0, domain.NewValidationError("If-Match", "must be a quoted entity tag")
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 0 {
+		return 0, domain.ErrPreconditionFailed
+	}
+
+	return version, nil
+}
+
+// versionValue parses a routing snapshot version taken from the given field
+func versionValue(field, value string) (int64, error) {
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, domain.NewValidationError(field, "must be a positive integer")
+	}
+	return version, nil
+}
+
+// optionalIntValue parses an optional non-negative integer query parameter, nil if it is absent
+func optionalIntValue(field, value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, domain.NewValidationError(field, "must be an integer")
+	}
+	return &number, nil
+}
+
+// optionalTimeValue parses an optional RFC 3339 timestamp query parameter, nil if it is absent
+func optionalTimeValue(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, domain.NewValidationError(field, "must be an RFC 3339 timestamp")
+	}
+	return &t, nil
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/service"
+	"go.uber.org/zap"
+)
+
+type ResolverHandler struct {
+	service service.ResolverService
+	logger  *zap.Logger
+}
+
+func NewResolverHandler(service service.ResolverService, logger *zap.Logger) *ResolverHandler {
+	return &ResolverHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Resolve returns the job owning the service or instance address given in the path
+func (h *ResolverHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "ip")
+	if _, err := domain.ParseServiceIp(address); err != nil {
+		response.Error(w, domain.NewValidationError("ip", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	resolution, err := h.service.Resolve(r.Context(), address)
+	if err != nil {
+		h.logger.Debug("Error resolving address", zap.String("address", address), zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, resolution, http.StatusOK)
+}
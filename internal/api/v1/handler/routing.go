@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -26,7 +25,7 @@ func NewRoutingHandler(service service.RoutingService, logger *zap.Logger) *Rout
 func (h *RoutingHandler) HandleRoutingChange(w http.ResponseWriter, r *http.Request) {
 	var req domain.RoutingChange
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeAndValidate(w, r, &req); err != nil {
 		response.Error(w, err, http.StatusBadRequest)
 		return
 	}
@@ -34,7 +33,10 @@ func (h *RoutingHandler) HandleRoutingChange(w http.ResponseWriter, r *http.Requ
 	h.logger.Info("Handling routing change", zap.Any("request", req))
 
 	err := h.service.HandleRoutingChange(r.Context(), &domain.RoutingChange{
-		AppName: req.AppName,
+		AppName:              req.AppName,
+		ServiceIP:            req.ServiceIP,
+		IpType:               req.IpType,
+		InstancePriorityList: req.InstancePriorityList,
 	})
 	if err != nil {
 		h.logger.Error("Error handling routing change", zap.Error(err))
@@ -46,7 +48,11 @@ func (h *RoutingHandler) HandleRoutingChange(w http.ResponseWriter, r *http.Requ
 }
 
 func (h *RoutingHandler) GetRouting(w http.ResponseWriter, r *http.Request) {
-	appName := chi.URLParam(r, "appName")
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
 
 	routing, err := h.service.GetRouting(r.Context(), appName)
 	if err != nil {
@@ -57,3 +63,235 @@ func (h *RoutingHandler) GetRouting(w http.ResponseWriter, r *http.Request) {
 
 	response.JSON(w, routing, http.StatusOK)
 }
+
+func (h *RoutingHandler) ListHistory(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	query, err := routingHistoryQuery(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	snapshots, err := h.service.ListHistory(r.Context(), appName, *query)
+	if err != nil {
+		h.logger.Error("Error listing routing history", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, snapshots, http.StatusOK)
+}
+
+func (h *RoutingHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionValue("version", chi.URLParam(r, "version"))
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := h.service.GetHistory(r.Context(), appName, version)
+	if err != nil {
+		h.logger.Error("Error getting routing snapshot", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, snapshot, http.StatusOK)
+}
+
+func (h *RoutingHandler) DiffHistory(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	fromVersion, err := versionValue("from", r.URL.Query().Get("from"))
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	toVersion, err := versionValue("to", r.URL.Query().Get("to"))
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	diff, err := h.service.DiffHistory(r.Context(), appName, fromVersion, toVersion)
+	if err != nil {
+		h.logger.Error("Error diffing routing snapshots", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, diff, http.StatusOK)
+}
+
+func (h *RoutingHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionValue("version", chi.URLParam(r, "version"))
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("Rolling back routing", zap.String("appName", appName), zap.Int64("version", version))
+
+	snapshot, err := h.service.Rollback(r.Context(), appName, version)
+	if err != nil {
+		h.logger.Error("Error rolling back routing", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, snapshot, http.StatusCreated)
+}
+
+func (h *RoutingHandler) GetSmoothingState(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.service.GetSmoothingState(r.Context(), appName)
+	if err != nil {
+		h.logger.Error("Error getting smoothing state", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, state, http.StatusOK)
+}
+
+func (h *RoutingHandler) CreateOverride(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req domain.OverrideRequest
+	if err := decodeAndValidate(w, r, &req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("Creating routing override", zap.String("appName", appName), zap.Any("request", req))
+
+	override, err := h.service.CreateOverride(r.Context(), appName, &req)
+	if err != nil {
+		h.logger.Error("Error creating routing override", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, override, http.StatusCreated)
+}
+
+func (h *RoutingHandler) ListOverrides(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	overrides, err := h.service.ListOverrides(r.Context(), appName)
+	if err != nil {
+		h.logger.Error("Error listing routing overrides", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, overrides, http.StatusOK)
+}
+
+func (h *RoutingHandler) DeleteOverride(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteOverride(r.Context(), appName, chi.URLParam(r, "id")); err != nil {
+		h.logger.Error("Error deleting routing override", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, nil, http.StatusOK)
+}
+
+func (h *RoutingHandler) GetRolloutState(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.service.GetRolloutState(r.Context(), appName)
+	if err != nil {
+		h.logger.Error("Error getting rollout state", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, state, http.StatusOK)
+}
+
+func (h *RoutingHandler) AbortRollout(w http.ResponseWriter, r *http.Request) {
+	appName, err := appNameParam(r)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("Aborting rollout", zap.String("appName", appName))
+
+	if err := h.service.AbortRollout(r.Context(), appName); err != nil {
+		h.logger.Error("Error aborting rollout", zap.Error(err))
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, nil, http.StatusOK)
+}
+
+// routingHistoryQuery reads the page of a routing history request from the before and limit query parameters
+func routingHistoryQuery(r *http.Request) (*domain.RoutingHistoryQuery, error) {
+	values := r.URL.Query()
+
+	query := &domain.RoutingHistoryQuery{}
+	if before := values.Get("before"); before != "" {
+		version, err := versionValue("before", before)
+		if err != nil {
+			return nil, err
+		}
+		query.Before = version
+	}
+	limit, err := optionalIntValue("limit", values.Get("limit"))
+	if err != nil {
+		return nil, err
+	}
+	if limit != nil {
+		query.Limit = *limit
+	}
+
+	return query, query.Validate()
+}
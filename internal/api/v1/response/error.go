@@ -16,9 +16,6 @@ type ErrorResponse struct {
 
 // Error sends an error response
 func Error(w http.ResponseWriter, err error, status int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
 	errResp := &ErrorResponse{
 		Code:    http.StatusText(status),
 		Message: err.Error(),
@@ -26,7 +23,12 @@ func Error(w http.ResponseWriter, err error, status int) {
 
 	// Map domain errors to appropriate status codes
 	var domainErr *domain.Error
-	if errors.As(err, &domainErr) {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		status = http.StatusBadRequest
+		errResp.Code = domain.CodeValidationFailed
+		errResp.Details = validationErr.Violations
+	} else if errors.As(err, &domainErr) {
 		switch domainErr.Code {
 		case domain.CodeNotFound:
 			status = http.StatusNotFound
@@ -34,10 +36,34 @@ func Error(w http.ResponseWriter, err error, status int) {
 		case domain.CodeInterestAlreadyExists:
 			status = http.StatusConflict
 			errResp.Code = "interest_already_exists"
+		case domain.CodeInterestDerived:
+			status = http.StatusConflict
+			errResp.Code = "interest_derived"
+		case domain.CodeQuotaExceeded:
+			status = http.StatusForbidden
+			errResp.Code = "quota_exceeded"
+		case domain.CodePreconditionFailed:
+			status = http.StatusPreconditionFailed
+			errResp.Code = "precondition_failed"
+		case domain.CodeInvalidTransition:
+			status = http.StatusConflict
+			errResp.Code = "invalid_transition"
+		case domain.CodeAlertRuleExists:
+			status = http.StatusConflict
+			errResp.Code = "alert_rule_already_exists"
+		case domain.CodeAlertRuleReadOnly:
+			status = http.StatusConflict
+			errResp.Code = "alert_rule_read_only"
+		case domain.CodeSnapshotUnavailable:
+			status = http.StatusConflict
+			errResp.Code = "snapshot_unavailable"
 			// Add other domain error mappings
 		}
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
 	response := Response{
 		Success: false,
 		Error:   errResp,
@@ -4,6 +4,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/smnzlnsk/routing-manager/internal/api/v1/handler"
+	appMiddleware "github.com/smnzlnsk/routing-manager/internal/middleware"
 	"github.com/smnzlnsk/routing-manager/internal/service"
 	"go.uber.org/zap"
 )
@@ -14,34 +15,103 @@ func Setup(services *service.Services, logger *zap.Logger) *chi.Mux {
 	// Middleware
 	router.Use(middleware.Logger)
 
-	// Setup Interests API
 	interestHandler := handler.NewInterestHandler(services.InterestService, logger)
-	router.Route("/api/v1/interests", func(r chi.Router) {
-		r.Post("/", interestHandler.Create)
-		r.Get("/", interestHandler.List)
+	routingHandler := handler.NewRoutingHandler(services.RoutingService, logger)
+	jobHandler := handler.NewJobHandler(services.JobService, logger)
+	resolverHandler := handler.NewResolverHandler(services.ResolverService, logger)
+	alertHandler := handler.NewAlertHandler(services.AlertService, logger)
+	alertRuleHandler := handler.NewAlertRuleHandler(services.AlertRuleService, logger)
+	reconcilerHandler := handler.NewReconcilerHandler(services.ReconcilerService, logger)
+	metricHandler := handler.NewMetricHandler(services.MetricService, logger)
+	metricSnapshotHandler := handler.NewMetricSnapshotHandler(services.MetricSnapshotService, logger)
 
-		r.Get("/app/{appName}", interestHandler.GetByAppName)
-		r.Delete("/app/{appName}", interestHandler.DeleteByAppName)
+	// All APIs are namespaced, they are served below /api/v1 with the namespace taken
+	// from the X-Namespace header and below /api/v1/namespaces/{namespace}
+	apiRoutes := func(r chi.Router) {
+		r.Use(appMiddleware.Namespace)
 
-		r.Get("/service/{serviceIp}", interestHandler.GetByServiceIp)
-		r.Delete("/service/{serviceIp}", interestHandler.DeleteByServiceIp)
-	})
+		// Setup Interests API
+		r.Route("/interests", func(r chi.Router) {
+			r.Post("/", interestHandler.Create)
+			r.Get("/", interestHandler.List)
 
-	/* Disable alert and routing for now
-	Functionality is taken over by the cluster service manager
-		alertHandler := handler.NewAlertHandler(services.AlertService, logger)
+			r.Get("/app/{appName}", interestHandler.GetByAppName)
+			r.Put("/app/{appName}", interestHandler.Update)
+			r.Delete("/app/{appName}", interestHandler.DeleteByAppName)
 
-		router.Route("/api/v1/alert", func(r chi.Router) {
-			r.Post("/", alertHandler.HandleAlert)
+			r.Get("/service/{serviceIp}", interestHandler.GetByServiceIp)
+			r.Delete("/service/{serviceIp}", interestHandler.DeleteByServiceIp)
+		})
+
+		// Setup Routing API, policy results posted here are normalized, smoothed, rolled out
+		// and overridden before they are persisted and recorded in the routing history
+		r.Post("/routing", routingHandler.HandleRoutingChange)
+		r.Get("/routing/app/{appName}", routingHandler.GetRouting)
+
+		// Setup Routing History API
+		r.Route("/routing/app/{appName}/history", func(r chi.Router) {
+			r.Get("/", routingHandler.ListHistory)
+			r.Get("/diff", routingHandler.DiffHistory)
+			r.Get("/{version}", routingHandler.GetHistory)
+			r.Post("/{version}/rollback", routingHandler.Rollback)
+		})
+		r.Get("/routing/app/{appName}/smoothing", routingHandler.GetSmoothingState)
+		r.Get("/routing/app/{appName}/rollout", routingHandler.GetRolloutState)
+		r.Post("/routing/app/{appName}/rollout/abort", routingHandler.AbortRollout)
+
+		// Setup Routing Overrides API
+		r.Route("/routing/app/{appName}/overrides", func(r chi.Router) {
+			r.Post("/", routingHandler.CreateOverride)
+			r.Get("/", routingHandler.ListOverrides)
+			r.Delete("/{id}", routingHandler.DeleteOverride)
+		})
 
+		// Setup Alerts API
+		r.Route("/alert", func(r chi.Router) {
+			r.Post("/", alertHandler.HandleAlert)
+			r.Get("/", alertHandler.List)
+			r.Get("/{id}", alertHandler.Get)
+			r.Post("/{id}/acknowledge", alertHandler.Acknowledge)
+			r.Post("/{id}/resolve", alertHandler.Resolve)
 		})
 
-		routingHandler := handler.NewRoutingHandler(services.RoutingService, logger)
-		router.Route("/api/v1/routing", func(r chi.Router) {
-			r.Post("/", routingHandler.HandleRoutingChange)
-			r.Get("/app/{appName}", routingHandler.GetRouting)
+		// Setup Alert Rules API
+		r.Route("/alert-rules", func(r chi.Router) {
+			r.Post("/", alertRuleHandler.Create)
+			r.Get("/", alertRuleHandler.List)
+			r.Get("/state", alertRuleHandler.State)
+			r.Get("/{id}", alertRuleHandler.Get)
+			r.Delete("/{id}", alertRuleHandler.Delete)
 		})
-	*/
+
+	}
+	router.Route("/api/v1", apiRoutes)
+
+	// Jobs are managed by the service-manager and not bound to a namespace,
+	// the read-only jobs API shows them exactly as the routing-manager sees them
+	router.Route("/api/v1/jobs", func(r chi.Router) {
+		r.Get("/", jobHandler.List)
+		r.Get("/{name}", jobHandler.GetByJobName)
+	})
+	router.Get("/api/v1/resolve/{ip}", resolverHandler.Resolve)
+	// Reconciliation spans the interests of all namespaces
+	router.Get("/api/v1/reconciliation", reconcilerHandler.Report)
+	// Metrics are recorded per service and shared by the alert rules of all namespaces
+	router.Route("/api/v1/metrics", func(r chi.Router) {
+		r.Post("/", metricHandler.Ingest)
+		r.Get("/", metricHandler.List)
+		r.Get("/history", metricHandler.SelectHistory)
+		r.Get("/aggregate", metricHandler.Aggregate)
+		r.Get("/app/{appName}", metricHandler.Get)
+		r.Get("/app/{appName}/history", metricHandler.History)
+	})
+	// Snapshots of the metrics kept in memory by the memory backend
+	router.Route("/api/v1/admin/metrics", func(r chi.Router) {
+		r.Post("/snapshot", metricSnapshotHandler.Snapshot)
+		r.Post("/restore", metricSnapshotHandler.Restore)
+		r.Get("/export", metricSnapshotHandler.Export)
+	})
+	router.Route("/api/v1/namespaces/{namespace}", apiRoutes)
 
 	return router
 }
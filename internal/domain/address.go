@@ -0,0 +1,68 @@
+package domain
+
+import "net/netip"
+
+// CanonicalServiceIp returns the canonical form of an IPv4 or IPv6 service address.
+// IPv6 addresses are compressed and lower case, IPv4-mapped IPv6 addresses are reduced to IPv4.
+func CanonicalServiceIp(serviceIp string) (string, error) {
+	addr, err := ParseServiceIp(serviceIp)
+	if err != nil {
+		return "", err
+	}
+	return addr.Unmap().String(), nil
+}
+
+// canonicalAddress returns the canonical form of a stored address, unparseable addresses are kept as they are
+func canonicalAddress(address string) string {
+	if canonical, err := CanonicalServiceIp(address); err == nil {
+		return canonical
+	}
+	return address
+}
+
+// isIPv4 reports whether the canonical address belongs to the IPv4 family
+func isIPv4(address string) bool {
+	addr, err := netip.ParseAddr(address)
+	return err == nil && addr.Is4()
+}
+
+// ResolveServiceIps sets both address families of the interest's service IP.
+// The family of the service IP itself is always set, the other one is taken from the
+// service IP list of the job if it holds the service IP. The job may be nil.
+func (i *Interest) ResolveServiceIps(job *Job) {
+	serviceIp := canonicalAddress(i.ServiceIp)
+	i.ServiceIpV4, i.ServiceIpV6 = "", ""
+	if isIPv4(serviceIp) {
+		i.ServiceIpV4 = serviceIp
+	} else {
+		i.ServiceIpV6 = serviceIp
+	}
+
+	if job == nil {
+		return
+	}
+	for _, entry := range job.ServiceIpList {
+		v4, v6 := canonicalAddress(entry.Address), canonicalAddress(entry.Addressv6)
+		if serviceIp != v4 && serviceIp != v6 {
+			continue
+		}
+		if v4 != "" {
+			i.ServiceIpV4 = v4
+		}
+		if v6 != "" {
+			i.ServiceIpV6 = v6
+		}
+		return
+	}
+}
+
+// MatchesServiceIp reports whether the interest is reachable under the service IP in either family
+func (i *Interest) MatchesServiceIp(serviceIp string) bool {
+	serviceIp = canonicalAddress(serviceIp)
+	for _, address := range []string{i.ServiceIp, i.ServiceIpV4, i.ServiceIpV6} {
+		if address != "" && canonicalAddress(address) == serviceIp {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,97 @@
 package domain
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
+
+// AlertSeverity tells how urgent an alert is
+type AlertSeverity string
+
+const (
+	AlertSeverityInfo     AlertSeverity = "info"
+	AlertSeverityWarning  AlertSeverity = "warning"
+	AlertSeverityCritical AlertSeverity = "critical"
+)
+
+// AlertState is the lifecycle state of an alert
+type AlertState string
+
+const (
+	AlertStateFiring       AlertState = "firing"
+	AlertStateAcknowledged AlertState = "acknowledged"
+	AlertStateResolved     AlertState = "resolved"
+)
 
 type Alert struct {
-	AppName   string    `json:"appName" bson:"appname"`
-	CreatedAt time.Time `json:"createdAt" bson:"createdat"`
+	ID        string        `json:"id" bson:"id"`
+	Namespace string        `json:"namespace" bson:"namespace"`
+	AppName   string        `json:"appName" bson:"appname"`
+	Severity  AlertSeverity `json:"severity" bson:"severity"`
+	// Source names the component that raised the alert
+	Source string `json:"source,omitempty" bson:"source,omitempty"`
+	// InstanceNumber and IpType narrow the alert down to an instance and a routing policy
+	InstanceNumber *int          `json:"instanceNumber,omitempty" bson:"instance_number,omitempty"`
+	IpType         ServiceIpType `json:"IpType,omitempty" bson:"IpType,omitempty"`
+	Message        string        `json:"message,omitempty" bson:"message,omitempty"`
+	// Fingerprint identifies duplicates of the alert
+	Fingerprint string `json:"fingerprint" bson:"fingerprint"`
+	// Count is the number of occurrences collapsed into the alert
+	Count int        `json:"count" bson:"count"`
+	State AlertState `json:"state" bson:"state"`
+	// AcknowledgedBy/At and ResolvedBy/At record who moved the alert to the respective state and when
+	AcknowledgedBy string     `json:"acknowledgedBy,omitempty" bson:"acknowledgedby,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty" bson:"acknowledgedat,omitempty"`
+	ResolvedBy     string     `json:"resolvedBy,omitempty" bson:"resolvedby,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty" bson:"resolvedat,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt" bson:"createdat"`
+	LastSeenAt     time.Time  `json:"lastSeenAt" bson:"lastseenat"`
+}
+
+// AlertTransitionSources lists the states an alert may be moved to the given state from
+func AlertTransitionSources(to AlertState) []AlertState {
+	switch to {
+	case AlertStateAcknowledged:
+		return []AlertState{AlertStateFiring}
+	case AlertStateResolved:
+		return []AlertState{AlertStateFiring, AlertStateAcknowledged}
+	default:
+		return nil
+	}
+}
+
+// AlertFilter restricts the listed alerts, zero values match every alert
+type AlertFilter struct {
+	AppName  string
+	State    AlertState
+	Severity AlertSeverity
+	// Since and Until restrict the alerts to those firing at some point within the range
+	Since *time.Time
+	Until *time.Time
+}
+
+// AlertTransitionRequest acknowledges or resolves an alert
+type AlertTransitionRequest struct {
+	// By names who acknowledges or resolves the alert
+	By string `json:"by"`
+}
+
+// ComputeFingerprint derives the fingerprint under which duplicates of the alert are collapsed
+func (a *Alert) ComputeFingerprint() string {
+	instance := ""
+	if a.InstanceNumber != nil {
+		instance = strconv.Itoa(*a.InstanceNumber)
+	}
+	return a.Namespace + "|" + a.AppName + "|" + string(a.Severity) + "|" + a.Source + "|" + instance + "|" + string(a.IpType)
 }
 
 type AlertRequest struct {
-	AppName string `json:"appName"`
+	AppName        string        `json:"appName"`
+	Severity       AlertSeverity `json:"severity,omitempty"`
+	Source         string        `json:"source,omitempty"`
+	InstanceNumber *int          `json:"instanceNumber,omitempty"`
+	IpType         ServiceIpType `json:"IpType,omitempty"`
+	Message        string        `json:"message,omitempty"`
 }
 
 type AlertResponse struct {
@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"time"
+)
+
+// Comparator compares a metric value against the threshold of an alert rule
+type Comparator string
+
+const (
+	ComparatorGreater      Comparator = "gt"
+	ComparatorGreaterEqual Comparator = "gte"
+	ComparatorLess         Comparator = "lt"
+	ComparatorLessEqual    Comparator = "lte"
+)
+
+// Breaches reports whether the value breaches the threshold
+func (c Comparator) Breaches(value, threshold float64) bool {
+	switch c {
+	case ComparatorGreater:
+		return value > threshold
+	case ComparatorGreaterEqual:
+		return value >= threshold
+	case ComparatorLess:
+		return value < threshold
+	case ComparatorLessEqual:
+		return value <= threshold
+	default:
+		return false
+	}
+}
+
+// AlertRuleSource tells where an alert rule was defined
+type AlertRuleSource string
+
+const (
+	AlertRuleSourceConfig AlertRuleSource = "config"
+	AlertRuleSourceAPI    AlertRuleSource = "api"
+)
+
+// AlertRule raises an alert for every app whose metric breaches the threshold for the whole duration,
+// and resolves it once the metric recovers
+type AlertRule struct {
+	ID         string        `json:"id" bson:"id"`
+	Namespace  string        `json:"namespace" bson:"namespace"`
+	Name       string        `json:"name" bson:"name"`
+	Metric     string        `json:"metric" bson:"metric"`
+	Comparator Comparator    `json:"comparator" bson:"comparator"`
+	Threshold  float64       `json:"threshold" bson:"threshold"`
+	Duration   string        `json:"duration,omitempty" bson:"duration,omitempty"`
+	Severity   AlertSeverity `json:"severity" bson:"severity"`
+	// Apps is a glob pattern selecting the apps the rule applies to, empty selects all apps of the namespace
+	Apps      string          `json:"apps,omitempty" bson:"apps,omitempty"`
+	Source    AlertRuleSource `json:"source" bson:"source"`
+	CreatedAt time.Time       `json:"createdAt" bson:"createdat"`
+}
+
+// Window returns how long the metric must breach the threshold before the rule fires
+func (r *AlertRule) Window() time.Duration {
+	window, _ := time.ParseDuration(r.Duration)
+	return window
+}
+
+// Selects reports whether the rule applies to the app
+func (r *AlertRule) Selects(appName string) bool {
+	if r.Apps == "" {
+		return true
+	}
+	matched, err := path.Match(r.Apps, appName)
+	return err == nil && matched
+}
+
+// AlertRuleRequest creates an alert rule
+type AlertRuleRequest struct {
+	Name       string        `json:"name"`
+	Metric     string        `json:"metric"`
+	Comparator Comparator    `json:"comparator"`
+	Threshold  float64       `json:"threshold"`
+	Duration   string        `json:"duration,omitempty"`
+	Severity   AlertSeverity `json:"severity,omitempty"`
+	Apps       string        `json:"apps,omitempty"`
+}
+
+// Validate checks an AlertRuleRequest
+func (r *AlertRuleRequest) Validate() error {
+	verr := &ValidationError{}
+	if r.Name == "" {
+		verr.Add("name", "is required")
+	} else if !appNamePattern.MatchString(r.Name) {
+		verr.Add("name", "must consist of alphanumerics, '.', '-' or '_' and start and end with an alphanumeric")
+	}
+	if r.Metric == "" {
+		verr.Add("metric", "is required")
+	}
+	switch r.Comparator {
+	case ComparatorGreater, ComparatorGreaterEqual, ComparatorLess, ComparatorLessEqual:
+	default:
+		verr.Add("comparator", fmt.Sprintf("must be one of %q, %q, %q or %q",
+			ComparatorGreater, ComparatorGreaterEqual, ComparatorLess, ComparatorLessEqual))
+	}
+	if math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0) {
+		verr.Add("threshold", "must be a finite number")
+	}
+	if r.Duration != "" {
+		if duration, err := time.ParseDuration(r.Duration); err != nil || duration < 0 {
+			verr.Add("duration", "must be a non-negative duration")
+		}
+	}
+	switch r.Severity {
+	case "", AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical:
+	default:
+		verr.Add("severity", fmt.Sprintf("must be one of %q, %q or %q",
+			AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical))
+	}
+	if _, err := path.Match(r.Apps, ""); err != nil {
+		verr.Add("apps", "must be a valid glob pattern")
+	}
+	return verr.ErrOrNil()
+}
+
+// AlertRuleStatus is the evaluation state of an alert rule for a single app
+type AlertRuleStatus string
+
+const (
+	AlertRuleInactive AlertRuleStatus = "inactive"
+	AlertRulePending  AlertRuleStatus = "pending"
+	AlertRuleFiring   AlertRuleStatus = "firing"
+)
+
+// AlertRuleState exposes the evaluation of an alert rule for a single series of its metric
+type AlertRuleState struct {
+	RuleID         string `json:"ruleId"`
+	Name           string `json:"name"`
+	AppName        string `json:"appName"`
+	InstanceNumber *int   `json:"instanceNumber,omitempty"`
+	// Labels identify the evaluated series of the metric
+	Labels map[string]string `json:"labels,omitempty"`
+	Status AlertRuleStatus   `json:"status"`
+	// Value is the latest value of the metric
+	Value *float64 `json:"value,omitempty"`
+	// Since is when the rule entered its status
+	Since           time.Time `json:"since"`
+	AlertID         string    `json:"alertId,omitempty"`
+	LastEvaluatedAt time.Time `json:"lastEvaluatedAt"`
+	Error           string    `json:"error,omitempty"`
+}
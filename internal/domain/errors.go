@@ -20,9 +20,23 @@ func NewError(code, message string) *Error {
 const (
 	CodeNotFound              = "not_found"
 	CodeInterestAlreadyExists = "interest_already_exists"
+	CodeValidationFailed      = "validation_failed"
+	CodePreconditionFailed    = "precondition_failed"
+	CodeInterestDerived       = "interest_derived"
+	CodeQuotaExceeded         = "quota_exceeded"
+	CodeInvalidTransition     = "invalid_transition"
+	CodeAlertRuleExists       = "alert_rule_already_exists"
+	CodeAlertRuleReadOnly     = "alert_rule_read_only"
+	CodeSnapshotUnavailable   = "snapshot_unavailable"
 )
 
 var (
 	ErrNotFound              = NewError(CodeNotFound, "not found")
 	ErrInterestAlreadyExists = NewError(CodeInterestAlreadyExists, "interest already exists")
+	ErrPreconditionFailed    = NewError(CodePreconditionFailed, "version does not match")
+	ErrInterestDerived       = NewError(CodeInterestDerived, "interest is derived from the interested nodes of the job")
+	ErrQuotaExceeded         = NewError(CodeQuotaExceeded, "namespace quota exceeded")
+	ErrInvalidTransition     = NewError(CodeInvalidTransition, "alert cannot be moved to the requested state")
+	ErrAlertRuleExists       = NewError(CodeAlertRuleExists, "alert rule already exists")
+	ErrAlertRuleReadOnly     = NewError(CodeAlertRuleReadOnly, "alert rule is defined in the configuration")
 )
@@ -2,16 +2,31 @@ package domain
 
 import "time"
 
+// AnyVersion disables the version check of conditional interest operations
+const AnyVersion int64 = -1
+
 type Interest struct {
-	AppName   string    `json:"appname" bson:"appname"`
-	ServiceIp string    `json:"serviceIp" bson:"serviceIp"`
-	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+	Namespace string `json:"namespace" bson:"namespace"`
+	AppName   string `json:"appname" bson:"appname"`
+	ServiceIp string `json:"serviceIp" bson:"serviceip"`
+	// ServiceIpV4 and ServiceIpV6 hold the service IP in both address families, as far as they are known
+	ServiceIpV4 string          `json:"serviceIpV4,omitempty" bson:"serviceipv4,omitempty"`
+	ServiceIpV6 string          `json:"serviceIpV6,omitempty" bson:"serviceipv6,omitempty"`
+	Policies    []RoutingPolicy `json:"policies,omitempty" bson:"policies,omitempty"`
+	Version     int64           `json:"version" bson:"version"`
+	CreatedAt   time.Time       `json:"createdAt" bson:"createdat"`
+	UpdatedAt   time.Time       `json:"updatedAt" bson:"updatedat"`
+}
+
+// Key identifies the interest across namespaces
+func (i *Interest) Key() string {
+	return i.Namespace + "/" + i.AppName
 }
 
 type InterestRequest struct {
-	AppName   string `json:"appname"`
-	ServiceIp string `json:"serviceIp"`
+	AppName   string          `json:"appname"`
+	ServiceIp string          `json:"serviceIp"`
+	Policies  []RoutingPolicy `json:"policies,omitempty"`
 }
 
 type InterestResponse struct {
@@ -27,9 +27,41 @@ type Job struct {
 	IpType              ServiceIpType              `json:"IpType,omitempty" bson:"IpType,omitempty"`
 	ServiceIpList       []ServiceIpListEntry       `json:"service_ip_list" bson:"service_ip_list"`
 	ServiceInstanceList []ServiceInstanceListEntry `json:"instance_list" bson:"instance_list"`
+	InterestedNodes     []string                   `json:"interested_nodes,omitempty" bson:"interested_nodes,omitempty"`
+	// Overrides lists the active routing overrides, it is only set in routing responses
+	Overrides []*RoutingOverride `json:"overrides,omitempty" bson:"-"`
 }
 
 type PriorityEntry struct {
 	IpType   ServiceIpType `json:"IpType" bson:"IpType"`
 	Priority float64       `json:"priority" bson:"priority"`
 }
+
+// JobFilter selects jobs by their properties, zero values match every job
+type JobFilter struct {
+	// IpType matches jobs offering a service IP of the type
+	IpType ServiceIpType
+	// MinInstances and MaxInstances bound the number of service instances, nil leaves the bound open
+	MinInstances *int
+	MaxInstances *int
+}
+
+// Matches reports whether the job passes the filter
+func (f *JobFilter) Matches(job *Job) bool {
+	if f.IpType != "" {
+		offered := job.IpType == f.IpType
+		for _, entry := range job.ServiceIpList {
+			offered = offered || entry.IpType == f.IpType
+		}
+		if !offered {
+			return false
+		}
+	}
+	if f.MinInstances != nil && len(job.ServiceInstanceList) < *f.MinInstances {
+		return false
+	}
+	if f.MaxInstances != nil && len(job.ServiceInstanceList) > *f.MaxInstances {
+		return false
+	}
+	return true
+}
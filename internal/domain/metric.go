@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// MaxMetricSamples caps the number of samples accepted in a single batch
+const MaxMetricSamples = 1000
+
+// MetricSample is a single value of a metric of an app or one of its instances
+type MetricSample struct {
+	// AppName is the service the sample belongs to
+	AppName string `json:"appName"`
+	// InstanceNumber narrows the sample down to a single instance of the service
+	InstanceNumber *int `json:"instanceNumber,omitempty"`
+	// Metric defaults to the performance metric
+	Metric string `json:"metric,omitempty"`
+	// Labels further identify the series of the sample, e.g. the node or the IpType
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+	// Timestamp defaults to the time the sample is received
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// MetricSamples is a batch of samples. It is decoded from a single sample object or an array of samples.
+type MetricSamples []MetricSample
+
+// UnmarshalJSON accepts a single sample as well as an array of samples
+func (s *MetricSamples) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var sample MetricSample
+		if err := json.Unmarshal(trimmed, &sample); err != nil {
+			return err
+		}
+		*s = MetricSamples{sample}
+		return nil
+	}
+
+	var samples []MetricSample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return err
+	}
+	*s = samples
+	return nil
+}
+
+// MetricValue is the latest value of a series of a metric
+type MetricValue struct {
+	AppName        string `json:"appName"`
+	InstanceNumber *int   `json:"instanceNumber,omitempty"`
+	Metric         string `json:"metric"`
+	// Labels holds the labels of the series besides the app and the instance
+	Labels     map[string]string `json:"labels,omitempty"`
+	Value      float64           `json:"value"`
+	RecordedAt time.Time         `json:"recordedAt"`
+}
+
+// MetricPoint is a value of a metric recorded at a point in time.
+// Older points may be downsampled from several samples, their value is the average of the samples then.
+type MetricPoint struct {
+	Value      float64   `json:"value"`
+	RecordedAt time.Time `json:"recordedAt"`
+	// Min, Max and Count are only set on downsampled points
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count,omitempty"`
+}
+
+// MetricHistory holds the recorded values of a series of a metric, newest first
+type MetricHistory struct {
+	AppName        string `json:"appName"`
+	InstanceNumber *int   `json:"instanceNumber,omitempty"`
+	Metric         string `json:"metric"`
+	// Labels holds the labels of the series besides the app and the instance
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []MetricPoint     `json:"points"`
+}
+
+// MetricSelector selects the series of a metric by their labels
+type MetricSelector struct {
+	// Metric restricts the series to a metric, empty selects every metric
+	Metric string
+	// AppName restricts the series to an app
+	AppName string
+	// Matchers are written as name=value, name!=value, name=~regexp or name!~regexp
+	Matchers []string
+	// Since and Limit restrict the history, zero values return all recorded values
+	Since *time.Time
+	Limit int
+}
+
+// MetricQuery selects the series of a metric of an app, or a single instance of it, without further labels
+type MetricQuery struct {
+	AppName        string
+	InstanceNumber *int
+	// Metric defaults to the performance metric
+	Metric string
+	// Since and Limit restrict the history, zero values return all recorded values
+	Since *time.Time
+	Limit int
+}
+
+// MetricAggregateFunc aggregates the values of a window into a single value
+type MetricAggregateFunc string
+
+const (
+	MetricAggregateAvg  MetricAggregateFunc = "avg"
+	MetricAggregateMin  MetricAggregateFunc = "min"
+	MetricAggregateMax  MetricAggregateFunc = "max"
+	MetricAggregateP50  MetricAggregateFunc = "p50"
+	MetricAggregateP90  MetricAggregateFunc = "p90"
+	MetricAggregateP99  MetricAggregateFunc = "p99"
+	MetricAggregateRate MetricAggregateFunc = "rate"
+	MetricAggregateEWMA MetricAggregateFunc = "ewma"
+)
+
+// MetricAggregation aggregates the values of the selected series within a window ending now
+type MetricAggregation struct {
+	Selector MetricSelector
+	Func     MetricAggregateFunc
+	Window   time.Duration
+	// GroupBy lists the labels the series are grouped by, every series is aggregated on its own if it is empty
+	GroupBy []string
+	// Alpha is the smoothing factor of the EWMA, a default applies if it is zero
+	Alpha float64
+}
+
+// MetricAggregate is the aggregated value of a series or a group of series
+type MetricAggregate struct {
+	Metric string `json:"metric"`
+	// Labels are the labels of the series, or the grouping labels of the group
+	Labels map[string]string   `json:"labels"`
+	Func   MetricAggregateFunc `json:"func"`
+	Value  float64             `json:"value"`
+	// Samples is the number of aggregated samples
+	Samples int `json:"samples"`
+}
+
+// MetricSnapshot describes the snapshot file of the metrics kept in memory
+type MetricSnapshot struct {
+	// Path is the snapshot file, empty if none is configured
+	Path string `json:"path,omitempty"`
+	// Size and ModifiedAt describe the snapshot file, they are omitted if there is none
+	Size       int64      `json:"size,omitempty"`
+	ModifiedAt *time.Time `json:"modifiedAt,omitempty"`
+	// Series is the number of series in the store
+	Series int `json:"series"`
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// DefaultNamespace is used for requests that do not specify a namespace
+	// and for records written before namespaces were introduced
+	DefaultNamespace = "default"
+
+	// AllNamespaces lifts the namespace restriction of repository queries.
+	// It is reserved for internal callers and never accepted from requests.
+	AllNamespaces = "*"
+
+	// MaxNamespaceLength is the maximum accepted length of a namespace
+	MaxNamespaceLength = 63
+)
+
+// namespacePattern matches DNS labels
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+type namespaceKey struct{}
+
+// WithNamespace returns a context scoped to the given namespace
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// NamespaceFromContext returns the namespace of the context, DefaultNamespace if none is set
+func NamespaceFromContext(ctx context.Context) string {
+	if namespace, ok := ctx.Value(namespaceKey{}).(string); ok && namespace != "" {
+		return namespace
+	}
+	return DefaultNamespace
+}
+
+// ValidateNamespace checks the syntax of a namespace
+func ValidateNamespace(namespace string) error {
+	if len(namespace) > MaxNamespaceLength {
+		return fmt.Errorf("must not exceed %d characters", MaxNamespaceLength)
+	}
+	if !namespacePattern.MatchString(namespace) {
+		return fmt.Errorf("must consist of lower case alphanumerics or '-' and start and end with an alphanumeric")
+	}
+	return nil
+}
+
+// NamespaceQuota limits the resources a namespace may use
+type NamespaceQuota struct {
+	// MaxInterests caps the number of interests, 0 means unlimited. It is enforced per process, see config.NamespaceQuotaConfig.
+	MaxInterests int
+	// MinInterval is the shortest scheduling interval for the interests of the namespace
+	MinInterval time.Duration
+}
+
+// NamespaceQuotas holds the quotas of all namespaces
+type NamespaceQuotas struct {
+	Default    NamespaceQuota
+	Namespaces map[string]NamespaceQuota
+}
+
+// For returns the quota of the given namespace
+func (q NamespaceQuotas) For(namespace string) NamespaceQuota {
+	if quota, ok := q.Namespaces[namespace]; ok {
+		return quota
+	}
+	return q.Default
+}
@@ -10,6 +10,12 @@ const (
 	InterestDeleted EventType = "INTEREST_DELETED"
 )
 
+// Routing event types
+const (
+	RoutingApplied    EventType = "ROUTING_APPLIED"
+	RoutingRolledBack EventType = "ROUTING_ROLLED_BACK"
+)
+
 // InterestEvent represents an event related to an interest
 type InterestEvent struct {
 	Type     EventType
@@ -36,3 +42,77 @@ type Subject interface {
 	// Notify notifies all observers of an event
 	Notify(event InterestEvent)
 }
+
+// RoutingEvent represents an event related to the routing priorities of an app
+type RoutingEvent struct {
+	Type     EventType
+	Snapshot *RoutingSnapshot
+}
+
+// RoutingObserver defines the interface for objects that want to be notified of routing events
+type RoutingObserver interface {
+	// OnRoutingEvent is called when the routing priorities of an app changed
+	OnRoutingEvent(event RoutingEvent)
+
+	// GetID returns the ID of the observer
+	GetID() string
+}
+
+// RoutingSubject defines the interface for objects that maintain routing observers
+type RoutingSubject interface {
+	// Register adds an observer to the notification list
+	Register(observer RoutingObserver)
+
+	// Deregister removes an observer from the notification list
+	Deregister(observer RoutingObserver)
+
+	// Notify notifies all observers of an event
+	Notify(event RoutingEvent)
+}
+
+// Job event types
+const (
+	JobCreated EventType = "JOB_CREATED"
+	JobUpdated EventType = "JOB_UPDATED"
+	JobDeleted EventType = "JOB_DELETED"
+)
+
+// JobEvent represents a change of a job document
+type JobEvent struct {
+	Type EventType `json:"type"`
+	Job  *Job      `json:"job"`
+}
+
+// Alert event types
+const (
+	AlertRaised       EventType = "ALERT_RAISED"
+	AlertAcknowledged EventType = "ALERT_ACKNOWLEDGED"
+	AlertResolved     EventType = "ALERT_RESOLVED"
+)
+
+// AlertEvent represents an event related to an alert of an app
+type AlertEvent struct {
+	Type  EventType
+	Alert *Alert
+}
+
+// AlertObserver defines the interface for objects that want to be notified of alerts
+type AlertObserver interface {
+	// OnAlertEvent is called when an alert of an app was raised, acknowledged or resolved
+	OnAlertEvent(event AlertEvent)
+
+	// GetID returns the ID of the observer
+	GetID() string
+}
+
+// AlertSubject defines the interface for objects that maintain alert observers
+type AlertSubject interface {
+	// Register adds an observer to the notification list
+	Register(observer AlertObserver)
+
+	// Deregister removes an observer from the notification list
+	Deregister(observer AlertObserver)
+
+	// Notify notifies all observers of an event
+	Notify(event AlertEvent)
+}
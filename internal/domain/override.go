@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// OverrideAction describes how an override changes the computed priority of an instance
+type OverrideAction string
+
+const (
+	// OverridePin routes all traffic of the IpType to the pinned instances
+	OverridePin OverrideAction = "pin"
+	// OverrideDrain routes no traffic of the IpType to the instance, its share moves to the other instances
+	OverrideDrain OverrideAction = "drain"
+	// OverrideWeight multiplies the computed priority of the instance by the override's weight,
+	// the other instances make up for the difference
+	OverrideWeight OverrideAction = "weight"
+)
+
+// RoutingOverride is a manual change of the routing priority of a single instance.
+// Overrides are applied on top of the computed priorities until they are deleted or expire.
+type RoutingOverride struct {
+	ID             string         `json:"id" bson:"id"`
+	Namespace      string         `json:"namespace" bson:"namespace"`
+	AppName        string         `json:"appName" bson:"appname"`
+	InstanceNumber int            `json:"instanceNumber" bson:"instance_number"`
+	IpType         ServiceIpType  `json:"IpType" bson:"IpType"`
+	Action         OverrideAction `json:"action" bson:"action"`
+	// Weight is the factor applied by weight overrides
+	Weight    float64    `json:"weight,omitempty" bson:"weight,omitempty"`
+	Reason    string     `json:"reason,omitempty" bson:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" bson:"expiresat,omitempty"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdat"`
+}
+
+// OverrideRequest creates an override for an instance of the app given in the path
+type OverrideRequest struct {
+	InstanceNumber int            `json:"instanceNumber"`
+	IpType         ServiceIpType  `json:"IpType"`
+	Action         OverrideAction `json:"action"`
+	Weight         float64        `json:"weight,omitempty"`
+	Reason         string         `json:"reason,omitempty"`
+	// ExpiresAt and TTL are mutually exclusive, an override without either never expires
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+}
+
+// Validate checks an OverrideRequest
+func (r *OverrideRequest) Validate() error {
+	verr := &ValidationError{}
+	if r.InstanceNumber < 0 {
+		verr.Add("instanceNumber", "must not be negative")
+	}
+	if !IsPolicyType(r.IpType) {
+		verr.Add("IpType", fmt.Sprintf("must be one of %q, %q or %q",
+			ServiceIpTypeUnderutilized, ServiceIpTypeClosest, ServiceIpTypeFPS))
+	}
+	switch r.Action {
+	case OverridePin, OverrideDrain:
+		if r.Weight != 0 {
+			verr.Add("weight", "is only accepted by weight overrides")
+		}
+	case OverrideWeight:
+		if r.Weight <= 0 || math.IsInf(r.Weight, 0) {
+			verr.Add("weight", "must be a positive number")
+		}
+	default:
+		verr.Add("action", fmt.Sprintf("must be one of %q, %q or %q", OverridePin, OverrideDrain, OverrideWeight))
+	}
+	if r.ExpiresAt != nil && r.TTL != "" {
+		verr.Add("ttl", "must not be combined with expiresAt")
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		verr.Add("expiresAt", "must be in the future")
+	}
+	if r.TTL != "" {
+		if ttl, err := time.ParseDuration(r.TTL); err != nil || ttl <= 0 {
+			verr.Add("ttl", "must be a positive duration")
+		}
+	}
+	return verr.ErrOrNil()
+}
+
+// Expiry returns the time the requested override expires, nil if it does not expire
+func (r *OverrideRequest) Expiry(now time.Time) *time.Time {
+	if r.ExpiresAt != nil {
+		expiresAt := *r.ExpiresAt
+		return &expiresAt
+	}
+	if ttl, err := time.ParseDuration(r.TTL); err == nil && ttl > 0 {
+		expiresAt := now.Add(ttl)
+		return &expiresAt
+	}
+	return nil
+}
+
+// Active reports whether the override is in effect at the given time
+func (o *RoutingOverride) Active(now time.Time) bool {
+	return o.ExpiresAt == nil || o.ExpiresAt.After(now)
+}
+
+// ApplyOverrides applies the active overrides of the IpType to the priorities of the job.
+// Weights are applied first, then drains, then pins. Weighted and drained priorities are rescaled to keep
+// the total of the computed ones, so the traffic taken from an instance moves to the others.
+// Pinned instances share the traffic evenly and every other instance of the job is drained for the IpType.
+func (j *Job) ApplyOverrides(ipType ServiceIpType, overrides []*RoutingOverride, now time.Time) {
+	weights := make(map[int]float64)
+	drained := make(map[int]bool)
+	pinned := make(map[int]bool)
+	for _, override := range overrides {
+		if override.IpType != ipType || !override.Active(now) {
+			continue
+		}
+		switch override.Action {
+		case OverrideWeight:
+			if _, ok := weights[override.InstanceNumber]; !ok {
+				weights[override.InstanceNumber] = 1
+			}
+			weights[override.InstanceNumber] *= override.Weight
+		case OverrideDrain:
+			drained[override.InstanceNumber] = true
+		case OverridePin:
+			pinned[override.InstanceNumber] = true
+		}
+	}
+
+	// Only pins of existing instances count, pinning a stopped instance must not drain the others
+	pins := 0
+	for _, instance := range j.ServiceInstanceList {
+		if pinned[instance.InstanceNumber] {
+			pins++
+		}
+	}
+
+	if pins > 0 {
+		for i := range j.ServiceInstanceList {
+			instance := &j.ServiceInstanceList[i]
+			entry := instance.priorityEntry(ipType)
+			if entry == nil {
+				// Pins decide the priority of every instance, even of those without a computed one
+				instance.RoutingPriority = append(instance.RoutingPriority, PriorityEntry{IpType: ipType})
+				entry = &instance.RoutingPriority[len(instance.RoutingPriority)-1]
+			}
+
+			if pinned[instance.InstanceNumber] {
+				entry.Priority = 1 / float64(pins)
+			} else {
+				entry.Priority = 0
+			}
+		}
+		return
+	}
+	if len(weights) == 0 && len(drained) == 0 {
+		return
+	}
+
+	var computed, adjusted float64
+	entries := make([]*PriorityEntry, 0, len(j.ServiceInstanceList))
+	for i := range j.ServiceInstanceList {
+		instance := &j.ServiceInstanceList[i]
+		entry := instance.priorityEntry(ipType)
+		if entry == nil {
+			continue
+		}
+
+		computed += entry.Priority
+		if weight, ok := weights[instance.InstanceNumber]; ok {
+			entry.Priority *= weight
+		}
+		if drained[instance.InstanceNumber] {
+			entry.Priority = 0
+		}
+		adjusted += entry.Priority
+		entries = append(entries, entry)
+	}
+
+	// Nothing is left to rescale if every instance with traffic is drained
+	if adjusted <= 0 || computed <= 0 {
+		return
+	}
+	for _, entry := range entries {
+		entry.Priority *= computed / adjusted
+	}
+}
+
+// priorityEntry returns the priority of the given type, nil if the instance has none
+func (e *ServiceInstanceListEntry) priorityEntry(ipType ServiceIpType) *PriorityEntry {
+	for i := range e.RoutingPriority {
+		if e.RoutingPriority[i].IpType == ipType {
+			return &e.RoutingPriority[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Well-known routing policy parameters
+const (
+	// PolicyParameterFPSTarget is the frame rate the fps policy should aim for
+	PolicyParameterFPSTarget = "fpsTarget"
+	// PolicyParameterMaxDistance is the maximum distance in kilometers considered by the closest policy
+	PolicyParameterMaxDistance = "maxDistance"
+)
+
+// RoutingPolicy selects a policy type an interest subscribes to, along with its parameters
+type RoutingPolicy struct {
+	IpType     ServiceIpType          `json:"IpType" bson:"IpType"`
+	Parameters map[string]interface{} `json:"parameters,omitempty" bson:"parameters,omitempty"`
+}
+
+// policyParameters lists the numeric parameters understood by each policy type, requests setting others are rejected
+var policyParameters = map[ServiceIpType][]string{
+	ServiceIpTypeUnderutilized: {},
+	ServiceIpTypeClosest:       {PolicyParameterMaxDistance},
+	ServiceIpTypeFPS:           {PolicyParameterFPSTarget},
+}
+
+// IsPolicyType reports whether routing priorities are computed for the given type
+func IsPolicyType(ipType ServiceIpType) bool {
+	_, ok := policyParameters[ipType]
+	return ok
+}
+
+// PolicyFor returns the policy of the interest for the given type.
+// An interest without any policies subscribes to every policy type.
+func (i *Interest) PolicyFor(ipType ServiceIpType) (RoutingPolicy, bool) {
+	if len(i.Policies) == 0 {
+		return RoutingPolicy{IpType: ipType}, IsPolicyType(ipType)
+	}
+	for _, policy := range i.Policies {
+		if policy.IpType == ipType {
+			return policy, true
+		}
+	}
+	return RoutingPolicy{}, false
+}
+
+// validatePolicies checks the policies of a request and records violations under the given field
+func validatePolicies(field string, policies []RoutingPolicy, verr *ValidationError) {
+	seen := make(map[ServiceIpType]bool, len(policies))
+	for i, policy := range policies {
+		policyField := fmt.Sprintf("%s[%d]", field, i)
+		known, ok := policyParameters[policy.IpType]
+		if !ok {
+			verr.Add(policyField+".IpType", fmt.Sprintf("must be one of %q, %q or %q",
+				ServiceIpTypeUnderutilized, ServiceIpTypeClosest, ServiceIpTypeFPS))
+			continue
+		}
+		if seen[policy.IpType] {
+			verr.Add(policyField+".IpType", "is duplicated")
+		}
+		seen[policy.IpType] = true
+
+		defined := make(map[string]bool, len(known))
+		for _, name := range known {
+			defined[name] = true
+		}
+		names := make([]string, 0, len(policy.Parameters))
+		for name := range policy.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if !defined[name] {
+				verr.Add(policyField+".parameters."+name, fmt.Sprintf("is not a parameter of the %s policy", policy.IpType))
+				continue
+			}
+			if number, ok := policy.Parameters[name].(float64); !ok || number <= 0 {
+				verr.Add(policyField+".parameters."+name, "must be a positive number")
+			}
+		}
+	}
+}
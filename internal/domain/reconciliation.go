@@ -0,0 +1,70 @@
+package domain
+
+import "time"
+
+// OrphanAction is applied to interests whose job is gone
+type OrphanAction string
+
+const (
+	// OrphanReport only reports orphaned interests
+	OrphanReport OrphanAction = "report"
+	// OrphanSuspend stops the scheduler of orphaned interests until their job is deployed again
+	OrphanSuspend OrphanAction = "suspend"
+	// OrphanDelete deletes orphaned interests
+	OrphanDelete OrphanAction = "delete"
+)
+
+// SchedulerAction is applied to schedulers that are out of sync with the interests
+type SchedulerAction string
+
+const (
+	// SchedulerReport only reports drifted schedulers
+	SchedulerReport SchedulerAction = "report"
+	// SchedulerRepair stops schedulers without an interest and restarts missing ones
+	SchedulerRepair SchedulerAction = "repair"
+)
+
+// DriftKind describes how interests, schedulers and jobs are out of sync
+type DriftKind string
+
+const (
+	DriftInterestWithoutJob       DriftKind = "interest_without_job"
+	DriftSchedulerWithoutInterest DriftKind = "scheduler_without_interest"
+	DriftInterestWithoutScheduler DriftKind = "interest_without_scheduler"
+)
+
+// Remediation describes what the reconciler did about a drift
+type Remediation string
+
+const (
+	RemediationNone      Remediation = "none"
+	RemediationSuspended Remediation = "suspended"
+	RemediationDeleted   Remediation = "deleted"
+	RemediationStopped   Remediation = "stopped"
+	RemediationRestarted Remediation = "restarted"
+	RemediationResumed   Remediation = "resumed"
+)
+
+// Drift is a single inconsistency found by the reconciler
+type Drift struct {
+	Kind      DriftKind `json:"kind"`
+	Namespace string    `json:"namespace"`
+	AppName   string    `json:"appName"`
+	// Since is when the drift was first observed
+	Since       time.Time   `json:"since"`
+	Remediation Remediation `json:"remediation"`
+	// Error is set if the remediation failed
+	Error string `json:"error,omitempty"`
+}
+
+// ReconciliationReport is the result of a single reconciliation run
+type ReconciliationReport struct {
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Interests  int       `json:"interests"`
+	Jobs       int       `json:"jobs"`
+	Schedulers int       `json:"schedulers"`
+	Drift      []Drift   `json:"drift"`
+	// Suspended lists the keys of interests whose scheduler is suspended until their job is deployed again
+	Suspended []string `json:"suspended"`
+}
@@ -0,0 +1,44 @@
+package domain
+
+// ResolutionKind tells which part of a job an address belongs to
+type ResolutionKind string
+
+const (
+	ResolutionService  ResolutionKind = "service"
+	ResolutionInstance ResolutionKind = "instance"
+)
+
+// Resolution maps an address to the job owning it
+type Resolution struct {
+	Address string         `json:"address"`
+	JobName string         `json:"jobName"`
+	Kind    ResolutionKind `json:"kind"`
+	// IpType is the type of the service IP, it is empty for instance addresses
+	IpType ServiceIpType `json:"IpType,omitempty"`
+	// InstanceNumber is set for instance addresses
+	InstanceNumber *int `json:"instanceNumber,omitempty"`
+}
+
+// Resolutions lists every address of the job in canonical form along with its resolution
+func (j *Job) Resolutions() []Resolution {
+	var resolutions []Resolution
+	add := func(address string, resolution Resolution) {
+		if address == "" {
+			return
+		}
+		resolution.Address = canonicalAddress(address)
+		resolution.JobName = j.JobName
+		resolutions = append(resolutions, resolution)
+	}
+
+	for _, entry := range j.ServiceIpList {
+		add(entry.Address, Resolution{Kind: ResolutionService, IpType: entry.IpType})
+		add(entry.Addressv6, Resolution{Kind: ResolutionService, IpType: entry.IpType})
+	}
+	for _, instance := range j.ServiceInstanceList {
+		instanceNumber := instance.InstanceNumber
+		add(instance.InstanceIP, Resolution{Kind: ResolutionInstance, InstanceNumber: &instanceNumber})
+		add(instance.InstanceIPv6, Resolution{Kind: ResolutionInstance, InstanceNumber: &instanceNumber})
+	}
+	return resolutions
+}
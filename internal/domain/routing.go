@@ -1,11 +1,14 @@
 package domain
 
 type RoutingChange struct {
+	Namespace            string                  `json:"namespace,omitempty" bson:"namespace"`
 	AppName              string                  `json:"appName" bson:"appName"`
 	ServiceIP            string                  `json:"serviceIp" bson:"serviceIp"`
+	IpType               ServiceIpType           `json:"IpType" bson:"IpType"`
 	InstancePriorityList []InstancePriorityEntry `json:"instancePriorityList" bson:"instancePriorityList"`
 }
 
+// InstancePriorityEntry assigns a priority to a service instance, identified by its instance number
 type InstancePriorityEntry struct {
 	InstanceID string  `json:"instanceId" bson:"instanceId"`
 	Priority   float64 `json:"priority" bson:"priority"`
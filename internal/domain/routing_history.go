@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// RoutingSnapshotSource describes how the routing priorities of a snapshot came about
+type RoutingSnapshotSource string
+
+const (
+	RoutingSourceChange   RoutingSnapshotSource = "change"
+	RoutingSourceRollback RoutingSnapshotSource = "rollback"
+	RoutingSourceOverride RoutingSnapshotSource = "override"
+	// RoutingSourceRolloutAbort restores the priorities in effect before an aborted rollout
+	RoutingSourceRolloutAbort RoutingSnapshotSource = "rollout_abort"
+)
+
+// InstanceRouting holds the routing priorities of a single service instance
+type InstanceRouting struct {
+	InstanceNumber int             `json:"instanceNumber" bson:"instance_number"`
+	InstanceIP     string          `json:"instanceIp,omitempty" bson:"instance_ip,omitempty"`
+	InstanceIPv6   string          `json:"instanceIpV6,omitempty" bson:"instance_ip_v6,omitempty"`
+	Routing        []PriorityEntry `json:"routing" bson:"routing"`
+}
+
+// RoutingSnapshot is a versioned record of the routing priorities of an app after a change was applied
+type RoutingSnapshot struct {
+	Namespace string                `json:"namespace" bson:"namespace"`
+	AppName   string                `json:"appName" bson:"appname"`
+	Version   int64                 `json:"version" bson:"version"`
+	Source    RoutingSnapshotSource `json:"source" bson:"source"`
+	// Change is the routing change that led to the snapshot, if any
+	Change *RoutingChange `json:"change,omitempty" bson:"change,omitempty"`
+	// RolledBackTo is the version restored by a rollback
+	RolledBackTo int64             `json:"rolledBackTo,omitempty" bson:"rolledbackto,omitempty"`
+	Instances    []InstanceRouting `json:"instances" bson:"instances"`
+	CreatedAt    time.Time         `json:"createdAt" bson:"createdat"`
+}
+
+const (
+	// DefaultRoutingHistoryLimit is the page size of the routing history if none is requested
+	DefaultRoutingHistoryLimit = 50
+	// MaxRoutingHistoryLimit is the largest page of the routing history that can be requested
+	MaxRoutingHistoryLimit = 500
+)
+
+// RoutingHistoryQuery selects a page of the snapshots of an app, newest first.
+// The next page starts before the version of the last snapshot of the previous one.
+type RoutingHistoryQuery struct {
+	// Before restricts the page to older versions, zero starts at the newest snapshot
+	Before int64
+	// Limit is the size of the page, zero selects DefaultRoutingHistoryLimit
+	Limit int
+}
+
+// RoutingDiffEntry describes the change of a single priority between two snapshots.
+// From or To are nil if the priority is absent in the respective snapshot.
+type RoutingDiffEntry struct {
+	InstanceNumber int           `json:"instanceNumber"`
+	IpType         ServiceIpType `json:"IpType"`
+	From           *float64      `json:"from"`
+	To             *float64      `json:"to"`
+}
+
+// RoutingDiff lists the priorities that differ between two snapshots of an app
+type RoutingDiff struct {
+	AppName     string             `json:"appName"`
+	FromVersion int64              `json:"fromVersion"`
+	ToVersion   int64              `json:"toVersion"`
+	Changes     []RoutingDiffEntry `json:"changes"`
+}
+
+// InstanceRoutings returns a copy of the routing priorities of all instances of the job
+func (j *Job) InstanceRoutings() []InstanceRouting {
+	instances := make([]InstanceRouting, 0, len(j.ServiceInstanceList))
+	for _, instance := range j.ServiceInstanceList {
+		routing := make([]PriorityEntry, len(instance.RoutingPriority))
+		copy(routing, instance.RoutingPriority)
+		instances = append(instances, InstanceRouting{
+			InstanceNumber: instance.InstanceNumber,
+			InstanceIP:     canonicalAddress(instance.InstanceIP),
+			InstanceIPv6:   canonicalAddress(instance.InstanceIPv6),
+			Routing:        routing,
+		})
+	}
+	return instances
+}
+
+// DiffRoutingSnapshots computes the priority changes leading from one snapshot to another
+func DiffRoutingSnapshots(from, to *RoutingSnapshot) *RoutingDiff {
+	type priorityKey struct {
+		instanceNumber int
+		ipType         ServiceIpType
+	}
+
+	index := func(snapshot *RoutingSnapshot) map[priorityKey]float64 {
+		priorities := make(map[priorityKey]float64)
+		for _, instance := range snapshot.Instances {
+			for _, entry := range instance.Routing {
+				priorities[priorityKey{instance.InstanceNumber, entry.IpType}] = entry.Priority
+			}
+		}
+		return priorities
+	}
+
+	fromPriorities := index(from)
+	toPriorities := index(to)
+
+	keys := make(map[priorityKey]bool, len(fromPriorities)+len(toPriorities))
+	for key := range fromPriorities {
+		keys[key] = true
+	}
+	for key := range toPriorities {
+		keys[key] = true
+	}
+
+	diff := &RoutingDiff{
+		AppName:     to.AppName,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     []RoutingDiffEntry{},
+	}
+	for key := range keys {
+		fromPriority, inFrom := fromPriorities[key]
+		toPriority, inTo := toPriorities[key]
+		if inFrom && inTo && fromPriority == toPriority {
+			continue
+		}
+
+		entry := RoutingDiffEntry{InstanceNumber: key.instanceNumber, IpType: key.ipType}
+		if inFrom {
+			entry.From = &fromPriority
+		}
+		if inTo {
+			entry.To = &toPriority
+		}
+		diff.Changes = append(diff.Changes, entry)
+	}
+
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		if diff.Changes[i].InstanceNumber != diff.Changes[j].InstanceNumber {
+			return diff.Changes[i].InstanceNumber < diff.Changes[j].InstanceNumber
+		}
+		return diff.Changes[i].IpType < diff.Changes[j].IpType
+	})
+
+	return diff
+}
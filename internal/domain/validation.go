@@ -0,0 +1,343 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxAppNameLength is the maximum accepted length of an app name
+const MaxAppNameLength = 253
+
+// labelNamePattern matches the names of metric labels
+var labelNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// appNamePattern matches job names as produced by the service-manager,
+// e.g. "app.namespace.service.namespace"
+var appNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+// FieldViolation describes a single invalid field of a request
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects all field violations of a request
+type ValidationError struct {
+	Violations []FieldViolation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, fmt.Sprintf("%s: %s", v.Field, v.Message))
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Add records a violation for the given field
+func (e *ValidationError) Add(field, message string) {
+	e.Violations = append(e.Violations, FieldViolation{Field: field, Message: message})
+}
+
+// ErrOrNil returns the ValidationError if it holds any violations, nil otherwise
+func (e *ValidationError) ErrOrNil() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// NewValidationError creates a ValidationError holding a single violation
+func NewValidationError(field, message string) *ValidationError {
+	e := &ValidationError{}
+	e.Add(field, message)
+	return e
+}
+
+// ValidateAppName checks the syntax of an app name
+func ValidateAppName(appName string) error {
+	if appName == "" {
+		return fmt.Errorf("is required")
+	}
+	if len(appName) > MaxAppNameLength {
+		return fmt.Errorf("must not exceed %d characters", MaxAppNameLength)
+	}
+	if !appNamePattern.MatchString(appName) {
+		return fmt.Errorf("must consist of alphanumerics, '.', '-' or '_' and start and end with an alphanumeric")
+	}
+	return nil
+}
+
+// ParseServiceIp parses an IPv4 or IPv6 service address
+func ParseServiceIp(serviceIp string) (netip.Addr, error) {
+	if serviceIp == "" {
+		return netip.Addr{}, fmt.Errorf("is required")
+	}
+	addr, err := netip.ParseAddr(serviceIp)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("must be a valid IPv4 or IPv6 address")
+	}
+	if addr.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("must not contain a zone")
+	}
+	return addr, nil
+}
+
+// Validate checks an InterestRequest
+func (r *InterestRequest) Validate() error {
+	verr := &ValidationError{}
+	if err := ValidateAppName(r.AppName); err != nil {
+		verr.Add("appname", err.Error())
+	}
+	if _, err := ParseServiceIp(r.ServiceIp); err != nil {
+		verr.Add("serviceIp", err.Error())
+	}
+	validatePolicies("policies", r.Policies, verr)
+	return verr.ErrOrNil()
+}
+
+// Validate checks a RoutingChange
+func (r *RoutingChange) Validate() error {
+	verr := &ValidationError{}
+	if err := ValidateAppName(r.AppName); err != nil {
+		verr.Add("appName", err.Error())
+	}
+	if r.ServiceIP != "" {
+		if _, err := ParseServiceIp(r.ServiceIP); err != nil {
+			verr.Add("serviceIp", err.Error())
+		}
+	}
+	if !IsPolicyType(r.IpType) {
+		verr.Add("IpType", fmt.Sprintf("must be one of %q, %q or %q",
+			ServiceIpTypeUnderutilized, ServiceIpTypeClosest, ServiceIpTypeFPS))
+	}
+	if len(r.InstancePriorityList) == 0 {
+		verr.Add("instancePriorityList", "must not be empty")
+	}
+	seen := make(map[string]bool, len(r.InstancePriorityList))
+	for i, entry := range r.InstancePriorityList {
+		field := fmt.Sprintf("instancePriorityList[%d]", i)
+		if entry.InstanceID == "" {
+			verr.Add(field+".instanceId", "is required")
+		} else if _, err := strconv.Atoi(entry.InstanceID); err != nil {
+			verr.Add(field+".instanceId", "must be an instance number")
+		} else if seen[entry.InstanceID] {
+			verr.Add(field+".instanceId", "is duplicated")
+		}
+		seen[entry.InstanceID] = true
+		if math.IsNaN(entry.Priority) || math.IsInf(entry.Priority, 0) {
+			verr.Add(field+".priority", "must be a finite number")
+		}
+	}
+	return verr.ErrOrNil()
+}
+
+// Validate checks an AlertRequest
+func (r *AlertRequest) Validate() error {
+	verr := &ValidationError{}
+	if err := ValidateAppName(r.AppName); err != nil {
+		verr.Add("appName", err.Error())
+	}
+	switch r.Severity {
+	case "", AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical:
+	default:
+		verr.Add("severity", fmt.Sprintf("must be one of %q, %q or %q",
+			AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical))
+	}
+	if r.InstanceNumber != nil && *r.InstanceNumber < 0 {
+		verr.Add("instanceNumber", "must not be negative")
+	}
+	if r.IpType != "" && !IsPolicyType(r.IpType) {
+		verr.Add("IpType", fmt.Sprintf("must be one of %q, %q or %q",
+			ServiceIpTypeUnderutilized, ServiceIpTypeClosest, ServiceIpTypeFPS))
+	}
+	return verr.ErrOrNil()
+}
+
+// ValidateMetricName checks the syntax of a metric name
+func ValidateMetricName(metric string) error {
+	if !appNamePattern.MatchString(metric) {
+		return fmt.Errorf("must consist of alphanumerics, '.', '-' or '_' and start and end with an alphanumeric")
+	}
+	return nil
+}
+
+// Validate checks a batch of MetricSamples
+func (s MetricSamples) Validate() error {
+	verr := &ValidationError{}
+	if len(s) == 0 {
+		verr.Add("samples", "must not be empty")
+	}
+	if len(s) > MaxMetricSamples {
+		verr.Add("samples", fmt.Sprintf("must not exceed %d samples", MaxMetricSamples))
+		return verr
+	}
+	for i, sample := range s {
+		field := fmt.Sprintf("samples[%d]", i)
+		if err := ValidateAppName(sample.AppName); err != nil {
+			verr.Add(field+".appName", err.Error())
+		}
+		if sample.InstanceNumber != nil && *sample.InstanceNumber < 0 {
+			verr.Add(field+".instanceNumber", "must not be negative")
+		}
+		if sample.Metric != "" {
+			if err := ValidateMetricName(sample.Metric); err != nil {
+				verr.Add(field+".metric", err.Error())
+			}
+		}
+		for name := range sample.Labels {
+			switch {
+			case name == "app" || name == "instance":
+				verr.Add(field+".labels."+name, "is set by appName and instanceNumber")
+			case !labelNamePattern.MatchString(name):
+				verr.Add(field+".labels."+name, "must consist of alphanumerics or '_' and must not start with a digit")
+			}
+		}
+		if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+			verr.Add(field+".value", "must be a finite number")
+		}
+	}
+	return verr.ErrOrNil()
+}
+
+// Validate checks a MetricSelector, the syntax of the matchers is checked when they are parsed
+func (s *MetricSelector) Validate() error {
+	verr := &ValidationError{}
+	if s.Metric != "" {
+		if err := ValidateMetricName(s.Metric); err != nil {
+			verr.Add("metric", err.Error())
+		}
+	}
+	if s.AppName != "" {
+		if err := ValidateAppName(s.AppName); err != nil {
+			verr.Add("appName", err.Error())
+		}
+	}
+	if s.Limit < 0 {
+		verr.Add("limit", "must not be negative")
+	}
+	return verr.ErrOrNil()
+}
+
+// Validate checks a MetricAggregation
+func (a *MetricAggregation) Validate() error {
+	verr := &ValidationError{}
+	if err := a.Selector.Validate(); err != nil {
+		var selectorErr *ValidationError
+		if errors.As(err, &selectorErr) {
+			verr.Violations = append(verr.Violations, selectorErr.Violations...)
+		}
+	}
+	switch a.Func {
+	case MetricAggregateAvg, MetricAggregateMin, MetricAggregateMax, MetricAggregateP50, MetricAggregateP90,
+		MetricAggregateP99, MetricAggregateRate, MetricAggregateEWMA:
+	default:
+		verr.Add("func", fmt.Sprintf("must be one of %q, %q, %q, %q, %q, %q, %q or %q",
+			MetricAggregateAvg, MetricAggregateMin, MetricAggregateMax, MetricAggregateP50, MetricAggregateP90,
+			MetricAggregateP99, MetricAggregateRate, MetricAggregateEWMA))
+	}
+	if a.Window <= 0 {
+		verr.Add("window", "must be a positive duration")
+	}
+	for _, name := range a.GroupBy {
+		if !labelNamePattern.MatchString(name) {
+			verr.Add("by", fmt.Sprintf("label name %q must consist of alphanumerics or '_' and must not start with a digit", name))
+		}
+	}
+	if a.Alpha < 0 || a.Alpha > 1 {
+		verr.Add("alpha", "must be within (0, 1]")
+	}
+	return verr.ErrOrNil()
+}
+
+// Validate checks a MetricQuery
+func (q *MetricQuery) Validate() error {
+	verr := &ValidationError{}
+	if err := ValidateAppName(q.AppName); err != nil {
+		verr.Add("appName", err.Error())
+	}
+	if q.InstanceNumber != nil && *q.InstanceNumber < 0 {
+		verr.Add("instance", "must not be negative")
+	}
+	if q.Metric != "" {
+		if err := ValidateMetricName(q.Metric); err != nil {
+			verr.Add("metric", err.Error())
+		}
+	}
+	if q.Limit < 0 {
+		verr.Add("limit", "must not be negative")
+	}
+	return verr.ErrOrNil()
+}
+
+// Validate checks a RoutingHistoryQuery
+func (q *RoutingHistoryQuery) Validate() error {
+	verr := &ValidationError{}
+	if q.Before < 0 {
+		verr.Add("before", "must not be negative")
+	}
+	if q.Limit < 0 || q.Limit > MaxRoutingHistoryLimit {
+		verr.Add("limit", fmt.Sprintf("must be between 0 and %d", MaxRoutingHistoryLimit))
+	}
+	return verr.ErrOrNil()
+}
+
+// Validate checks an AlertFilter
+func (f *AlertFilter) Validate() error {
+	verr := &ValidationError{}
+	if f.AppName != "" {
+		if err := ValidateAppName(f.AppName); err != nil {
+			verr.Add("appName", err.Error())
+		}
+	}
+	switch f.State {
+	case "", AlertStateFiring, AlertStateAcknowledged, AlertStateResolved:
+	default:
+		verr.Add("state", fmt.Sprintf("must be one of %q, %q or %q", AlertStateFiring, AlertStateAcknowledged, AlertStateResolved))
+	}
+	switch f.Severity {
+	case "", AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical:
+	default:
+		verr.Add("severity", fmt.Sprintf("must be one of %q, %q or %q",
+			AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical))
+	}
+	if f.Since != nil && f.Until != nil && f.Until.Before(*f.Since) {
+		verr.Add("until", "must not be before since")
+	}
+	return verr.ErrOrNil()
+}
+
+// Validate checks an AlertTransitionRequest
+func (r *AlertTransitionRequest) Validate() error {
+	verr := &ValidationError{}
+	if strings.TrimSpace(r.By) == "" {
+		verr.Add("by", "is required")
+	}
+	return verr.ErrOrNil()
+}
+
+// Validate checks a JobFilter
+func (f *JobFilter) Validate() error {
+	verr := &ValidationError{}
+	switch f.IpType {
+	case "", ServiceIpTypeRoundRobin, ServiceIpTypeUnderutilized, ServiceIpTypeClosest, ServiceIpTypeFPS:
+	default:
+		verr.Add("ipType", fmt.Sprintf("must be one of %q, %q, %q or %q",
+			ServiceIpTypeRoundRobin, ServiceIpTypeUnderutilized, ServiceIpTypeClosest, ServiceIpTypeFPS))
+	}
+	if f.MinInstances != nil && *f.MinInstances < 0 {
+		verr.Add("minInstances", "must not be negative")
+	}
+	if f.MaxInstances != nil && *f.MaxInstances < 0 {
+		verr.Add("maxInstances", "must not be negative")
+	}
+	if f.MinInstances != nil && f.MaxInstances != nil && *f.MinInstances > *f.MaxInstances {
+		verr.Add("maxInstances", "must not be less than minInstances")
+	}
+	return verr.ErrOrNil()
+}
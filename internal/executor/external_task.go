@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/smnzlnsk/routing-manager/internal/domain"
 	"github.com/smnzlnsk/routing-manager/internal/service"
+	"github.com/smnzlnsk/routing-manager/internal/storage"
 	"go.uber.org/zap"
 )
 
@@ -20,19 +22,27 @@ type ExternalTaskExecutor struct {
 	serviceURL string
 	logger     *zap.Logger
 	jobService service.JobService
+	store      storage.PerformanceStore
 }
 
 // TaskPayload represents the data to be sent to the external service
 type TaskPayload struct {
-	AppName   string                 `json:"appName"`
-	ServiceIP string                 `json:"serviceIp"`
-	IpType    domain.ServiceIpType   `json:"IpType"`
-	Timestamp time.Time              `json:"timestamp"`
-	JobData   map[string]interface{} `json:"jobData,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	AppName   string `json:"appName"`
+	ServiceIP string `json:"serviceIp"`
+	// ServiceIPv4 and ServiceIPv6 hold the service IP in both address families, as far as they are known
+	ServiceIPv4 string                 `json:"serviceIpV4,omitempty"`
+	ServiceIPv6 string                 `json:"serviceIpV6,omitempty"`
+	IpType      domain.ServiceIpType   `json:"IpType"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	JobData     map[string]interface{} `json:"jobData,omitempty"`
+	// Metrics holds the latest value of every metric recorded for the instances of the app
+	Metrics []domain.MetricValue `json:"metrics,omitempty"`
 }
 
 // NewExternalTaskExecutor creates a new instance of ExternalTaskExecutor
-func NewExternalTaskExecutor(serviceURL string, timeout time.Duration, jobService service.JobService, logger *zap.Logger) domain.TaskExecutor {
+func NewExternalTaskExecutor(serviceURL string, timeout time.Duration, jobService service.JobService, store storage.PerformanceStore, logger *zap.Logger) domain.TaskExecutor {
 	if timeout <= 0 {
 		timeout = 10 * time.Second
 	}
@@ -43,6 +53,7 @@ func NewExternalTaskExecutor(serviceURL string, timeout time.Duration, jobServic
 		},
 		serviceURL: serviceURL,
 		jobService: jobService,
+		store:      store,
 		logger:     logger,
 	}
 }
@@ -51,6 +62,7 @@ func NewExternalTaskExecutor(serviceURL string, timeout time.Duration, jobServic
 func (e *ExternalTaskExecutor) ExecuteTask(interest *domain.Interest) error {
 	// Create a basic payload
 	payload := TaskPayload{
+		Namespace: interest.Namespace,
 		AppName:   interest.AppName,
 		ServiceIP: interest.ServiceIp,
 		Timestamp: time.Now(),
@@ -66,26 +78,46 @@ func (e *ExternalTaskExecutor) ExecuteTask(interest *domain.Interest) error {
 		jobData["instance_list"] = job.ServiceInstanceList
 		// Add the job data to the payload
 		payload.JobData = jobData
+
+		// Resolve both address families against the current service IPs of the job
+		resolved := *interest
+		resolved.ResolveServiceIps(job)
+		payload.ServiceIPv4 = resolved.ServiceIpV4
+		payload.ServiceIPv6 = resolved.ServiceIpV6
 	} else {
 		return fmt.Errorf("could not find job data for interest: %w", err)
 	}
 
+	// Policies may run without metrics, a failing store only leaves them out
+	metrics, err := e.instanceMetrics(context.Background(), interest.AppName)
+	if err != nil {
+		e.logger.Warn("Failed to read instance metrics for task", zap.String("appName", interest.AppName), zap.Error(err))
+	}
+	payload.Metrics = metrics
+
 	for _, entry := range payload.JobData["service_ip_list"].([]domain.ServiceIpListEntry) {
-		payload.IpType = entry.IpType
-		if payload.IpType == domain.ServiceIpTypeRoundRobin {
+		// Only request the policies the interest subscribed to, RR has no policy
+		policy, ok := interest.PolicyFor(entry.IpType)
+		if !ok {
 			continue
 		}
+		payload.IpType = entry.IpType
+		payload.Parameters = policy.Parameters
 
 		jsonData, err := json.Marshal(payload)
 		if err != nil {
 			return fmt.Errorf("failed to marshal task payload: %w", err)
 		}
-		fmt.Println("jsonData", string(jsonData))
 
 		// Construct the target URL
 		targetURL := fmt.Sprintf("%s/policy/routing/%s", e.serviceURL, entry.IpType)
 
-		fmt.Println("targetURL", targetURL)
+		e.logger.Debug("Sending policy task",
+			zap.String("appName", interest.AppName),
+			zap.String("url", targetURL),
+			zap.Int("metrics", len(payload.Metrics)),
+			zap.Int("bytes", len(jsonData)))
+
 		// Create the HTTP request
 		req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewBuffer(jsonData))
 		if err != nil {
@@ -123,3 +155,50 @@ func (e *ExternalTaskExecutor) ExecuteTask(interest *domain.Interest) error {
 
 	return nil
 }
+
+// instanceMetrics returns the latest value of every metric recorded for an instance of the app,
+// ordered by instance and metric
+func (e *ExternalTaskExecutor) instanceMetrics(ctx context.Context, appName string) ([]domain.MetricValue, error) {
+	if e.store == nil {
+		return nil, nil
+	}
+
+	latest, err := e.store.Latest(ctx, storage.ExactSelector("", storage.Labels{storage.LabelApp: appName}))
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []domain.MetricValue
+	for _, series := range latest {
+		instance := series.Labels.Instance()
+		if instance == nil || len(series.Points) == 0 {
+			continue
+		}
+
+		value := domain.MetricValue{
+			AppName:        appName,
+			InstanceNumber: instance,
+			Metric:         series.Metric,
+			Value:          series.Points[0].Value,
+			RecordedAt:     series.Points[0].Timestamp,
+		}
+		for name, label := range series.Labels {
+			if name == storage.LabelApp || name == storage.LabelInstance || label == "" {
+				continue
+			}
+			if value.Labels == nil {
+				value.Labels = make(map[string]string)
+			}
+			value.Labels[name] = label
+		}
+		metrics = append(metrics, value)
+	}
+
+	sort.SliceStable(metrics, func(i, j int) bool {
+		if *metrics[i].InstanceNumber != *metrics[j].InstanceNumber {
+			return *metrics[i].InstanceNumber < *metrics[j].InstanceNumber
+		}
+		return metrics[i].Metric < metrics[j].Metric
+	})
+	return metrics, nil
+}
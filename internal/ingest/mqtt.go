@@ -0,0 +1,192 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/mqtt"
+	"go.uber.org/zap"
+)
+
+// statsInterval is how often the counters of the subscriber are logged if they changed
+const statsInterval = time.Minute
+
+// Ingester stores decoded metric samples, it is implemented by the metric service
+type Ingester interface {
+	Ingest(ctx context.Context, samples domain.MetricSamples) error
+}
+
+// Config holds the topics metrics are received on and the size of the queue in front of the store
+type Config struct {
+	// Topics may contain the MQTT wildcards + and #
+	Topics []string
+	// QueueSize bounds the messages waiting to be stored, messages arriving while the queue is full are dropped
+	QueueSize int
+}
+
+// Validate checks the topics and the queue size
+func (c Config) Validate() error {
+	if len(c.Topics) == 0 {
+		return fmt.Errorf("at least one topic is required")
+	}
+	for _, topic := range c.Topics {
+		if topic == "" {
+			return fmt.Errorf("topic must not be empty")
+		}
+	}
+	if c.QueueSize <= 0 {
+		return fmt.Errorf("queue size must be positive")
+	}
+	return nil
+}
+
+// Stats counts the messages handled by the subscriber since it started
+type Stats struct {
+	// Received is the number of messages received on the topics
+	Received uint64 `json:"received"`
+	// Ingested is the number of samples stored
+	Ingested uint64 `json:"ingested"`
+	// Malformed is the number of messages dropped because they could not be decoded or were invalid
+	Malformed uint64 `json:"malformed"`
+	// Dropped is the number of messages dropped because the queue was full
+	Dropped uint64 `json:"dropped"`
+	// Failed is the number of messages the store rejected
+	Failed uint64 `json:"failed"`
+}
+
+// message is a received message waiting to be stored
+type message struct {
+	topic   string
+	payload []byte
+}
+
+// MQTTSubscriber receives metric samples published by the worker nodes and stores them.
+// Payloads are JSON encoded like the body of the metrics API, a single sample or an array of samples.
+type MQTTSubscriber struct {
+	client   mqtt.Client
+	ingester Ingester
+	config   Config
+	logger   *zap.Logger
+
+	queue chan message
+
+	received  uint64
+	ingested  uint64
+	malformed uint64
+	dropped   uint64
+	failed    uint64
+}
+
+// NewMQTTSubscriber creates a subscriber receiving samples with the client
+func NewMQTTSubscriber(client mqtt.Client, ingester Ingester, config Config, logger *zap.Logger) (*MQTTSubscriber, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &MQTTSubscriber{
+		client:   client,
+		ingester: ingester,
+		config:   config,
+		logger:   logger,
+		queue:    make(chan message, config.QueueSize),
+	}, nil
+}
+
+// Run subscribes to the topics and stores the received samples until the context is done.
+// A subscription failing because the broker is not reachable yet is restored by the client once it connects.
+func (s *MQTTSubscriber) Run(ctx context.Context) {
+	for _, topic := range s.config.Topics {
+		if err := s.client.Subscribe(topic, s.receive); err != nil {
+			s.logger.Warn("Failed to subscribe to metric topic, retrying on reconnect", zap.String("topic", topic), zap.Error(err))
+			continue
+		}
+		s.logger.Info("Subscribed to metric topic", zap.String("topic", topic))
+	}
+
+	go s.work(ctx)
+}
+
+// Stats returns the counters of the subscriber
+func (s *MQTTSubscriber) Stats() Stats {
+	return Stats{
+		Received:  atomic.LoadUint64(&s.received),
+		Ingested:  atomic.LoadUint64(&s.ingested),
+		Malformed: atomic.LoadUint64(&s.malformed),
+		Dropped:   atomic.LoadUint64(&s.dropped),
+		Failed:    atomic.LoadUint64(&s.failed),
+	}
+}
+
+// receive queues a message. It is called by the client and must not block, so messages are dropped if the queue is full.
+func (s *MQTTSubscriber) receive(topic string, payload []byte) {
+	atomic.AddUint64(&s.received, 1)
+
+	select {
+	case s.queue <- message{topic: topic, payload: payload}:
+	default:
+		if atomic.AddUint64(&s.dropped, 1) == 1 {
+			s.logger.Warn("Metric queue is full, dropping messages", zap.String("topic", topic), zap.Int("queueSize", s.config.QueueSize))
+		}
+	}
+}
+
+// work stores the queued messages and logs the counters until the context is done
+func (s *MQTTSubscriber) work(ctx context.Context) {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	var logged Stats
+	for {
+		select {
+		case <-ctx.Done():
+			for _, topic := range s.config.Topics {
+				if err := s.client.Unsubscribe(topic); err != nil {
+					s.logger.Warn("Failed to unsubscribe from metric topic", zap.String("topic", topic), zap.Error(err))
+				}
+			}
+			s.logStats(s.Stats())
+			return
+		case msg := <-s.queue:
+			s.store(ctx, msg)
+		case <-ticker.C:
+			if stats := s.Stats(); stats != logged {
+				s.logStats(stats)
+				logged = stats
+			}
+		}
+	}
+}
+
+// store decodes and stores the samples of a message
+func (s *MQTTSubscriber) store(ctx context.Context, msg message) {
+	var samples domain.MetricSamples
+	if err := json.Unmarshal(msg.payload, &samples); err != nil {
+		atomic.AddUint64(&s.malformed, 1)
+		s.logger.Debug("Dropping malformed metric message", zap.String("topic", msg.topic), zap.Error(err))
+		return
+	}
+	if err := samples.Validate(); err != nil {
+		atomic.AddUint64(&s.malformed, 1)
+		s.logger.Debug("Dropping invalid metric message", zap.String("topic", msg.topic), zap.Error(err))
+		return
+	}
+
+	if err := s.ingester.Ingest(ctx, samples); err != nil {
+		atomic.AddUint64(&s.failed, 1)
+		return
+	}
+	atomic.AddUint64(&s.ingested, uint64(len(samples)))
+}
+
+func (s *MQTTSubscriber) logStats(stats Stats) {
+	s.logger.Info("MQTT metric ingestion",
+		zap.Uint64("received", stats.Received),
+		zap.Uint64("ingested", stats.Ingested),
+		zap.Uint64("malformed", stats.Malformed),
+		zap.Uint64("dropped", stats.Dropped),
+		zap.Uint64("failed", stats.Failed))
+}
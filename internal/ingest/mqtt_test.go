@@ -0,0 +1,339 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/mqtt"
+	"go.uber.org/zap"
+)
+
+// fakeClient is an in-memory broker connection. Like the paho client it keeps the subscriptions
+// made while disconnected and restores all subscriptions when it reconnects.
+type fakeClient struct {
+	mutex     sync.Mutex
+	connected bool
+	// subscriptions are restored on reconnect, active are those in effect at the broker
+	subscriptions map[string]mqtt.MessageHandler
+	active        map[string]mqtt.MessageHandler
+	unsubscribed  []string
+}
+
+func newFakeClient(connected bool) *fakeClient {
+	return &fakeClient{
+		connected:     connected,
+		subscriptions: make(map[string]mqtt.MessageHandler),
+		active:        make(map[string]mqtt.MessageHandler),
+	}
+}
+
+func (c *fakeClient) Publish(topic string, payload []byte) error {
+	return errors.New("not supported")
+}
+
+func (c *fakeClient) Subscribe(topic string, handler mqtt.MessageHandler) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.subscriptions[topic] = handler
+	if !c.connected {
+		return errors.New("not connected")
+	}
+	c.active[topic] = handler
+	return nil
+}
+
+func (c *fakeClient) Unsubscribe(topic string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.subscriptions, topic)
+	delete(c.active, topic)
+	c.unsubscribed = append(c.unsubscribed, topic)
+	return nil
+}
+
+func (c *fakeClient) Close() {}
+
+// disconnect drops the subscriptions at the broker
+func (c *fakeClient) disconnect() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.connected = false
+	c.active = make(map[string]mqtt.MessageHandler)
+}
+
+// reconnect restores the subscriptions
+func (c *fakeClient) reconnect() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.connected = true
+	for topic, handler := range c.subscriptions {
+		c.active[topic] = handler
+	}
+}
+
+// deliver passes a message published on the topic to the handlers of all matching subscriptions
+func (c *fakeClient) deliver(topic, payload string) {
+	c.mutex.Lock()
+	var handlers []mqtt.MessageHandler
+	for filter, handler := range c.active {
+		if topicMatches(filter, topic) {
+			handlers = append(handlers, handler)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(topic, []byte(payload))
+	}
+}
+
+func (c *fakeClient) activeTopics() map[string]bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	topics := make(map[string]bool, len(c.active))
+	for topic := range c.active {
+		topics[topic] = true
+	}
+	return topics
+}
+
+// topicMatches reports whether the topic matches the filter, which may contain the wildcards + and #
+func topicMatches(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// fakeIngester records the ingested samples. If gate is set, Ingest signals entered and blocks until the gate is closed.
+type fakeIngester struct {
+	mutex   sync.Mutex
+	samples domain.MetricSamples
+	err     error
+	gate    chan struct{}
+	entered chan struct{}
+}
+
+func (i *fakeIngester) Ingest(ctx context.Context, samples domain.MetricSamples) error {
+	if i.gate != nil {
+		i.entered <- struct{}{}
+		<-i.gate
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.err != nil {
+		return i.err
+	}
+	i.samples = append(i.samples, samples...)
+	return nil
+}
+
+func (i *fakeIngester) ingested() domain.MetricSamples {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return append(domain.MetricSamples(nil), i.samples...)
+}
+
+func startSubscriber(t *testing.T, client mqtt.Client, ingester Ingester, config Config) *MQTTSubscriber {
+	t.Helper()
+
+	subscriber, err := NewMQTTSubscriber(client, ingester, config, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	subscriber.Run(ctx)
+	return subscriber
+}
+
+// waitForStats waits until the counters of the subscriber equal the expected ones
+func waitForStats(t *testing.T, subscriber *MQTTSubscriber, want Stats) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for subscriber.Stats() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("got stats %+v, want %+v", subscriber.Stats(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+const (
+	sample       = `{"appName": "web", "instanceNumber": 0, "metric": "cpu", "value": 0.5}`
+	sampleBatch  = `[{"appName": "web", "instanceNumber": 0, "value": 1}, {"appName": "web", "instanceNumber": 1, "value": 2}]`
+	otherSample  = `{"appName": "db", "value": 3}`
+	invalidJSON  = `{"appName": "web", "value":`
+	invalidValue = `{"appName": "", "value": 1}`
+)
+
+func TestSubscribesToWildcardTopics(t *testing.T) {
+	client := newFakeClient(true)
+	ingester := &fakeIngester{}
+	subscriber := startSubscriber(t, client, ingester, Config{Topics: []string{"metrics/+/samples", "nodes/#"}, QueueSize: 10})
+
+	topics := client.activeTopics()
+	if len(topics) != 2 || !topics["metrics/+/samples"] || !topics["nodes/#"] {
+		t.Fatalf("got subscriptions %v", topics)
+	}
+
+	client.deliver("metrics/worker-1/samples", sample)
+	client.deliver("nodes/worker-2/gpu/samples", otherSample)
+	client.deliver("metrics/worker-1/other", sample)
+	client.deliver("alerts/worker-1", sample)
+	waitForStats(t, subscriber, Stats{Received: 2, Ingested: 2})
+
+	samples := ingester.ingested()
+	if len(samples) != 2 || samples[0].AppName != "web" || samples[1].AppName != "db" {
+		t.Fatalf("got samples %+v", samples)
+	}
+}
+
+func TestIngestsSamples(t *testing.T) {
+	client := newFakeClient(true)
+	ingester := &fakeIngester{}
+	subscriber := startSubscriber(t, client, ingester, Config{Topics: []string{"metrics"}, QueueSize: 10})
+
+	client.deliver("metrics", sample)
+	client.deliver("metrics", sampleBatch)
+	waitForStats(t, subscriber, Stats{Received: 2, Ingested: 3})
+
+	samples := ingester.ingested()
+	if len(samples) != 3 {
+		t.Fatalf("got %d samples, want 3", len(samples))
+	}
+	first := samples[0]
+	if first.AppName != "web" || first.InstanceNumber == nil || *first.InstanceNumber != 0 || first.Metric != "cpu" || first.Value != 0.5 {
+		t.Fatalf("got sample %+v", first)
+	}
+	if samples[2].InstanceNumber == nil || *samples[2].InstanceNumber != 1 || samples[2].Value != 2 {
+		t.Fatalf("got sample %+v", samples[2])
+	}
+}
+
+func TestCountsMalformedMessages(t *testing.T) {
+	client := newFakeClient(true)
+	ingester := &fakeIngester{}
+	subscriber := startSubscriber(t, client, ingester, Config{Topics: []string{"metrics"}, QueueSize: 10})
+
+	client.deliver("metrics", invalidJSON)
+	client.deliver("metrics", invalidValue)
+	client.deliver("metrics", `[]`)
+	client.deliver("metrics", sample)
+	waitForStats(t, subscriber, Stats{Received: 4, Ingested: 1, Malformed: 3})
+
+	if samples := ingester.ingested(); len(samples) != 1 {
+		t.Fatalf("got %d samples, want 1", len(samples))
+	}
+}
+
+func TestCountsFailedMessages(t *testing.T) {
+	client := newFakeClient(true)
+	ingester := &fakeIngester{err: errors.New("store unavailable")}
+	subscriber := startSubscriber(t, client, ingester, Config{Topics: []string{"metrics"}, QueueSize: 10})
+
+	client.deliver("metrics", sampleBatch)
+	waitForStats(t, subscriber, Stats{Received: 1, Failed: 1})
+}
+
+func TestDropsMessagesWhileQueueIsFull(t *testing.T) {
+	client := newFakeClient(true)
+	ingester := &fakeIngester{gate: make(chan struct{}), entered: make(chan struct{}, 10)}
+	subscriber := startSubscriber(t, client, ingester, Config{Topics: []string{"metrics"}, QueueSize: 1})
+
+	// The first message blocks the worker, the second fills the queue and the others are dropped
+	client.deliver("metrics", sample)
+	<-ingester.entered
+	client.deliver("metrics", sample)
+	client.deliver("metrics", sample)
+	client.deliver("metrics", sample)
+	waitForStats(t, subscriber, Stats{Received: 4, Dropped: 2})
+
+	close(ingester.gate)
+	waitForStats(t, subscriber, Stats{Received: 4, Ingested: 2, Dropped: 2})
+}
+
+func TestResubscribesAfterReconnect(t *testing.T) {
+	// The broker is not reachable when the subscriber starts
+	client := newFakeClient(false)
+	ingester := &fakeIngester{}
+	subscriber := startSubscriber(t, client, ingester, Config{Topics: []string{"metrics/#"}, QueueSize: 10})
+
+	client.deliver("metrics/worker-1", sample)
+	if stats := subscriber.Stats(); stats.Received != 0 {
+		t.Fatalf("received %d messages before connecting", stats.Received)
+	}
+
+	client.reconnect()
+	client.deliver("metrics/worker-1", sample)
+	waitForStats(t, subscriber, Stats{Received: 1, Ingested: 1})
+
+	// Messages published while the connection is lost are missed, later ones are received again
+	client.disconnect()
+	client.deliver("metrics/worker-1", sample)
+	client.reconnect()
+	client.deliver("metrics/worker-1", sampleBatch)
+	waitForStats(t, subscriber, Stats{Received: 2, Ingested: 3})
+}
+
+func TestUnsubscribesWhenDone(t *testing.T) {
+	client := newFakeClient(true)
+	subscriber, err := NewMQTTSubscriber(client, &fakeIngester{}, Config{Topics: []string{"a/#", "b/+"}, QueueSize: 10}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	subscriber.Run(ctx)
+	cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(client.activeTopics()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("still subscribed to %v", client.activeTopics())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{name: "valid", config: Config{Topics: []string{"metrics/#"}, QueueSize: 1}, valid: true},
+		{name: "no topics", config: Config{QueueSize: 1}},
+		{name: "empty topic", config: Config{Topics: []string{"metrics", ""}, QueueSize: 1}},
+		{name: "no queue", config: Config{Topics: []string{"metrics"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("got error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/smnzlnsk/routing-manager/internal/api/v1/response"
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+// NamespaceHeader selects the namespace of a request
+const NamespaceHeader = "X-Namespace"
+
+// Namespace scopes the request context to a namespace.
+// The namespace is taken from the {namespace} path segment or the X-Namespace header,
+// requests specifying neither use the default namespace.
+func Namespace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pathNamespace := chi.URLParam(r, "namespace")
+		headerNamespace := r.Header.Get(NamespaceHeader)
+
+		namespace := pathNamespace
+		if namespace == "" {
+			namespace = headerNamespace
+		}
+		if namespace == "" {
+			namespace = domain.DefaultNamespace
+		}
+
+		if pathNamespace != "" && headerNamespace != "" && pathNamespace != headerNamespace {
+			response.Error(w, domain.NewValidationError("namespace", "path and "+NamespaceHeader+" header must match"), http.StatusBadRequest)
+			return
+		}
+		if err := domain.ValidateNamespace(namespace); err != nil {
+			response.Error(w, domain.NewValidationError("namespace", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.WithNamespace(r.Context(), namespace)))
+	})
+}
@@ -0,0 +1,150 @@
+package mqtt
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+// MessageHandler is called for every message received on a subscribed topic
+type MessageHandler func(topic string, payload []byte)
+
+// Client publishes and receives MQTT messages. It is an interface so that tests and
+// alternative brokers can replace the paho client.
+type Client interface {
+	Publish(topic string, payload []byte) error
+	Subscribe(topic string, handler MessageHandler) error
+	Unsubscribe(topic string) error
+	Close()
+}
+
+// Config holds the connection settings of the MQTT broker
+type Config struct {
+	// Broker is the URL of the broker, e.g. tcp://mqtt:1883
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	QoS      byte
+	// Timeout bounds connecting, publishing and subscribing
+	Timeout time.Duration
+}
+
+// pahoClient implements Client with the paho MQTT client
+type pahoClient struct {
+	client  paho.Client
+	qos     byte
+	timeout time.Duration
+	logger  *zap.Logger
+
+	mutex sync.Mutex
+	// subscriptions are restored whenever the client reconnects
+	subscriptions map[string]MessageHandler
+}
+
+// NewClient connects to the broker. The client keeps reconnecting in the background if the broker is lost,
+// subscriptions are restored on every reconnect.
+func NewClient(cfg Config, logger *zap.Logger) (Client, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	c := &pahoClient{
+		qos:           cfg.QoS,
+		timeout:       cfg.Timeout,
+		logger:        logger,
+		subscriptions: make(map[string]MessageHandler),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetConnectTimeout(cfg.Timeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOrderMatters(false).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logger.Warn("Lost connection to MQTT broker", zap.String("broker", cfg.Broker), zap.Error(err))
+		}).
+		SetOnConnectHandler(func(_ paho.Client) {
+			logger.Info("Connected to MQTT broker", zap.String("broker", cfg.Broker))
+			c.resubscribe()
+		})
+	c.client = paho.NewClient(opts)
+
+	// With connect retry the token only completes once connected, the client keeps trying after the timeout
+	token := c.client.Connect()
+	if !token.WaitTimeout(cfg.Timeout) {
+		logger.Warn("MQTT broker not reachable yet, retrying in the background", zap.String("broker", cfg.Broker))
+		return c, nil
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: %w", cfg.Broker, err)
+	}
+
+	return c, nil
+}
+
+// Publish sends the payload to the topic
+func (c *pahoClient) Publish(topic string, payload []byte) error {
+	return c.wait(c.client.Publish(topic, c.qos, false, payload))
+}
+
+// Subscribe calls the handler for every message received on the topic, which may contain wildcards
+func (c *pahoClient) Subscribe(topic string, handler MessageHandler) error {
+	c.mutex.Lock()
+	c.subscriptions[topic] = handler
+	c.mutex.Unlock()
+
+	return c.wait(c.client.Subscribe(topic, c.qos, messageCallback(handler)))
+}
+
+// Unsubscribe stops receiving messages of the topic
+func (c *pahoClient) Unsubscribe(topic string) error {
+	c.mutex.Lock()
+	delete(c.subscriptions, topic)
+	c.mutex.Unlock()
+
+	return c.wait(c.client.Unsubscribe(topic))
+}
+
+// resubscribe restores the subscriptions after a reconnect. It must not block, as it runs in the connect handler.
+func (c *pahoClient) resubscribe() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for topic, handler := range c.subscriptions {
+		topic := topic
+		token := c.client.Subscribe(topic, c.qos, messageCallback(handler))
+		go func() {
+			if err := c.wait(token); err != nil {
+				c.logger.Error("Failed to restore MQTT subscription", zap.String("topic", topic), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// messageCallback adapts a MessageHandler to the paho callback
+func messageCallback(handler MessageHandler) paho.MessageHandler {
+	return func(_ paho.Client, message paho.Message) {
+		handler(message.Topic(), message.Payload())
+	}
+}
+
+// Close disconnects from the broker
+func (c *pahoClient) Close() {
+	c.client.Disconnect(uint(c.timeout / time.Millisecond))
+}
+
+// wait waits for the token to complete within the timeout
+func (c *pahoClient) wait(token paho.Token) error {
+	if !token.WaitTimeout(c.timeout) {
+		return fmt.Errorf("mqtt operation timed out after %s", c.timeout)
+	}
+	return token.Error()
+}
@@ -0,0 +1,283 @@
+package normalization
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+// Strategy defines how raw policy priorities are mapped onto a common scale
+type Strategy string
+
+const (
+	// StrategySumToOne shifts negative priorities to zero and scales them to sum up to 1
+	StrategySumToOne Strategy = "sum_to_one"
+	// StrategyMinMax scales priorities linearly onto [0, 1]
+	StrategyMinMax Strategy = "min_max"
+	// StrategySoftmax maps priorities onto a probability distribution favouring the highest
+	StrategySoftmax Strategy = "softmax"
+	// StrategyRank replaces priorities by their rank, scaled onto (0, 1]
+	StrategyRank Strategy = "rank"
+)
+
+// madScale makes the median absolute deviation a consistent estimator of the standard deviation
+const madScale = 1.4826
+
+// StrategyConfig configures the normalization of a single policy type
+type StrategyConfig struct {
+	Strategy Strategy
+	// OutlierThreshold clamps priorities further than this many (robust) standard deviations
+	// from the median before normalizing, 0 disables clamping
+	OutlierThreshold float64
+	// Temperature of the softmax strategy, defaults to 1
+	Temperature float64
+}
+
+// Config configures the normalization of all policy types
+type Config struct {
+	Default StrategyConfig
+	IpTypes map[domain.ServiceIpType]StrategyConfig
+}
+
+// For returns the configuration for the given policy type
+func (c Config) For(ipType domain.ServiceIpType) StrategyConfig {
+	if cfg, ok := c.IpTypes[ipType]; ok {
+		return cfg
+	}
+	return c.Default
+}
+
+// Validate checks the configuration
+func (c Config) Validate() error {
+	if err := c.Default.validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for ipType, cfg := range c.IpTypes {
+		if err := cfg.validate(); err != nil {
+			return fmt.Errorf("%s: %w", ipType, err)
+		}
+	}
+	return nil
+}
+
+func (c StrategyConfig) validate() error {
+	switch c.Strategy {
+	case "", StrategySumToOne, StrategyMinMax, StrategySoftmax, StrategyRank:
+	default:
+		return fmt.Errorf("unknown normalization strategy %q", c.Strategy)
+	}
+	if c.OutlierThreshold < 0 || c.Temperature < 0 {
+		return fmt.Errorf("outlier threshold and temperature must not be negative")
+	}
+	return nil
+}
+
+// Normalizer normalizes the priorities of routing changes before they are persisted
+type Normalizer struct {
+	config Config
+}
+
+// NewNormalizer creates a new Normalizer
+func NewNormalizer(config Config) *Normalizer {
+	return &Normalizer{
+		config: config,
+	}
+}
+
+// NormalizeChange normalizes the priorities of a routing change in place
+func (n *Normalizer) NormalizeChange(routingChange *domain.RoutingChange) error {
+	priorities := make([]float64, len(routingChange.InstancePriorityList))
+	for i, entry := range routingChange.InstancePriorityList {
+		priorities[i] = entry.Priority
+	}
+
+	normalized, err := n.Normalize(routingChange.IpType, priorities)
+	if err != nil {
+		return err
+	}
+
+	for i := range routingChange.InstancePriorityList {
+		routingChange.InstancePriorityList[i].Priority = normalized[i]
+	}
+	return nil
+}
+
+// Normalize maps the priorities of the given policy type onto the configured scale.
+// NaN and infinite priorities, and priorities too large to normalize, are rejected with a *domain.ValidationError.
+func (n *Normalizer) Normalize(ipType domain.ServiceIpType, priorities []float64) ([]float64, error) {
+	verr := &domain.ValidationError{}
+	for i, priority := range priorities {
+		if math.IsNaN(priority) || math.IsInf(priority, 0) {
+			verr.Add(fmt.Sprintf("instancePriorityList[%d].priority", i), "must be a finite number")
+		}
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	if len(priorities) == 0 {
+		return []float64{}, nil
+	}
+
+	cfg := n.config.For(ipType)
+	values := clampOutliers(priorities, cfg.OutlierThreshold)
+
+	var normalized []float64
+	switch cfg.Strategy {
+	case StrategyMinMax:
+		normalized = minMax(values)
+	case StrategySoftmax:
+		normalized = softmax(values, cfg.Temperature)
+	case StrategyRank:
+		normalized = rank(values)
+	default:
+		normalized = sumToOne(values)
+	}
+
+	// Finite priorities far apart can still overflow the strategy
+	for i, priority := range normalized {
+		if math.IsNaN(priority) || math.IsInf(priority, 0) {
+			verr.Add(fmt.Sprintf("instancePriorityList[%d].priority", i), "is out of range for normalization")
+		}
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// clampOutliers limits priorities to median ± threshold * MAD, leaving the input untouched
+func clampOutliers(priorities []float64, threshold float64) []float64 {
+	values := make([]float64, len(priorities))
+	copy(values, priorities)
+	if threshold <= 0 || len(values) < 3 {
+		return values
+	}
+
+	center := median(values)
+	deviations := make([]float64, len(values))
+	for i, value := range values {
+		deviations[i] = math.Abs(value - center)
+	}
+	spread := madScale * median(deviations)
+	if spread == 0 {
+		return values
+	}
+
+	lower, upper := center-threshold*spread, center+threshold*spread
+	for i, value := range values {
+		values[i] = math.Max(lower, math.Min(upper, value))
+	}
+	return values
+}
+
+// sumToOne shifts the priorities to be non-negative and scales them to sum up to 1.
+// If all priorities are equal after shifting, they are distributed evenly.
+func sumToOne(values []float64) []float64 {
+	lowest := values[0]
+	for _, value := range values {
+		lowest = math.Min(lowest, value)
+	}
+	shift := math.Min(lowest, 0)
+
+	sum := 0.0
+	for _, value := range values {
+		sum += value - shift
+	}
+
+	result := make([]float64, len(values))
+	for i, value := range values {
+		if sum == 0 {
+			result[i] = 1 / float64(len(values))
+		} else {
+			result[i] = (value - shift) / sum
+		}
+	}
+	return result
+}
+
+// minMax scales the priorities linearly onto [0, 1]. Equal priorities all map to 1.
+func minMax(values []float64) []float64 {
+	lowest, highest := values[0], values[0]
+	for _, value := range values {
+		lowest = math.Min(lowest, value)
+		highest = math.Max(highest, value)
+	}
+
+	result := make([]float64, len(values))
+	for i, value := range values {
+		if highest == lowest {
+			result[i] = 1
+		} else {
+			result[i] = (value - lowest) / (highest - lowest)
+		}
+	}
+	return result
+}
+
+// softmax maps the priorities onto a probability distribution
+func softmax(values []float64, temperature float64) []float64 {
+	if temperature <= 0 {
+		temperature = 1
+	}
+
+	highest := values[0]
+	for _, value := range values {
+		highest = math.Max(highest, value)
+	}
+
+	// Subtracting the maximum keeps the exponentials from overflowing
+	sum := 0.0
+	result := make([]float64, len(values))
+	for i, value := range values {
+		result[i] = math.Exp((value - highest) / temperature)
+		sum += result[i]
+	}
+	for i := range result {
+		result[i] /= sum
+	}
+	return result
+}
+
+// rank replaces the priorities by their rank scaled onto (0, 1], the highest priority maps to 1.
+// Tied priorities share their average rank.
+func rank(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+
+	n := float64(len(values))
+	result := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && values[order[end+1]] == values[order[start]] {
+			end++
+		}
+		// Ranks are 1-based, ascending with the priority
+		averageRank := float64(start+end)/2 + 1
+		for k := start; k <= end; k++ {
+			result[order[k]] = averageRank / n
+		}
+		start = end + 1
+	}
+	return result
+}
+
+// median returns the median of the values without modifying them
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
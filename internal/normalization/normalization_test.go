@@ -0,0 +1,322 @@
+package normalization
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+var ipTypes = []domain.ServiceIpType{
+	domain.ServiceIpTypeRoundRobin,
+	domain.ServiceIpTypeUnderutilized,
+	domain.ServiceIpTypeClosest,
+	domain.ServiceIpTypeFPS,
+}
+
+func assertPriorities(t *testing.T, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func softmaxOf(values ...float64) []float64 {
+	sum := 0.0
+	result := make([]float64, len(values))
+	for i, value := range values {
+		result[i] = math.Exp(value)
+		sum += result[i]
+	}
+	for i := range result {
+		result[i] /= sum
+	}
+	return result
+}
+
+func TestNormalizeStrategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     StrategyConfig
+		priorities []float64
+		want       []float64
+	}{
+		{
+			name:       "sum_to_one",
+			config:     StrategyConfig{Strategy: StrategySumToOne},
+			priorities: []float64{1, 2, 3, 4},
+			want:       []float64{0.1, 0.2, 0.3, 0.4},
+		},
+		{
+			name:       "sum_to_one shifts negatives",
+			config:     StrategyConfig{Strategy: StrategySumToOne},
+			priorities: []float64{-1, 0, 1, 3},
+			want:       []float64{0, 1.0 / 7, 2.0 / 7, 4.0 / 7},
+		},
+		{
+			name:       "sum_to_one is the default",
+			config:     StrategyConfig{},
+			priorities: []float64{1, 3},
+			want:       []float64{0.25, 0.75},
+		},
+		{
+			name:       "min_max",
+			config:     StrategyConfig{Strategy: StrategyMinMax},
+			priorities: []float64{-2, 0, 2, 6},
+			want:       []float64{0, 0.25, 0.5, 1},
+		},
+		{
+			name:       "softmax",
+			config:     StrategyConfig{Strategy: StrategySoftmax},
+			priorities: []float64{1, 2, 3},
+			want:       softmaxOf(1, 2, 3),
+		},
+		{
+			name:       "softmax temperature",
+			config:     StrategyConfig{Strategy: StrategySoftmax, Temperature: 2},
+			priorities: []float64{2, 4, 6},
+			want:       softmaxOf(1, 2, 3),
+		},
+		{
+			name:       "softmax does not overflow",
+			config:     StrategyConfig{Strategy: StrategySoftmax},
+			priorities: []float64{1000, 1000},
+			want:       []float64{0.5, 0.5},
+		},
+		{
+			name:       "rank",
+			config:     StrategyConfig{Strategy: StrategyRank},
+			priorities: []float64{30, -5, 10, 20},
+			want:       []float64{1, 0.25, 0.5, 0.75},
+		},
+		{
+			name:       "rank shares ties",
+			config:     StrategyConfig{Strategy: StrategyRank},
+			priorities: []float64{1, 1, 2},
+			want:       []float64{0.5, 0.5, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, ipType := range ipTypes {
+			t.Run(tt.name+"/"+string(ipType), func(t *testing.T) {
+				n := NewNormalizer(Config{IpTypes: map[domain.ServiceIpType]StrategyConfig{ipType: tt.config}})
+
+				got, err := n.Normalize(ipType, tt.priorities)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertPriorities(t, got, tt.want)
+			})
+		}
+	}
+}
+
+func TestNormalizeUsesStrategyOfIpType(t *testing.T) {
+	n := NewNormalizer(Config{
+		Default: StrategyConfig{Strategy: StrategySumToOne},
+		IpTypes: map[domain.ServiceIpType]StrategyConfig{
+			domain.ServiceIpTypeClosest: {Strategy: StrategyMinMax},
+			domain.ServiceIpTypeFPS:     {Strategy: StrategyRank},
+		},
+	})
+
+	want := map[domain.ServiceIpType][]float64{
+		domain.ServiceIpTypeRoundRobin:    {0.2, 0.8},
+		domain.ServiceIpTypeUnderutilized: {0.2, 0.8},
+		domain.ServiceIpTypeClosest:       {0, 1},
+		domain.ServiceIpTypeFPS:           {0.5, 1},
+	}
+	for _, ipType := range ipTypes {
+		got, err := n.Normalize(ipType, []float64{1, 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertPriorities(t, got, want[ipType])
+	}
+}
+
+func TestNormalizeEdgeCases(t *testing.T) {
+	tests := []struct {
+		strategy Strategy
+		// equal holds the result of three equal priorities, single that of a single priority
+		equal  []float64
+		single []float64
+	}{
+		{strategy: StrategySumToOne, equal: []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}, single: []float64{1}},
+		{strategy: StrategyMinMax, equal: []float64{1, 1, 1}, single: []float64{1}},
+		{strategy: StrategySoftmax, equal: []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}, single: []float64{1}},
+		{strategy: StrategyRank, equal: []float64{2.0 / 3, 2.0 / 3, 2.0 / 3}, single: []float64{1}},
+	}
+
+	for _, tt := range tests {
+		for _, ipType := range ipTypes {
+			t.Run(string(tt.strategy)+"/"+string(ipType), func(t *testing.T) {
+				n := NewNormalizer(Config{Default: StrategyConfig{Strategy: tt.strategy}})
+
+				for _, equal := range [][]float64{{5, 5, 5}, {0, 0, 0}, {-2, -2, -2}} {
+					got, err := n.Normalize(ipType, equal)
+					if err != nil {
+						t.Fatal(err)
+					}
+					assertPriorities(t, got, tt.equal)
+				}
+
+				for _, single := range []float64{-3, 0, 0.5, 42} {
+					got, err := n.Normalize(ipType, []float64{single})
+					if err != nil {
+						t.Fatal(err)
+					}
+					assertPriorities(t, got, tt.single)
+				}
+
+				got, err := n.Normalize(ipType, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertPriorities(t, got, []float64{})
+			})
+		}
+	}
+}
+
+func TestNormalizeRejectsNonFinite(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   Strategy
+		priorities []float64
+		fields     []string
+	}{
+		{
+			name:       "NaN",
+			priorities: []float64{0.5, math.NaN()},
+			fields:     []string{"instancePriorityList[1].priority"},
+		},
+		{
+			name:       "infinity",
+			priorities: []float64{math.Inf(1), 0.5, math.Inf(-1)},
+			fields:     []string{"instancePriorityList[0].priority", "instancePriorityList[2].priority"},
+		},
+		{
+			name:       "overflow sum_to_one",
+			strategy:   StrategySumToOne,
+			priorities: []float64{1.7e308, 1.7e308, -1.7e308},
+			fields:     []string{"instancePriorityList[0].priority", "instancePriorityList[1].priority"},
+		},
+		{
+			name:       "overflow min_max",
+			strategy:   StrategyMinMax,
+			priorities: []float64{1.7e308, 1.7e308, -1.7e308},
+			fields:     []string{"instancePriorityList[0].priority", "instancePriorityList[1].priority"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, ipType := range ipTypes {
+			t.Run(tt.name+"/"+string(ipType), func(t *testing.T) {
+				n := NewNormalizer(Config{Default: StrategyConfig{Strategy: tt.strategy}})
+
+				_, err := n.Normalize(ipType, tt.priorities)
+				var verr *domain.ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("got error %v, want a validation error", err)
+				}
+				if len(verr.Violations) != len(tt.fields) {
+					t.Fatalf("got violations %+v, want fields %v", verr.Violations, tt.fields)
+				}
+				for i, field := range tt.fields {
+					if verr.Violations[i].Field != field {
+						t.Errorf("got field %q, want %q", verr.Violations[i].Field, field)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestClampOutliers(t *testing.T) {
+	// The median of the values is 3, the median absolute deviation 1
+	spread := madScale * 1
+
+	tests := []struct {
+		name      string
+		values    []float64
+		threshold float64
+		want      []float64
+	}{
+		{
+			name:      "clamps high outlier",
+			values:    []float64{1, 2, 3, 4, 100},
+			threshold: 2,
+			want:      []float64{1, 2, 3, 4, 3 + 2*spread},
+		},
+		{
+			name:      "clamps low outlier",
+			values:    []float64{-100, 2, 3, 4, 5},
+			threshold: 1,
+			want:      []float64{3 - spread, 2, 3, 4, 3 + spread},
+		},
+		{
+			name:      "disabled",
+			values:    []float64{1, 2, 3, 4, 100},
+			threshold: 0,
+			want:      []float64{1, 2, 3, 4, 100},
+		},
+		{
+			name:      "too few values",
+			values:    []float64{1, 100},
+			threshold: 1,
+			want:      []float64{1, 100},
+		},
+		{
+			name:      "no spread",
+			values:    []float64{3, 3, 3, 100},
+			threshold: 1,
+			want:      []float64{3, 3, 3, 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]float64(nil), tt.values...)
+			assertPriorities(t, clampOutliers(input, tt.threshold), tt.want)
+			assertPriorities(t, input, tt.values)
+		})
+	}
+}
+
+func TestNormalizeClampsBeforeNormalizing(t *testing.T) {
+	for _, ipType := range ipTypes {
+		n := NewNormalizer(Config{Default: StrategyConfig{Strategy: StrategyMinMax, OutlierThreshold: 2}})
+
+		got, err := n.Normalize(ipType, []float64{1, 2, 3, 4, 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Without clamping the outlier would push every other priority below 0.04
+		upper := 3 + 2*madScale
+		assertPriorities(t, got, []float64{0, 1 / (upper - 1), 2 / (upper - 1), 3 / (upper - 1), 1})
+	}
+}
+
+func TestNormalizeChange(t *testing.T) {
+	n := NewNormalizer(Config{})
+	change := &domain.RoutingChange{
+		IpType: domain.ServiceIpTypeClosest,
+		InstancePriorityList: []domain.InstancePriorityEntry{
+			{InstanceID: "0", Priority: 3},
+			{InstanceID: "1", Priority: 1},
+		},
+	}
+
+	if err := n.NormalizeChange(change); err != nil {
+		t.Fatal(err)
+	}
+	assertPriorities(t, []float64{change.InstancePriorityList[0].Priority, change.InstancePriorityList[1].Priority}, []float64{0.75, 0.25})
+}
@@ -0,0 +1,250 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"go.uber.org/zap"
+)
+
+// notifyTimeout bounds the delivery of a single notification
+const notifyTimeout = 10 * time.Second
+
+// Config holds the routing, grouping and rate limiting of notifications
+type Config struct {
+	// GroupWait is how long alerts are collected before the first notification of a group is sent
+	GroupWait time.Duration
+	// GroupInterval is the minimum time between two notifications of a group to the same channel,
+	// alerts arriving in the meantime are sent together once it has passed
+	GroupInterval time.Duration
+	// GroupBy lists the alert fields notifications are grouped by
+	GroupBy []GroupBy
+	// Routes decide which channels an alert is sent to, an alert matching several routes is sent to all of their channels
+	Routes []Route
+}
+
+// group collects the alerts of a notification that is yet to be sent
+type group struct {
+	channel  string
+	key      string
+	status   Status
+	alerts   []*domain.Alert
+	timer    *time.Timer
+	lastSent time.Time
+}
+
+// Dispatcher sends raised and resolved alerts to the notification channels.
+// It is registered as an observer of the alert subject.
+type Dispatcher struct {
+	notifiers map[string]Notifier
+	config    Config
+	logger    *zap.Logger
+
+	mutex   sync.Mutex
+	groups  map[string]*group
+	stopped bool
+}
+
+var _ domain.AlertObserver = &Dispatcher{}
+
+// NewDispatcher creates a dispatcher sending to the named notifiers
+func NewDispatcher(notifiers map[string]Notifier, config Config, logger *zap.Logger) (*Dispatcher, error) {
+	for i, route := range config.Routes {
+		if len(route.Channels) == 0 {
+			return nil, fmt.Errorf("route %d has no channels", i)
+		}
+		for _, channel := range route.Channels {
+			if _, ok := notifiers[channel]; !ok {
+				return nil, fmt.Errorf("route %d refers to unknown channel %s", i, channel)
+			}
+		}
+	}
+	for _, field := range config.GroupBy {
+		switch field {
+		case GroupByNamespace, GroupByApp, GroupBySeverity, GroupBySource:
+		default:
+			return nil, fmt.Errorf("invalid group by field: %s", field)
+		}
+	}
+
+	return &Dispatcher{
+		notifiers: notifiers,
+		config:    config,
+		logger:    logger,
+		groups:    make(map[string]*group),
+	}, nil
+}
+
+// OnAlertEvent queues the alert for every channel of the matching routes
+func (d *Dispatcher) OnAlertEvent(event domain.AlertEvent) {
+	var status Status
+	switch event.Type {
+	case domain.AlertRaised:
+		status = StatusFiring
+	case domain.AlertResolved:
+		status = StatusResolved
+	default:
+		return
+	}
+
+	channels := make(map[string]bool)
+	for i := range d.config.Routes {
+		if d.config.Routes[i].Matches(event.Alert) {
+			for _, channel := range d.config.Routes[i].Channels {
+				channels[channel] = true
+			}
+		}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.stopped {
+		return
+	}
+
+	key := groupKey(d.config.GroupBy, event.Alert)
+	for channel := range channels {
+		// An alert resolved before it was notified as firing is not sent at all
+		if status == StatusResolved && d.withdraw(groupID(channel, StatusFiring, key), event.Alert) {
+			continue
+		}
+		d.enqueue(channel, status, key, event.Alert)
+	}
+}
+
+// GetID returns the ID of the observer
+func (d *Dispatcher) GetID() string {
+	return "NotificationDispatcher"
+}
+
+// Stop sends the pending notifications and ignores further alerts
+func (d *Dispatcher) Stop() {
+	d.mutex.Lock()
+	d.stopped = true
+	pending := make([]string, 0, len(d.groups))
+	for id, g := range d.groups {
+		if g.timer != nil && g.timer.Stop() {
+			pending = append(pending, id)
+		}
+	}
+	d.mutex.Unlock()
+
+	for _, id := range pending {
+		d.flush(id)
+	}
+}
+
+// enqueue adds the alert to its group and schedules the notification of the group
+func (d *Dispatcher) enqueue(channel string, status Status, key string, alert *domain.Alert) {
+	id := groupID(channel, status, key)
+	g, ok := d.groups[id]
+	if !ok {
+		g = &group{channel: channel, key: key, status: status}
+		d.groups[id] = g
+	}
+
+	// A repeated alert replaces its pending copy
+	replaced := false
+	for i, pending := range g.alerts {
+		if pending.ID == alert.ID {
+			g.alerts[i] = alert
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		g.alerts = append(g.alerts, alert)
+	}
+
+	if g.timer != nil {
+		return
+	}
+
+	delay := d.config.GroupWait
+	if next := time.Until(g.lastSent.Add(d.config.GroupInterval)); next > delay {
+		delay = next
+	}
+	g.timer = time.AfterFunc(delay, func() { d.flush(id) })
+}
+
+// withdraw removes the alert from a pending group, it reports whether the alert was pending
+func (d *Dispatcher) withdraw(id string, alert *domain.Alert) bool {
+	g, ok := d.groups[id]
+	if !ok {
+		return false
+	}
+	for i, pending := range g.alerts {
+		if pending.ID == alert.ID {
+			g.alerts = append(g.alerts[:i], g.alerts[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// flush sends the pending alerts of a group
+func (d *Dispatcher) flush(id string) {
+	d.mutex.Lock()
+	g, ok := d.groups[id]
+	if !ok {
+		d.mutex.Unlock()
+		return
+	}
+	alerts := g.alerts
+	g.alerts = nil
+	g.timer = nil
+	now := time.Now()
+	if len(alerts) > 0 {
+		g.lastSent = now
+	}
+	d.prune(now)
+	d.mutex.Unlock()
+
+	if len(alerts) == 0 {
+		return
+	}
+
+	notification := &Notification{
+		Channel:  g.channel,
+		GroupKey: g.key,
+		Status:   g.status,
+		Alerts:   alerts,
+		SentAt:   now,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	if err := d.notifiers[g.channel].Notify(ctx, notification); err != nil {
+		d.logger.Error("Failed to send notification",
+			zap.String("channel", g.channel),
+			zap.String("group", g.key),
+			zap.String("status", string(g.status)),
+			zap.Int("alerts", len(alerts)),
+			zap.Error(err))
+		return
+	}
+
+	d.logger.Debug("Sent notification",
+		zap.String("channel", g.channel),
+		zap.String("group", g.key),
+		zap.String("status", string(g.status)),
+		zap.Int("alerts", len(alerts)))
+}
+
+// prune drops idle groups whose rate limit has passed
+func (d *Dispatcher) prune(now time.Time) {
+	for id, g := range d.groups {
+		if g.timer == nil && len(g.alerts) == 0 && now.Sub(g.lastSent) >= d.config.GroupInterval {
+			delete(d.groups, id)
+		}
+	}
+}
+
+func groupID(channel string, status Status, key string) string {
+	return channel + "|" + string(status) + "|" + key
+}
@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileNotifier writes notifications as newline delimited JSON, e.g. to a file or stdout
+type FileNotifier struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewFileNotifier creates a notifier writing to the writer
+func NewFileNotifier(writer io.Writer) *FileNotifier {
+	return &FileNotifier{writer: writer}
+}
+
+// OpenFileNotifier creates a notifier appending to the file at the path, "-" writes to stdout
+func OpenFileNotifier(path string) (*FileNotifier, error) {
+	if path == "-" {
+		return NewFileNotifier(os.Stdout), nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewFileNotifier(file), nil
+}
+
+// Notify writes the notification as a single line
+func (n *FileNotifier) Notify(ctx context.Context, notification *Notification) error {
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	_, err = n.writer.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,31 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/smnzlnsk/routing-manager/internal/mqtt"
+)
+
+// MQTTNotifier publishes notifications as JSON to an MQTT topic
+type MQTTNotifier struct {
+	client mqtt.Client
+	topic  string
+}
+
+// NewMQTTNotifier creates a notifier publishing to the topic
+func NewMQTTNotifier(client mqtt.Client, topic string) *MQTTNotifier {
+	return &MQTTNotifier{
+		client: client,
+		topic:  topic,
+	}
+}
+
+// Notify publishes the notification
+func (n *MQTTNotifier) Notify(ctx context.Context, notification *Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	return n.client.Publish(n.topic, payload)
+}
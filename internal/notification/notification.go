@@ -0,0 +1,112 @@
+package notification
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+// Status tells whether the alerts of a notification fired or were resolved
+type Status string
+
+const (
+	StatusFiring   Status = "firing"
+	StatusResolved Status = "resolved"
+)
+
+// Notification groups the alerts sent to a channel at once
+type Notification struct {
+	Channel  string          `json:"channel"`
+	GroupKey string          `json:"groupKey"`
+	Status   Status          `json:"status"`
+	Alerts   []*domain.Alert `json:"alerts"`
+	SentAt   time.Time       `json:"sentAt"`
+}
+
+// Notifier delivers notifications to a single channel
+type Notifier interface {
+	Notify(ctx context.Context, notification *Notification) error
+}
+
+// Route sends the alerts it matches to its channels. Empty matchers match every alert.
+type Route struct {
+	Channels   []string
+	Namespaces []string
+	// Apps and Sources are glob patterns
+	Apps       string
+	Sources    string
+	Severities []domain.AlertSeverity
+}
+
+// Matches reports whether the alert is sent along the route
+func (r *Route) Matches(alert *domain.Alert) bool {
+	if len(r.Namespaces) > 0 && !containsString(r.Namespaces, alert.Namespace) {
+		return false
+	}
+	if !globMatches(r.Apps, alert.AppName) || !globMatches(r.Sources, alert.Source) {
+		return false
+	}
+	if len(r.Severities) > 0 {
+		matched := false
+		for _, severity := range r.Severities {
+			if severity == alert.Severity {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// GroupBy names an alert field notifications are grouped by
+type GroupBy string
+
+const (
+	GroupByNamespace GroupBy = "namespace"
+	GroupByApp       GroupBy = "app"
+	GroupBySeverity  GroupBy = "severity"
+	GroupBySource    GroupBy = "source"
+)
+
+// groupKey joins the grouped fields of the alert
+func groupKey(groupBy []GroupBy, alert *domain.Alert) string {
+	key := ""
+	for i, field := range groupBy {
+		if i > 0 {
+			key += "/"
+		}
+		switch field {
+		case GroupByNamespace:
+			key += alert.Namespace
+		case GroupByApp:
+			key += alert.AppName
+		case GroupBySeverity:
+			key += string(alert.Severity)
+		case GroupBySource:
+			key += alert.Source
+		}
+	}
+	return key
+}
+
+func globMatches(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package observer
+
+import (
+	"sync"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"go.uber.org/zap"
+)
+
+// AlertSubject notifies registered observers of alert events
+type AlertSubject struct {
+	observers map[string]domain.AlertObserver
+	mutex     sync.RWMutex
+	logger    *zap.Logger
+}
+
+// NewAlertSubject creates a new instance of AlertSubject
+func NewAlertSubject(logger *zap.Logger) *AlertSubject {
+	return &AlertSubject{
+		observers: make(map[string]domain.AlertObserver),
+		logger:    logger,
+	}
+}
+
+// Register adds an observer to the notification list
+func (s *AlertSubject) Register(obs domain.AlertObserver) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.observers[obs.GetID()] = obs
+	s.logger.Debug("Alert observer registered", zap.String("observer", obs.GetID()))
+}
+
+// Deregister removes an observer from the notification list
+func (s *AlertSubject) Deregister(obs domain.AlertObserver) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.observers, obs.GetID())
+	s.logger.Debug("Alert observer deregistered", zap.String("observer", obs.GetID()))
+}
+
+// Notify notifies all observers of an event
+func (s *AlertSubject) Notify(event domain.AlertEvent) {
+	s.mutex.RLock()
+	observers := make([]domain.AlertObserver, 0, len(s.observers))
+	for _, obs := range s.observers {
+		observers = append(observers, obs)
+	}
+	s.mutex.RUnlock()
+
+	s.logger.Debug("Notifying alert observers",
+		zap.String("eventType", string(event.Type)),
+		zap.String("namespace", event.Alert.Namespace),
+		zap.String("appName", event.Alert.AppName),
+		zap.Int("observerCount", len(observers)))
+
+	for _, obs := range observers {
+		go obs.OnAlertEvent(event)
+	}
+}
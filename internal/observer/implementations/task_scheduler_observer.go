@@ -15,6 +15,7 @@ type TaskSchedulerObserver struct {
 	schedulers   map[string]*time.Ticker
 	done         map[string]chan bool
 	interval     time.Duration
+	quotas       domain.NamespaceQuotas
 	mutex        sync.Mutex
 }
 
@@ -37,19 +38,31 @@ func NewTaskSchedulerObserver(
 	}
 }
 
+// WithNamespaceQuotas applies the minimum scheduling interval of each namespace
+func (o *TaskSchedulerObserver) WithNamespaceQuotas(quotas domain.NamespaceQuotas) *TaskSchedulerObserver {
+	o.quotas = quotas
+	return o
+}
+
+// intervalFor returns the scheduling interval for the interests of a namespace
+func (o *TaskSchedulerObserver) intervalFor(namespace string) time.Duration {
+	if minInterval := o.quotas.For(namespace).MinInterval; minInterval > o.interval {
+		return minInterval
+	}
+	return o.interval
+}
+
 // Update handles interest events by starting or stopping the scheduled tasks
 func (o *TaskSchedulerObserver) Update(event domain.InterestEvent) {
 	interest := event.Interest
-	appName := interest.AppName
+	key := interest.Key()
 
 	switch event.Type {
-	case domain.InterestCreated:
-		o.startTaskScheduler(interest)
-
-	case domain.InterestUpdated:
-		// If we have a scheduler, stop it and start a new one with the updated interest
-		if o.hasScheduler(appName) {
-			o.stopTaskScheduler(appName)
+	case domain.InterestCreated, domain.InterestUpdated:
+		// If we have a scheduler, stop it and start a new one with the updated interest.
+		// Interests may be reported as created more than once, e.g. by the API and by a repository watcher.
+		if o.hasScheduler(key) {
+			o.stopTaskScheduler(key)
 			o.startTaskScheduler(interest)
 		} else {
 			// If no scheduler exists, start a new one
@@ -58,7 +71,7 @@ func (o *TaskSchedulerObserver) Update(event domain.InterestEvent) {
 
 	case domain.InterestDeleted:
 		// Stop the scheduler for deleted interests
-		o.stopTaskScheduler(appName)
+		o.stopTaskScheduler(key)
 	}
 }
 
@@ -68,25 +81,33 @@ func (o *TaskSchedulerObserver) startTaskScheduler(interest *domain.Interest) {
 	defer o.mutex.Unlock()
 
 	appName := interest.AppName
+	key := interest.Key()
+	interval := o.intervalFor(interest.Namespace)
 
 	// Make a copy of the interest to prevent issues with concurrent access
 	interestCopy := &domain.Interest{
-		AppName:   interest.AppName,
-		ServiceIp: interest.ServiceIp,
-		CreatedAt: interest.CreatedAt,
-		UpdatedAt: interest.UpdatedAt,
+		Namespace:   interest.Namespace,
+		AppName:     interest.AppName,
+		ServiceIp:   interest.ServiceIp,
+		ServiceIpV4: interest.ServiceIpV4,
+		ServiceIpV6: interest.ServiceIpV6,
+		Policies:    interest.Policies,
+		Version:     interest.Version,
+		CreatedAt:   interest.CreatedAt,
+		UpdatedAt:   interest.UpdatedAt,
 	}
 
 	// Create a ticker for the scheduler
-	ticker := time.NewTicker(o.interval)
+	ticker := time.NewTicker(interval)
 	done := make(chan bool)
 
-	o.schedulers[appName] = ticker
-	o.done[appName] = done
+	o.schedulers[key] = ticker
+	o.done[key] = done
 
 	o.logger.Info("Started task scheduler",
+		zap.String("namespace", interest.Namespace),
 		zap.String("appName", appName),
-		zap.Duration("interval", o.interval))
+		zap.Duration("interval", interval))
 
 	// Start the scheduler in a goroutine
 	go func() {
@@ -103,6 +124,7 @@ func (o *TaskSchedulerObserver) startTaskScheduler(interest *domain.Interest) {
 			case <-ticker.C:
 				if err := o.taskExecutor.ExecuteTask(interestCopy); err != nil {
 					o.logger.Error("Failed to execute scheduled task",
+						zap.String("namespace", interestCopy.Namespace),
 						zap.String("appName", appName),
 						zap.Error(err))
 				}
@@ -113,40 +135,57 @@ func (o *TaskSchedulerObserver) startTaskScheduler(interest *domain.Interest) {
 	}()
 }
 
-// stopTaskScheduler stops the scheduler for the given app name
-func (o *TaskSchedulerObserver) stopTaskScheduler(appName string) {
+// stopTaskScheduler stops the scheduler for the given interest key
+func (o *TaskSchedulerObserver) stopTaskScheduler(key string) {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	if ticker, ok := o.schedulers[appName]; ok {
+	if ticker, ok := o.schedulers[key]; ok {
 		ticker.Stop()
-		close(o.done[appName])
+		close(o.done[key])
 
-		delete(o.schedulers, appName)
-		delete(o.done, appName)
+		delete(o.schedulers, key)
+		delete(o.done, key)
 
-		o.logger.Info("Stopped task scheduler", zap.String("appName", appName))
+		o.logger.Info("Stopped task scheduler", zap.String("interest", key))
 	}
 }
 
-// hasScheduler checks if a scheduler exists for the given app name
-func (o *TaskSchedulerObserver) hasScheduler(appName string) bool {
+// hasScheduler checks if a scheduler exists for the given interest key
+func (o *TaskSchedulerObserver) hasScheduler(key string) bool {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	_, exists := o.schedulers[appName]
+	_, exists := o.schedulers[key]
 	return exists
 }
 
+// ScheduledKeys lists the keys of the interests with a running scheduler
+func (o *TaskSchedulerObserver) ScheduledKeys() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	keys := make([]string, 0, len(o.schedulers))
+	for key := range o.schedulers {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// StopScheduler stops the scheduler of the given interest key without deleting the interest
+func (o *TaskSchedulerObserver) StopScheduler(key string) {
+	o.stopTaskScheduler(key)
+}
+
 // Shutdown stops all schedulers
 func (o *TaskSchedulerObserver) Shutdown() {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	for appName, ticker := range o.schedulers {
+	for key, ticker := range o.schedulers {
 		ticker.Stop()
-		close(o.done[appName])
-		o.logger.Info("Stopped task scheduler during shutdown", zap.String("appName", appName))
+		close(o.done[key])
+		o.logger.Info("Stopped task scheduler during shutdown", zap.String("interest", key))
 	}
 
 	o.schedulers = make(map[string]*time.Ticker)
@@ -0,0 +1,61 @@
+package observer
+
+import (
+	"sync"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"go.uber.org/zap"
+)
+
+// RoutingSubject notifies registered observers of routing events
+type RoutingSubject struct {
+	observers map[string]domain.RoutingObserver
+	mutex     sync.RWMutex
+	logger    *zap.Logger
+}
+
+// NewRoutingSubject creates a new instance of RoutingSubject
+func NewRoutingSubject(logger *zap.Logger) *RoutingSubject {
+	return &RoutingSubject{
+		observers: make(map[string]domain.RoutingObserver),
+		logger:    logger,
+	}
+}
+
+// Register adds an observer to the notification list
+func (s *RoutingSubject) Register(obs domain.RoutingObserver) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.observers[obs.GetID()] = obs
+	s.logger.Debug("Routing observer registered", zap.String("observer", obs.GetID()))
+}
+
+// Deregister removes an observer from the notification list
+func (s *RoutingSubject) Deregister(obs domain.RoutingObserver) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.observers, obs.GetID())
+	s.logger.Debug("Routing observer deregistered", zap.String("observer", obs.GetID()))
+}
+
+// Notify notifies all observers of an event
+func (s *RoutingSubject) Notify(event domain.RoutingEvent) {
+	s.mutex.RLock()
+	observers := make([]domain.RoutingObserver, 0, len(s.observers))
+	for _, obs := range s.observers {
+		observers = append(observers, obs)
+	}
+	s.mutex.RUnlock()
+
+	s.logger.Debug("Notifying routing observers",
+		zap.String("eventType", string(event.Type)),
+		zap.String("appName", event.Snapshot.AppName),
+		zap.Int64("version", event.Snapshot.Version),
+		zap.Int("observerCount", len(observers)))
+
+	for _, obs := range observers {
+		go obs.OnRoutingEvent(event)
+	}
+}
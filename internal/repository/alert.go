@@ -2,11 +2,21 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/smnzlnsk/routing-manager/internal/domain"
 )
 
 type AlertRepository interface {
+	// Create stores an alert and assigns it an ID
 	Create(ctx context.Context, alert *domain.Alert) error
-	GetByAppName(ctx context.Context, appName string) (*domain.Alert, error)
+	Get(ctx context.Context, id string) (*domain.Alert, error)
+	// List returns the alerts passing the filter, newest first
+	List(ctx context.Context, filter domain.AlertFilter) ([]*domain.Alert, error)
+	// RecordDuplicate counts another occurrence of the newest unresolved alert with the fingerprint that was
+	// last seen at or after since. It returns the updated alert, or domain.ErrNotFound if there is no such alert.
+	RecordDuplicate(ctx context.Context, fingerprint string, since, seenAt time.Time) (*domain.Alert, error)
+	// Transition moves an alert to the given state and records who did so and when.
+	// It fails with domain.ErrInvalidTransition if the alert cannot be moved to the state from its current one.
+	Transition(ctx context.Context, id string, to domain.AlertState, by string, at time.Time) (*domain.Alert, error)
 }
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+type AlertRuleRepository interface {
+	// Create stores a rule and assigns it an ID, rule names are unique within a namespace
+	Create(ctx context.Context, rule *domain.AlertRule) error
+	Get(ctx context.Context, id string) (*domain.AlertRule, error)
+	// List returns the rules of the namespace, ordered by name
+	List(ctx context.Context) ([]*domain.AlertRule, error)
+	Delete(ctx context.Context, id string) error
+}
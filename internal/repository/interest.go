@@ -10,8 +10,17 @@ type InterestRepository interface {
 	Create(ctx context.Context, interest *domain.Interest) error
 	GetByAppName(ctx context.Context, appName string) (*domain.Interest, error)
 	GetByServiceIp(ctx context.Context, serviceIp string) (*domain.Interest, error)
-	Update(ctx context.Context, interest *domain.Interest) (*domain.Interest, error)
-	DeleteByAppName(ctx context.Context, appName string) error
-	DeleteByServiceIp(ctx context.Context, serviceIp string) error
+	// Update, DeleteByAppName and DeleteByServiceIp only succeed if the stored interest carries
+	// the expected version, unless domain.AnyVersion is passed.
+	Update(ctx context.Context, interest *domain.Interest, expectedVersion int64) (*domain.Interest, error)
+	DeleteByAppName(ctx context.Context, appName string, expectedVersion int64) error
+	DeleteByServiceIp(ctx context.Context, serviceIp string, expectedVersion int64) error
 	List(ctx context.Context) ([]*domain.Interest, error)
 }
+
+// InterestWatcher is implemented by interest repositories whose interests can change
+// outside of the routing-manager. Watch blocks until the context is cancelled and
+// reports every observed change as an interest event.
+type InterestWatcher interface {
+	Watch(ctx context.Context, notify func(event domain.InterestEvent)) error
+}
@@ -8,4 +8,8 @@ import (
 
 type JobRepository interface {
 	GetByJobName(ctx context.Context, jobName string) (*domain.Job, error)
+	// List returns the jobs passing the filter, ordered by job name
+	List(ctx context.Context, filter domain.JobFilter) ([]*domain.Job, error)
+	// Watch calls notify for every created, updated or deleted job until the context is done
+	Watch(ctx context.Context, notify func(event domain.JobEvent)) error
 }
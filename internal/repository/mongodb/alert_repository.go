@@ -2,11 +2,14 @@ package mongodb
 
 import (
 	"context"
+	"time"
 
 	"github.com/smnzlnsk/routing-manager/internal/domain"
 	"github.com/smnzlnsk/routing-manager/internal/repository"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
@@ -16,20 +19,72 @@ type alertRepository struct {
 	logger     *zap.Logger
 }
 
-// NewAlertRepository creates a new MongoDB-based alert repository
-func NewAlertRepository(db *mongo.Database, collection string, logger *zap.Logger) repository.AlertRepository {
+// resolvedAlertTTLIndex is the name of the index removing resolved alerts after the retention period
+const resolvedAlertTTLIndex = "resolvedat_ttl"
+
+// NewAlertRepository creates a new MongoDB-based alert repository.
+// Resolved alerts are removed by a TTL index once the retention period has passed since their resolution.
+func NewAlertRepository(db *mongo.Database, collection string, retention time.Duration, logger *zap.Logger) repository.AlertRepository {
 	coll := db.Collection(collection)
 
+	indexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "appname", Value: 1}, {Key: "createdat", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "fingerprint", Value: 1}, {Key: "lastseenat", Value: -1}},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	backfillNamespace(ctx, coll, "namespace", bson.M{}, logger)
+
+	if _, err := coll.Indexes().CreateMany(ctx, indexModels); err != nil {
+		logger.Error("Failed to create alert indexes", zap.Error(err))
+	}
+
+	ensureRetention(ctx, db, coll, retention, logger)
+
 	return &alertRepository{
 		collection: coll,
 		logger:     logger,
 	}
 }
 
+// ensureRetention creates the TTL index on the resolution time, or adapts its expiry to a changed retention period
+func ensureRetention(ctx context.Context, db *mongo.Database, coll *mongo.Collection, retention time.Duration, logger *zap.Logger) {
+	expireAfter := int32(retention / time.Second)
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "resolvedat", Value: 1}},
+		Options: options.Index().SetName(resolvedAlertTTLIndex).SetExpireAfterSeconds(expireAfter),
+	})
+	if err == nil {
+		return
+	}
+
+	// The index exists with a different retention period
+	err = db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: coll.Name()},
+		{Key: "index", Value: bson.D{
+			{Key: "name", Value: resolvedAlertTTLIndex},
+			{Key: "expireAfterSeconds", Value: expireAfter},
+		}},
+	}).Err()
+	if err != nil {
+		logger.Error("Failed to create TTL index on resolved alerts", zap.Error(err))
+	}
+}
+
 // Create adds a new alert to the database
 func (r *alertRepository) Create(ctx context.Context, alert *domain.Alert) error {
 	r.logger.Debug("Creating alert in MongoDB", zap.String("appName", alert.AppName))
 
+	alert.ID = primitive.NewObjectID().Hex()
 	_, err := r.collection.InsertOne(ctx, alert)
 	if err != nil {
 		return err
@@ -37,15 +92,156 @@ func (r *alertRepository) Create(ctx context.Context, alert *domain.Alert) error
 	return nil
 }
 
-// GetByAppName retrieves an alert by app name
-func (r *alertRepository) GetByAppName(ctx context.Context, appName string) (*domain.Alert, error) {
-	r.logger.Debug("Getting alert by app name", zap.String("appName", appName))
+// Get retrieves an alert by its ID
+func (r *alertRepository) Get(ctx context.Context, id string) (*domain.Alert, error) {
+	r.logger.Debug("Getting alert from MongoDB", zap.String("id", id))
 
-	// Find the alert by app name
 	var alert domain.Alert
-	err := r.collection.FindOne(ctx, bson.M{"appname": appName}).Decode(&alert)
+	err := r.collection.FindOne(ctx, r.scoped(ctx, bson.M{"id": id})).Decode(&alert)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// List retrieves the alerts passing the filter, newest first
+func (r *alertRepository) List(ctx context.Context, filter domain.AlertFilter) ([]*domain.Alert, error) {
+	r.logger.Debug("Listing alerts from MongoDB", zap.Any("filter", filter))
+
+	query := bson.M{}
+	if filter.AppName != "" {
+		query["appname"] = filter.AppName
+	}
+	if filter.State != "" {
+		query["state"] = bson.M{"$in": stateValues(filter.State)}
+	}
+	if filter.Severity != "" {
+		query["severity"] = filter.Severity
+	}
+	if filter.Since != nil {
+		query["lastseenat"] = bson.M{"$gte": *filter.Since}
+	}
+	if filter.Until != nil {
+		query["createdat"] = bson.M{"$lte": *filter.Until}
+	}
+
+	cursor, err := r.collection.Find(ctx, r.scoped(ctx, query),
+		options.Find().SetSort(bson.D{{Key: "createdat", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []*domain.Alert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		return nil, err
+	}
+	for _, alert := range alerts {
+		if alert.State == "" {
+			alert.State = domain.AlertStateFiring
+		}
+	}
+	return alerts, nil
+}
+
+// RecordDuplicate counts another occurrence of a recent alert with the same fingerprint
+func (r *alertRepository) RecordDuplicate(ctx context.Context, fingerprint string, since, seenAt time.Time) (*domain.Alert, error) {
+	r.logger.Debug("Recording duplicate alert in MongoDB", zap.String("fingerprint", fingerprint))
+
+	result := r.collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"fingerprint": fingerprint,
+			"lastseenat":  bson.M{"$gte": since},
+			"state":       bson.M{"$ne": domain.AlertStateResolved},
+		},
+		bson.M{
+			"$inc": bson.M{"count": 1},
+			"$set": bson.M{"lastseenat": seenAt},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "lastseenat", Value: -1}}).
+			SetReturnDocument(options.After),
+	)
+	if result.Err() != nil {
+		if result.Err() == mongo.ErrNoDocuments {
+			return nil, domain.ErrNotFound
+		}
+		return nil, result.Err()
+	}
+
+	var alert domain.Alert
+	if err := result.Decode(&alert); err != nil {
 		return nil, err
 	}
 	return &alert, nil
 }
+
+// Transition moves an alert to the given state if its current state allows it
+func (r *alertRepository) Transition(ctx context.Context, id string, to domain.AlertState, by string, at time.Time) (*domain.Alert, error) {
+	r.logger.Debug("Transitioning alert in MongoDB",
+		zap.String("id", id),
+		zap.String("state", string(to)),
+		zap.String("by", by))
+
+	sources := domain.AlertTransitionSources(to)
+	if len(sources) == 0 {
+		return nil, domain.ErrInvalidTransition
+	}
+	states := bson.A{}
+	for _, source := range sources {
+		states = append(states, stateValues(source)...)
+	}
+
+	set := bson.M{"state": to}
+	switch to {
+	case domain.AlertStateAcknowledged:
+		set["acknowledgedby"] = by
+		set["acknowledgedat"] = at
+	case domain.AlertStateResolved:
+		set["resolvedby"] = by
+		set["resolvedat"] = at
+	}
+
+	result := r.collection.FindOneAndUpdate(ctx,
+		r.scoped(ctx, bson.M{"id": id, "state": bson.M{"$in": states}}),
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+	if result.Err() != nil {
+		if result.Err() != mongo.ErrNoDocuments {
+			return nil, result.Err()
+		}
+		// Tell a missing alert apart from one in the wrong state
+		count, err := r.collection.CountDocuments(ctx, r.scoped(ctx, bson.M{"id": id}))
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, domain.ErrNotFound
+		}
+		return nil, domain.ErrInvalidTransition
+	}
+
+	var alert domain.Alert
+	if err := result.Decode(&alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// scoped restricts the filter to the namespace of the context
+func (r *alertRepository) scoped(ctx context.Context, filter bson.M) bson.M {
+	return namespaceFilter(ctx, "namespace", filter)
+}
+
+// stateValues lists the stored values of the given state.
+// Alerts stored before the lifecycle was introduced have no state and are firing.
+func stateValues(state domain.AlertState) bson.A {
+	if state == domain.AlertStateFiring {
+		return bson.A{state, nil}
+	}
+	return bson.A{state}
+}
@@ -0,0 +1,111 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// alertRuleRepository implements repository.AlertRuleRepository using MongoDB
+type alertRuleRepository struct {
+	collection *mongo.Collection
+	logger     *zap.Logger
+}
+
+// NewAlertRuleRepository creates a new MongoDB-based alert rule repository
+func NewAlertRuleRepository(db *mongo.Database, collection string, logger *zap.Logger) repository.AlertRuleRepository {
+	coll := db.Collection(collection)
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "namespace", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := coll.Indexes().CreateMany(ctx, indexModels); err != nil {
+		logger.Error("Failed to create alert rule indexes", zap.Error(err))
+	}
+
+	return &alertRuleRepository{
+		collection: coll,
+		logger:     logger,
+	}
+}
+
+// Create stores a rule under a new ID
+func (r *alertRuleRepository) Create(ctx context.Context, rule *domain.AlertRule) error {
+	r.logger.Debug("Creating alert rule in MongoDB", zap.String("name", rule.Name))
+
+	rule.ID = primitive.NewObjectID().Hex()
+	if _, err := r.collection.InsertOne(ctx, rule); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrAlertRuleExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Get retrieves a single rule
+func (r *alertRuleRepository) Get(ctx context.Context, id string) (*domain.AlertRule, error) {
+	r.logger.Debug("Getting alert rule from MongoDB", zap.String("id", id))
+
+	var rule domain.AlertRule
+	err := r.collection.FindOne(ctx, namespaceFilter(ctx, "namespace", bson.M{"id": id})).Decode(&rule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// List retrieves the rules of the namespace, ordered by name
+func (r *alertRuleRepository) List(ctx context.Context) ([]*domain.AlertRule, error) {
+	r.logger.Debug("Listing alert rules from MongoDB")
+
+	cursor, err := r.collection.Find(ctx, namespaceFilter(ctx, "namespace", bson.M{}),
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []*domain.AlertRule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Delete removes a rule
+func (r *alertRuleRepository) Delete(ctx context.Context, id string) error {
+	r.logger.Debug("Deleting alert rule from MongoDB", zap.String("id", id))
+
+	result, err := r.collection.DeleteOne(ctx, namespaceFilter(ctx, "namespace", bson.M{"id": id}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
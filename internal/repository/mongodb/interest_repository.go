@@ -22,31 +22,45 @@ type interestRepository struct {
 func NewInterestRepository(db *mongo.Database, collection string, logger *zap.Logger) repository.InterestRepository {
 	coll := db.Collection(collection)
 
-	// Create unique index on AppName
+	// Create unique index on AppName within a namespace
 	indexModel := mongo.IndexModel{
 		Keys: bson.D{
+			{Key: "namespace", Value: 1},
 			{Key: "appname", Value: 1},
 		},
 		Options: options.Index().SetUnique(true),
 	}
 
-	// Create a non-unique index for ServiceIp for efficient querying
-	serviceIpIndex := mongo.IndexModel{
-		Keys: bson.D{
-			{Key: "serviceip", Value: 1},
-		},
-		// Non-unique index (no SetUnique)
+	// Create non-unique indexes for the service IP in both families for efficient querying
+	serviceIpIndexes := make([]mongo.IndexModel, 0, 3)
+	for _, field := range []string{"serviceip", "serviceipv4", "serviceipv6"} {
+		serviceIpIndexes = append(serviceIpIndexes, mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "namespace", Value: 1},
+				{Key: field, Value: 1},
+			},
+		})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Interests were unique per app name before namespaces were introduced
+	backfillNamespace(ctx, coll, "namespace", bson.M{}, logger)
+	for _, legacyIndex := range []string{"appname_1", "serviceip_1"} {
+		if _, err := coll.Indexes().DropOne(ctx, legacyIndex); err == nil {
+			logger.Info("Dropped legacy index", zap.String("index", legacyIndex))
+		}
+	}
+
+	backfillServiceIps(ctx, coll, logger)
+
 	if _, err := coll.Indexes().CreateOne(ctx, indexModel); err != nil {
-		logger.Error("Failed to create index on appname", zap.Error(err))
+		logger.Error("Failed to create index on namespace and appname", zap.Error(err))
 	}
 
-	if _, err := coll.Indexes().CreateOne(ctx, serviceIpIndex); err != nil {
-		logger.Error("Failed to create index on serviceip", zap.Error(err))
+	if _, err := coll.Indexes().CreateMany(ctx, serviceIpIndexes); err != nil {
+		logger.Error("Failed to create service IP indexes", zap.Error(err))
 	}
 
 	return &interestRepository{
@@ -61,10 +75,15 @@ func (r *interestRepository) Create(ctx context.Context, interest *domain.Intere
 
 	// Convert domain.Interest to BSON document
 	doc := bson.M{
-		"appname":   interest.AppName,
-		"serviceip": interest.ServiceIp,
-		"createdat": interest.CreatedAt,
-		"updatedat": interest.UpdatedAt,
+		"namespace":   interest.Namespace,
+		"appname":     interest.AppName,
+		"serviceip":   interest.ServiceIp,
+		"serviceipv4": interest.ServiceIpV4,
+		"serviceipv6": interest.ServiceIpV6,
+		"policies":    interest.Policies,
+		"version":     interest.Version,
+		"createdat":   interest.CreatedAt,
+		"updatedat":   interest.UpdatedAt,
 	}
 
 	_, err := r.collection.InsertOne(ctx, doc)
@@ -84,7 +103,7 @@ func (r *interestRepository) GetByAppName(ctx context.Context, appName string) (
 	r.logger.Debug("Getting interest by app name from MongoDB", zap.String("appName", appName))
 
 	var interest domain.Interest
-	err := r.collection.FindOne(ctx, bson.M{"appname": appName}).Decode(&interest)
+	err := r.collection.FindOne(ctx, r.scoped(ctx, bson.M{"appname": appName})).Decode(&interest)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, domain.ErrNotFound
@@ -100,7 +119,7 @@ func (r *interestRepository) GetByServiceIp(ctx context.Context, serviceIp strin
 	r.logger.Debug("Getting interest by service IP from MongoDB", zap.String("serviceIp", serviceIp))
 
 	var interest domain.Interest
-	err := r.collection.FindOne(ctx, bson.M{"serviceip": serviceIp}).Decode(&interest)
+	err := r.collection.FindOne(ctx, r.scoped(ctx, serviceIpFilter(serviceIp))).Decode(&interest)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, domain.ErrNotFound
@@ -111,28 +130,38 @@ func (r *interestRepository) GetByServiceIp(ctx context.Context, serviceIp strin
 	return &interest, nil
 }
 
-// Update updates an existing interest
-func (r *interestRepository) Update(ctx context.Context, interest *domain.Interest) (*domain.Interest, error) {
-	r.logger.Debug("Updating interest in MongoDB", zap.String("appName", interest.AppName))
+// Update updates an existing interest and increments its version
+func (r *interestRepository) Update(ctx context.Context, interest *domain.Interest, expectedVersion int64) (*domain.Interest, error) {
+	r.logger.Debug("Updating interest in MongoDB",
+		zap.String("appName", interest.AppName),
+		zap.Int64("expectedVersion", expectedVersion))
+
+	filter := versionedFilter(r.scoped(ctx, bson.M{"appname": interest.AppName}), expectedVersion)
 
 	// Prepare update document
 	update := bson.M{
 		"$set": bson.M{
-			"serviceip": interest.ServiceIp,
-			"updatedat": time.Now(),
+			"serviceip":   interest.ServiceIp,
+			"serviceipv4": interest.ServiceIpV4,
+			"serviceipv6": interest.ServiceIpV6,
+			"policies":    interest.Policies,
+			"updatedat":   time.Now(),
+		},
+		"$inc": bson.M{
+			"version": 1,
 		},
 	}
 
 	result := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"appname": interest.AppName},
+		filter,
 		update,
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
 
 	if result.Err() != nil {
 		if result.Err() == mongo.ErrNoDocuments {
-			return nil, domain.ErrNotFound
+			return nil, r.conditionalMissError(ctx, r.scoped(ctx, bson.M{"appname": interest.AppName}), expectedVersion)
 		}
 		return nil, result.Err()
 	}
@@ -146,32 +175,32 @@ func (r *interestRepository) Update(ctx context.Context, interest *domain.Intere
 }
 
 // DeleteByAppName deletes an interest by its app name
-func (r *interestRepository) DeleteByAppName(ctx context.Context, appName string) error {
+func (r *interestRepository) DeleteByAppName(ctx context.Context, appName string, expectedVersion int64) error {
 	r.logger.Debug("Deleting interest by app name from MongoDB", zap.String("appName", appName))
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"appname": appName})
+	result, err := r.collection.DeleteOne(ctx, versionedFilter(r.scoped(ctx, bson.M{"appname": appName}), expectedVersion))
 	if err != nil {
 		return err
 	}
 
 	if result.DeletedCount == 0 {
-		return domain.ErrNotFound
+		return r.conditionalMissError(ctx, r.scoped(ctx, bson.M{"appname": appName}), expectedVersion)
 	}
 
 	return nil
 }
 
 // DeleteByServiceIp deletes an interest by its service IP
-func (r *interestRepository) DeleteByServiceIp(ctx context.Context, serviceIp string) error {
+func (r *interestRepository) DeleteByServiceIp(ctx context.Context, serviceIp string, expectedVersion int64) error {
 	r.logger.Debug("Deleting interest by service IP from MongoDB", zap.String("serviceIp", serviceIp))
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"serviceip": serviceIp})
+	result, err := r.collection.DeleteOne(ctx, versionedFilter(r.scoped(ctx, serviceIpFilter(serviceIp)), expectedVersion))
 	if err != nil {
 		return err
 	}
 
 	if result.DeletedCount == 0 {
-		return domain.ErrNotFound
+		return r.conditionalMissError(ctx, r.scoped(ctx, serviceIpFilter(serviceIp)), expectedVersion)
 	}
 
 	return nil
@@ -181,7 +210,7 @@ func (r *interestRepository) DeleteByServiceIp(ctx context.Context, serviceIp st
 func (r *interestRepository) List(ctx context.Context) ([]*domain.Interest, error) {
 	r.logger.Debug("Listing all interests from MongoDB")
 
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, r.scoped(ctx, bson.M{}))
 	if err != nil {
 		return nil, err
 	}
@@ -203,3 +232,99 @@ func (r *interestRepository) List(ctx context.Context) ([]*domain.Interest, erro
 
 	return interests, nil
 }
+
+// scoped restricts the filter to the namespace of the context
+func (r *interestRepository) scoped(ctx context.Context, filter bson.M) bson.M {
+	return namespaceFilter(ctx, "namespace", filter)
+}
+
+// versionedFilter restricts the filter to documents carrying the expected version.
+// Documents written before versioning was introduced have no version field and count as version 0.
+func versionedFilter(filter bson.M, expectedVersion int64) bson.M {
+	if expectedVersion == domain.AnyVersion {
+		return filter
+	}
+	if expectedVersion == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["version"] = expectedVersion
+	}
+	return filter
+}
+
+// conditionalMissError determines why a conditional write matched no document
+func (r *interestRepository) conditionalMissError(ctx context.Context, filter bson.M, expectedVersion int64) error {
+	if expectedVersion == domain.AnyVersion {
+		return domain.ErrNotFound
+	}
+
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain.ErrNotFound
+	}
+
+	return domain.ErrPreconditionFailed
+}
+
+// serviceIpFilter matches interests reachable under the service IP in either address family
+func serviceIpFilter(serviceIp string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"serviceip": serviceIp},
+		bson.M{"serviceipv4": serviceIp},
+		bson.M{"serviceipv6": serviceIp},
+	}}
+}
+
+// backfillServiceIps canonicalizes the service IPs of interests written before both address families were stored.
+// The other family cannot be resolved here, it is completed on the next update of the interest.
+func backfillServiceIps(ctx context.Context, coll *mongo.Collection, logger *zap.Logger) {
+	cursor, err := coll.Find(ctx, bson.M{
+		"serviceipv4": bson.M{"$exists": false},
+		"serviceipv6": bson.M{"$exists": false},
+	})
+	if err != nil {
+		logger.Error("Failed to backfill service IPs", zap.Error(err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var interest domain.Interest
+		if err := cursor.Decode(&interest); err != nil {
+			logger.Error("Failed to decode interest during service IP backfill", zap.Error(err))
+			continue
+		}
+
+		serviceIp, err := domain.CanonicalServiceIp(interest.ServiceIp)
+		if err != nil {
+			logger.Warn("Skipping interest with invalid service IP",
+				zap.String("appName", interest.AppName),
+				zap.String("serviceIp", interest.ServiceIp))
+			continue
+		}
+		interest.ServiceIp = serviceIp
+		interest.ResolveServiceIps(nil)
+
+		_, err = coll.UpdateOne(ctx,
+			bson.M{"namespace": interest.Namespace, "appname": interest.AppName},
+			bson.M{"$set": bson.M{
+				"serviceip":   interest.ServiceIp,
+				"serviceipv4": interest.ServiceIpV4,
+				"serviceipv6": interest.ServiceIpV6,
+			}},
+		)
+		if err != nil {
+			logger.Error("Failed to backfill service IPs", zap.String("appName", interest.AppName), zap.Error(err))
+			continue
+		}
+		updated++
+	}
+
+	if updated > 0 {
+		logger.Info("Canonicalized service IPs of existing interests", zap.Int("count", updated))
+	}
+}
@@ -0,0 +1,614 @@
+package mongodb
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// jobInterestPollInterval is the resync interval used when change streams are unavailable
+const jobInterestPollInterval = 5 * time.Second
+
+// routingInterest is the routing-manager owned part of an interest, stored in the job document
+type routingInterest struct {
+	Namespace string                 `bson:"namespace"`
+	ServiceIp string                 `bson:"serviceip"`
+	Policies  []domain.RoutingPolicy `bson:"policies,omitempty"`
+	Version   int64                  `bson:"version"`
+	CreatedAt time.Time              `bson:"createdat"`
+	UpdatedAt time.Time              `bson:"updatedat"`
+}
+
+// jobInterestDocument is the projection of a job document needed to derive its interest
+type jobInterestDocument struct {
+	JobName         string                      `bson:"job_name"`
+	ServiceIpList   []domain.ServiceIpListEntry `bson:"service_ip_list"`
+	InterestedNodes []string                    `bson:"interested_nodes"`
+	RoutingInterest *routingInterest            `bson:"routing_interest"`
+}
+
+// jobInterestRepository implements repository.InterestRepository on top of the jobs collection.
+// A job is considered an interest if any node is interested in it or if an interest was created
+// through the routing-manager, which is then stored in the routing_interest field of the job.
+// A job carries at most one interest. Interests derived from interested nodes belong to the default namespace.
+type jobInterestRepository struct {
+	collection *mongo.Collection
+	logger     *zap.Logger
+}
+
+var _ repository.InterestWatcher = &jobInterestRepository{}
+
+// NewJobInterestRepository creates a new interest repository deriving its interests from the jobs collection
+func NewJobInterestRepository(db *mongo.Database, collection string, logger *zap.Logger) repository.InterestRepository {
+	return newJobInterestRepository(db, collection, logger)
+}
+
+func newJobInterestRepository(db *mongo.Database, collection string, logger *zap.Logger) *jobInterestRepository {
+	coll := db.Collection(collection)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	backfillNamespace(ctx, coll, "routing_interest.namespace", bson.M{"routing_interest": bson.M{"$exists": true}}, logger)
+
+	return &jobInterestRepository{
+		collection: coll,
+		logger:     logger,
+	}
+}
+
+// Create stores a routing-manager owned interest in the job document
+func (r *jobInterestRepository) Create(ctx context.Context, interest *domain.Interest) error {
+	r.logger.Debug("Creating interest in jobs collection", zap.String("appName", interest.AppName))
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{
+			"job_name":         interest.AppName,
+			"routing_interest": bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{"routing_interest": routingInterest{
+			Namespace: interest.Namespace,
+			ServiceIp: interest.ServiceIp,
+			Policies:  interest.Policies,
+			Version:   interest.Version,
+			CreatedAt: interest.CreatedAt,
+			UpdatedAt: interest.UpdatedAt,
+		}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := r.collection.CountDocuments(ctx, bson.M{"job_name": interest.AppName})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return domain.ErrNotFound
+		}
+		return domain.ErrInterestAlreadyExists
+	}
+
+	return nil
+}
+
+// GetByAppName retrieves the interest derived from the job with the given name
+func (r *jobInterestRepository) GetByAppName(ctx context.Context, appName string) (*domain.Interest, error) {
+	r.logger.Debug("Getting interest by app name from jobs collection", zap.String("appName", appName))
+
+	var doc jobInterestDocument
+	err := r.collection.FindOne(ctx, r.scoped(ctx, interestMembershipFilter(bson.M{"job_name": appName}))).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return doc.toInterest(), nil
+}
+
+// GetByServiceIp retrieves the interest whose service IP matches the given one.
+// The addresses of the jobs are written by the service-manager in whatever form it uses,
+// so they are compared in canonical form in Go rather than in the query.
+func (r *jobInterestRepository) GetByServiceIp(ctx context.Context, serviceIp string) (*domain.Interest, error) {
+	r.logger.Debug("Getting interest by service IP from jobs collection", zap.String("serviceIp", serviceIp))
+
+	interests, err := r.find(ctx, r.scoped(ctx, interestMembershipFilter(bson.M{})))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, interest := range interests {
+		if interest.MatchesServiceIp(serviceIp) {
+			return interest, nil
+		}
+	}
+
+	return nil, domain.ErrNotFound
+}
+
+// Update sets the service IP of an interest and increments its version
+func (r *jobInterestRepository) Update(ctx context.Context, interest *domain.Interest, expectedVersion int64) (*domain.Interest, error) {
+	r.logger.Debug("Updating interest in jobs collection",
+		zap.String("appName", interest.AppName),
+		zap.Int64("expectedVersion", expectedVersion))
+
+	now := time.Now()
+	result := r.collection.FindOneAndUpdate(
+		ctx,
+		jobInterestVersionFilter(r.scoped(ctx, interestMembershipFilter(bson.M{"job_name": interest.AppName})), expectedVersion),
+		bson.M{
+			"$set": bson.M{
+				"routing_interest.namespace": interest.Namespace,
+				"routing_interest.serviceip": interest.ServiceIp,
+				"routing_interest.policies":  interest.Policies,
+				"routing_interest.updatedat": now,
+			},
+			"$min": bson.M{
+				"routing_interest.createdat": now,
+			},
+			"$inc": bson.M{
+				"routing_interest.version": 1,
+			},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	if result.Err() != nil {
+		if result.Err() == mongo.ErrNoDocuments {
+			return nil, r.conditionalMissError(ctx, bson.M{"job_name": interest.AppName}, expectedVersion)
+		}
+		return nil, result.Err()
+	}
+
+	var doc jobInterestDocument
+	if err := result.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return doc.toInterest(), nil
+}
+
+// DeleteByAppName removes the routing-manager owned interest from the job document.
+// Interests of jobs that still have interested nodes cannot be deleted.
+func (r *jobInterestRepository) DeleteByAppName(ctx context.Context, appName string, expectedVersion int64) error {
+	r.logger.Debug("Deleting interest by app name from jobs collection", zap.String("appName", appName))
+
+	filter := jobInterestVersionFilter(r.scoped(ctx, bson.M{
+		"job_name":           appName,
+		"routing_interest":   bson.M{"$exists": true},
+		"interested_nodes.0": bson.M{"$exists": false},
+	}), expectedVersion)
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"routing_interest": ""}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		derived, err := r.collection.CountDocuments(ctx, r.scoped(ctx, bson.M{
+			"job_name":           appName,
+			"interested_nodes.0": bson.M{"$exists": true},
+		}))
+		if err != nil {
+			return err
+		}
+		if derived > 0 {
+			return domain.ErrInterestDerived
+		}
+		return r.conditionalMissError(ctx, bson.M{"job_name": appName}, expectedVersion)
+	}
+
+	return nil
+}
+
+// DeleteByServiceIp removes the interest with the given service IP
+func (r *jobInterestRepository) DeleteByServiceIp(ctx context.Context, serviceIp string, expectedVersion int64) error {
+	r.logger.Debug("Deleting interest by service IP from jobs collection", zap.String("serviceIp", serviceIp))
+
+	interest, err := r.GetByServiceIp(ctx, serviceIp)
+	if err != nil {
+		return err
+	}
+
+	return r.DeleteByAppName(ctx, interest.AppName, expectedVersion)
+}
+
+// List retrieves all interests derived from the jobs collection
+func (r *jobInterestRepository) List(ctx context.Context) ([]*domain.Interest, error) {
+	r.logger.Debug("Listing all interests from jobs collection")
+	return r.find(ctx, r.scoped(ctx, interestMembershipFilter(bson.M{})))
+}
+
+// jobInterestFields matches the paths of the job fields an interest is derived from
+const jobInterestFields = `^(job_name|service_ip_list|interested_nodes|routing_interest)(\.|$)`
+
+// jobInterestChangePipeline drops the updates of jobs that leave the fields of their interest untouched,
+// such as the routing written on every cycle, and the instance list from the looked up job
+var jobInterestChangePipeline = mongo.Pipeline{
+	{{Key: "$addFields", Value: bson.M{
+		"interestFields": bson.M{"$filter": bson.M{
+			"input": bson.M{"$concatArrays": bson.A{
+				bson.M{"$map": bson.M{
+					"input": bson.M{"$objectToArray": bson.M{"$ifNull": bson.A{"$updateDescription.updatedFields", bson.M{}}}},
+					"in":    "$$this.k",
+				}},
+				bson.M{"$ifNull": bson.A{"$updateDescription.removedFields", bson.A{}}},
+			}},
+			"cond": bson.M{"$regexMatch": bson.M{"input": "$$this", "regex": jobInterestFields}},
+		}},
+	}}},
+	{{Key: "$match", Value: bson.M{"$or": bson.A{
+		bson.M{"operationType": bson.M{"$ne": "update"}},
+		bson.M{"interestFields.0": bson.M{"$exists": true}},
+	}}}},
+	{{Key: "$project", Value: bson.M{"interestFields": 0, "fullDocument.instance_list": 0}}},
+}
+
+// Watch follows the jobs collection and reports interests appearing, changing and disappearing.
+// It follows a change stream if the deployment supports it and falls back to polling otherwise.
+// Stream events carry the changed job, only events that cannot be applied on their own cause a resync.
+func (r *jobInterestRepository) Watch(ctx context.Context, notify func(event domain.InterestEvent)) error {
+	ctx = domain.WithNamespace(ctx, domain.AllNamespaces)
+
+	// The stream is opened before the interests are listed, so that no change falls in between.
+	// Changes already contained in the listing are replayed, they do not differ from the known interests.
+	stream, streamErr := r.collection.Watch(ctx, jobInterestChangePipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if streamErr == nil {
+		defer stream.Close(context.Background())
+	}
+
+	known, keys, err := r.snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	resync := func() {
+		current, currentKeys, err := r.snapshot(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Error("Failed to resync interests from jobs collection", zap.Error(err))
+			}
+			return
+		}
+		for _, event := range diffInterests(known, current) {
+			notify(event)
+		}
+		known, keys = current, currentKeys
+	}
+
+	// forget removes the interest of a job document
+	forget := func(id string) {
+		key, ok := keys[id]
+		if !ok {
+			return
+		}
+		delete(keys, id)
+		if old, ok := known[key]; ok {
+			delete(known, key)
+			notify(domain.InterestEvent{Type: domain.InterestDeleted, Interest: old})
+		}
+	}
+
+	if streamErr != nil {
+		r.logger.Warn("Change streams unavailable, polling jobs collection for interest changes",
+			zap.Duration("interval", jobInterestPollInterval),
+			zap.Error(streamErr))
+
+		ticker := time.NewTicker(jobInterestPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				resync()
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+
+	r.logger.Info("Watching jobs collection for interest changes")
+	for stream.Next(ctx) {
+		var event jobChangeEvent
+		if err := stream.Decode(&event); err != nil {
+			r.logger.Error("Failed to decode job change, resyncing interests", zap.Error(err))
+			resync()
+			continue
+		}
+		id := event.DocumentKey.Lookup("_id").String()
+
+		switch event.OperationType {
+		case "insert", "update", "replace":
+			// The job may be gone by the time the update is looked up, its delete event follows
+			if len(event.FullDocument) == 0 {
+				continue
+			}
+			var doc jobInterestDocument
+			if err := bson.Unmarshal(event.FullDocument, &doc); err != nil {
+				r.logger.Error("Failed to decode changed job", zap.String("id", id), zap.Error(err))
+				continue
+			}
+			if !doc.isInterest() {
+				forget(id)
+				continue
+			}
+
+			// A renamed job or a moved interest replaces the interest of its previous key
+			interest := doc.toInterest()
+			if key, ok := keys[id]; ok && key != interest.Key() {
+				forget(id)
+			}
+			keys[id] = interest.Key()
+
+			old, ok := known[interest.Key()]
+			known[interest.Key()] = interest
+			switch {
+			case !ok:
+				notify(domain.InterestEvent{Type: domain.InterestCreated, Interest: interest})
+			case interestChanged(old, interest):
+				notify(domain.InterestEvent{Type: domain.InterestUpdated, Interest: interest})
+			}
+		case "delete":
+			forget(id)
+		default:
+			// Drops, renames and invalidations affect the collection as a whole
+			r.logger.Info("Jobs collection changed, resyncing interests", zap.String("operationType", event.OperationType))
+			resync()
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
+
+// find decodes all job documents matching the filter into interests
+func (r *jobInterestRepository) find(ctx context.Context, filter bson.M) ([]*domain.Interest, error) {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var interests []*domain.Interest
+	for cursor.Next(ctx) {
+		var doc jobInterestDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		interests = append(interests, doc.toInterest())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return interests, nil
+}
+
+// snapshot returns all current interests keyed by namespace and app name, and their keys keyed by document ID
+func (r *jobInterestRepository) snapshot(ctx context.Context) (map[string]*domain.Interest, map[string]string, error) {
+	cursor, err := r.collection.Find(ctx, r.scoped(ctx, interestMembershipFilter(bson.M{})))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	interests := make(map[string]*domain.Interest)
+	keys := make(map[string]string)
+	for cursor.Next(ctx) {
+		var doc jobInterestDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, nil, err
+		}
+
+		interest := doc.toInterest()
+		interests[interest.Key()] = interest
+		keys[cursor.Current.Lookup("_id").String()] = interest.Key()
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return interests, keys, nil
+}
+
+// conditionalMissError determines why a conditional write matched no job document
+func (r *jobInterestRepository) conditionalMissError(ctx context.Context, filter bson.M, expectedVersion int64) error {
+	if expectedVersion == domain.AnyVersion {
+		return domain.ErrNotFound
+	}
+
+	count, err := r.collection.CountDocuments(ctx, r.scoped(ctx, interestMembershipFilter(filter)))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain.ErrNotFound
+	}
+
+	return domain.ErrPreconditionFailed
+}
+
+// isInterest reports whether the job document carries an interest, see interestMembershipFilter
+func (d *jobInterestDocument) isInterest() bool {
+	return len(d.InterestedNodes) > 0 || d.RoutingInterest != nil
+}
+
+// toInterest derives the interest of a job document.
+// Without a routing-manager owned interest the round robin service IP of the job is used.
+// The other address family of the service IP is taken from the service IP list of the job.
+func (d *jobInterestDocument) toInterest() *domain.Interest {
+	interest := &domain.Interest{
+		Namespace: domain.DefaultNamespace,
+		AppName:   d.JobName,
+	}
+
+	if d.RoutingInterest != nil {
+		interest.Namespace = d.RoutingInterest.Namespace
+		interest.ServiceIp = d.RoutingInterest.ServiceIp
+		interest.Policies = d.RoutingInterest.Policies
+		interest.Version = d.RoutingInterest.Version
+		interest.CreatedAt = d.RoutingInterest.CreatedAt
+		interest.UpdatedAt = d.RoutingInterest.UpdatedAt
+	} else {
+		for _, entry := range d.ServiceIpList {
+			if interest.ServiceIp == "" || entry.IpType == domain.ServiceIpTypeRoundRobin {
+				interest.ServiceIp = entry.Address
+			}
+			if entry.IpType == domain.ServiceIpTypeRoundRobin {
+				break
+			}
+		}
+	}
+
+	// The job document holds both address families of its service IPs
+	interest.ResolveServiceIps(&domain.Job{ServiceIpList: d.ServiceIpList})
+	return interest
+}
+
+// scoped restricts the filter to jobs whose interest belongs to the namespace of the context
+func (r *jobInterestRepository) scoped(ctx context.Context, filter bson.M) bson.M {
+	switch namespace := domain.NamespaceFromContext(ctx); namespace {
+	case domain.AllNamespaces:
+		return filter
+	case domain.DefaultNamespace:
+		return bson.M{"$and": bson.A{
+			filter,
+			bson.M{"$or": bson.A{
+				bson.M{"routing_interest.namespace": namespace},
+				bson.M{"routing_interest": bson.M{"$exists": false}},
+			}},
+		}}
+	default:
+		return namespaceFilter(ctx, "routing_interest.namespace", filter)
+	}
+}
+
+// interestMembershipFilter restricts the filter to jobs that constitute an interest
+func interestMembershipFilter(filter bson.M) bson.M {
+	return bson.M{"$and": bson.A{
+		filter,
+		bson.M{"$or": bson.A{
+			bson.M{"interested_nodes.0": bson.M{"$exists": true}},
+			bson.M{"routing_interest": bson.M{"$exists": true}},
+		}},
+	}}
+}
+
+// jobInterestVersionFilter restricts the filter to jobs whose interest carries the expected version.
+// Derived interests without a routing_interest field count as version 0.
+func jobInterestVersionFilter(filter bson.M, expectedVersion int64) bson.M {
+	if expectedVersion == domain.AnyVersion {
+		return filter
+	}
+	if expectedVersion == 0 {
+		filter["routing_interest.version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["routing_interest.version"] = expectedVersion
+	}
+	return filter
+}
+
+// diffInterests computes the interest events leading from the previous to the current interests
+func diffInterests(previous, current map[string]*domain.Interest) []domain.InterestEvent {
+	var events []domain.InterestEvent
+
+	for key, interest := range current {
+		old, ok := previous[key]
+		switch {
+		case !ok:
+			events = append(events, domain.InterestEvent{Type: domain.InterestCreated, Interest: interest})
+		case interestChanged(old, interest):
+			events = append(events, domain.InterestEvent{Type: domain.InterestUpdated, Interest: interest})
+		}
+	}
+
+	for key, interest := range previous {
+		if _, ok := current[key]; !ok {
+			events = append(events, domain.InterestEvent{Type: domain.InterestDeleted, Interest: interest})
+		}
+	}
+
+	return events
+}
+
+// interestChanged reports whether an interest differs from its previous state
+func interestChanged(old, interest *domain.Interest) bool {
+	return old.ServiceIp != interest.ServiceIp || old.Version != interest.Version || !reflect.DeepEqual(old.Policies, interest.Policies)
+}
+
+// InterestMigrationReport summarizes the reconciliation of the interests collection into the jobs collection
+type InterestMigrationReport struct {
+	Migrated  []string `json:"migrated"`
+	Unchanged []string `json:"unchanged"`
+	Orphaned  []string `json:"orphaned"`
+}
+
+// ReconcileInterests copies every interest of the source repository into the routing_interest field
+// of the matching job document. Interests without a job are reported as orphaned and left untouched.
+// The reconciliation is idempotent, with dryRun set no document is modified.
+func ReconcileInterests(ctx context.Context, source repository.InterestRepository, db *mongo.Database, collection string, dryRun bool, logger *zap.Logger) (*InterestMigrationReport, error) {
+	target := newJobInterestRepository(db, collection, logger)
+
+	interests, err := source.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &InterestMigrationReport{}
+	for _, interest := range interests {
+		var doc jobInterestDocument
+		err := target.collection.FindOne(ctx, bson.M{"job_name": interest.AppName}).Decode(&doc)
+		if err == mongo.ErrNoDocuments {
+			logger.Warn("No job found for interest", zap.String("appName", interest.AppName))
+			report.Orphaned = append(report.Orphaned, interest.AppName)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if doc.RoutingInterest != nil && doc.RoutingInterest.Namespace == interest.Namespace && doc.RoutingInterest.ServiceIp == interest.ServiceIp &&
+			doc.RoutingInterest.Version >= interest.Version && reflect.DeepEqual(doc.RoutingInterest.Policies, interest.Policies) {
+			report.Unchanged = append(report.Unchanged, interest.AppName)
+			continue
+		}
+
+		if !dryRun {
+			_, err := target.collection.UpdateOne(ctx,
+				bson.M{"job_name": interest.AppName},
+				bson.M{"$set": bson.M{"routing_interest": routingInterest{
+					Namespace: interest.Namespace,
+					ServiceIp: interest.ServiceIp,
+					Policies:  interest.Policies,
+					Version:   interest.Version,
+					CreatedAt: interest.CreatedAt,
+					UpdatedAt: interest.UpdatedAt,
+				}}},
+			)
+			if err != nil {
+				return report, err
+			}
+		}
+
+		logger.Info("Migrated interest into jobs collection",
+			zap.String("appName", interest.AppName),
+			zap.Bool("dryRun", dryRun))
+		report.Migrated = append(report.Migrated, interest.AppName)
+	}
+
+	return report, nil
+}
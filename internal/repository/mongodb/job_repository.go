@@ -2,14 +2,20 @@ package mongodb
 
 import (
 	"context"
+	"reflect"
+	"time"
 
 	"github.com/smnzlnsk/routing-manager/internal/domain"
 	"github.com/smnzlnsk/routing-manager/internal/repository"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
+// jobPollInterval is the resync interval used when change streams are unavailable
+const jobPollInterval = 5 * time.Second
+
 // jobRepository implements repository.ServiceRepository using MongoDB
 type jobRepository struct {
 	collection *mongo.Collection
@@ -40,3 +46,222 @@ func (r *jobRepository) GetByJobName(ctx context.Context, jobName string) (*doma
 
 	return &job, nil
 }
+
+// List retrieves the jobs passing the filter, ordered by job name
+func (r *jobRepository) List(ctx context.Context, filter domain.JobFilter) ([]*domain.Job, error) {
+	r.logger.Debug("Listing jobs from MongoDB", zap.Any("filter", filter))
+
+	cursor, err := r.collection.Find(ctx, jobQuery(filter), options.Find().SetSort(bson.D{{Key: "job_name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []*domain.Job{}
+	for cursor.Next(ctx) {
+		var job domain.Job
+		if err := cursor.Decode(&job); err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, &job)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// jobChangeEvent is the part of a change stream event the job watch needs
+type jobChangeEvent struct {
+	OperationType string   `bson:"operationType"`
+	DocumentKey   bson.Raw `bson:"documentKey"`
+	// FullDocument is the job after the change, it is looked up for updates and absent for deletes
+	FullDocument bson.Raw `bson:"fullDocument"`
+}
+
+// Watch notifies about job changes. It follows a change stream if the deployment supports it
+// and falls back to polling otherwise. Stream events carry the changed job, only events that
+// cannot be attributed to a single job, such as a dropped collection, resync the full set of jobs.
+func (r *jobRepository) Watch(ctx context.Context, notify func(event domain.JobEvent)) error {
+	// The stream is opened before the jobs are listed, so that no change falls in between.
+	// Changes already contained in the listing are replayed, they do not differ from the known jobs.
+	stream, streamErr := r.collection.Watch(ctx, mongo.Pipeline{}, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if streamErr == nil {
+		defer stream.Close(context.Background())
+	}
+
+	known, names, err := r.snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	resync := func() {
+		current, currentNames, err := r.snapshot(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Error("Failed to resync jobs", zap.Error(err))
+			}
+			return
+		}
+		for _, event := range diffJobs(known, current) {
+			notify(event)
+		}
+		known, names = current, currentNames
+	}
+
+	if streamErr != nil {
+		r.logger.Warn("Change streams unavailable, polling jobs collection",
+			zap.Duration("interval", jobPollInterval),
+			zap.Error(streamErr))
+
+		ticker := time.NewTicker(jobPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				resync()
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+
+	r.logger.Info("Watching jobs collection for changes")
+	for stream.Next(ctx) {
+		var event jobChangeEvent
+		if err := stream.Decode(&event); err != nil {
+			r.logger.Error("Failed to decode job change, resyncing", zap.Error(err))
+			resync()
+			continue
+		}
+		id := event.DocumentKey.Lookup("_id").String()
+
+		switch event.OperationType {
+		case "insert", "update", "replace":
+			// The job may be gone by the time the update is looked up, its delete event follows
+			if len(event.FullDocument) == 0 {
+				continue
+			}
+			var job domain.Job
+			if err := bson.Unmarshal(event.FullDocument, &job); err != nil {
+				r.logger.Error("Failed to decode changed job", zap.String("id", id), zap.Error(err))
+				continue
+			}
+
+			// A renamed job replaces the job of its previous name
+			if previous, ok := names[id]; ok && previous != job.JobName {
+				if old, ok := known[previous]; ok {
+					delete(known, previous)
+					notify(domain.JobEvent{Type: domain.JobDeleted, Job: old})
+				}
+			}
+			names[id] = job.JobName
+
+			old, ok := known[job.JobName]
+			known[job.JobName] = &job
+			switch {
+			case !ok:
+				notify(domain.JobEvent{Type: domain.JobCreated, Job: &job})
+			case !reflect.DeepEqual(old, &job):
+				notify(domain.JobEvent{Type: domain.JobUpdated, Job: &job})
+			}
+		case "delete":
+			name, ok := names[id]
+			if !ok {
+				continue
+			}
+			delete(names, id)
+			if old, ok := known[name]; ok {
+				delete(known, name)
+				notify(domain.JobEvent{Type: domain.JobDeleted, Job: old})
+			}
+		default:
+			// Drops, renames and invalidations affect the collection as a whole
+			r.logger.Info("Jobs collection changed, resyncing", zap.String("operationType", event.OperationType))
+			resync()
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
+
+// snapshot returns all current jobs keyed by job name, and their names keyed by document ID
+func (r *jobRepository) snapshot(ctx context.Context) (map[string]*domain.Job, map[string]string, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := make(map[string]*domain.Job)
+	names := make(map[string]string)
+	for cursor.Next(ctx) {
+		var job domain.Job
+		if err := cursor.Decode(&job); err != nil {
+			return nil, nil, err
+		}
+
+		jobs[job.JobName] = &job
+		names[cursor.Current.Lookup("_id").String()] = job.JobName
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return jobs, names, nil
+}
+
+// jobQuery translates a job filter into a MongoDB query
+func jobQuery(filter domain.JobFilter) bson.M {
+	query := bson.M{}
+	if filter.IpType != "" {
+		query["$or"] = bson.A{
+			bson.M{"IpType": filter.IpType},
+			bson.M{"service_ip_list.IpType": filter.IpType},
+		}
+	}
+
+	instances := bson.M{"$size": bson.M{"$ifNull": bson.A{"$instance_list", bson.A{}}}}
+	var bounds bson.A
+	if filter.MinInstances != nil {
+		bounds = append(bounds, bson.M{"$gte": bson.A{instances, *filter.MinInstances}})
+	}
+	if filter.MaxInstances != nil {
+		bounds = append(bounds, bson.M{"$lte": bson.A{instances, *filter.MaxInstances}})
+	}
+	if len(bounds) > 0 {
+		query["$expr"] = bson.M{"$and": bounds}
+	}
+
+	return query
+}
+
+// diffJobs returns the events turning the previous set of jobs into the current one
+func diffJobs(previous, current map[string]*domain.Job) []domain.JobEvent {
+	var events []domain.JobEvent
+
+	for name, job := range current {
+		old, ok := previous[name]
+		switch {
+		case !ok:
+			events = append(events, domain.JobEvent{Type: domain.JobCreated, Job: job})
+		case !reflect.DeepEqual(old, job):
+			events = append(events, domain.JobEvent{Type: domain.JobUpdated, Job: job})
+		}
+	}
+
+	for name, job := range previous {
+		if _, ok := current[name]; !ok {
+			events = append(events, domain.JobEvent{Type: domain.JobDeleted, Job: job})
+		}
+	}
+
+	return events
+}
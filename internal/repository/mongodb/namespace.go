@@ -0,0 +1,34 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// namespaceFilter scopes the filter to the namespace of the context
+func namespaceFilter(ctx context.Context, field string, filter bson.M) bson.M {
+	if namespace := domain.NamespaceFromContext(ctx); namespace != domain.AllNamespaces {
+		filter[field] = namespace
+	}
+	return filter
+}
+
+// backfillNamespace assigns the default namespace to documents written before namespaces were introduced
+func backfillNamespace(ctx context.Context, coll *mongo.Collection, field string, filter bson.M, logger *zap.Logger) {
+	filter[field] = bson.M{"$exists": false}
+
+	result, err := coll.UpdateMany(ctx, filter, bson.M{"$set": bson.M{field: domain.DefaultNamespace}})
+	if err != nil {
+		logger.Error("Failed to backfill namespace", zap.String("collection", coll.Name()), zap.Error(err))
+		return
+	}
+	if result.ModifiedCount > 0 {
+		logger.Info("Assigned default namespace to existing documents",
+			zap.String("collection", coll.Name()),
+			zap.Int64("count", result.ModifiedCount))
+	}
+}
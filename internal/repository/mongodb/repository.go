@@ -9,12 +9,24 @@ import (
 
 // New creates a new Repositories instance with MongoDB implementations
 func New(cfg *config.MongoDBConfig, mongoClient *mongodb.Client, logger *zap.Logger) *repository.Repositories {
+	var interestRepository repository.InterestRepository
+	switch cfg.InterestSource {
+	case config.InterestSourceJobs:
+		interestRepository = NewJobInterestRepository(mongoClient.GetDatabase("jobs"), "jobs", logger)
+	default:
+		interestRepository = NewInterestRepository(mongoClient.GetDatabase("routing"), "interests", logger)
+	}
+
 	return &repository.Repositories{
-		AlertRepository:    NewAlertRepository(mongoClient.GetDatabase("routing"), "alerts", logger),
-		InterestRepository: NewInterestRepository(mongoClient.GetDatabase("routing"), "interests", logger),
+		AlertRepository:    NewAlertRepository(mongoClient.GetDatabase("routing"), "alerts", cfg.AlertRetention, logger),
+		InterestRepository: interestRepository,
 		JobRepository:      NewJobRepository(mongoClient.GetDatabase("jobs"), "jobs", logger),
 		RoutingRepository:  NewRoutingRepository(mongoClient.GetDatabase("jobs"), "jobs", logger),
 
+		RoutingHistoryRepository:  NewRoutingHistoryRepository(mongoClient.GetDatabase("routing"), "routing_history", cfg.RoutingHistoryVersions, logger),
+		RoutingOverrideRepository: NewRoutingOverrideRepository(mongoClient.GetDatabase("routing"), "routing_overrides", logger),
+		AlertRuleRepository:       NewAlertRuleRepository(mongoClient.GetDatabase("routing"), "alert_rules", logger),
+
 		// TODO: Initialize other repositories here with their dependencies
 	}
 }
@@ -0,0 +1,204 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// maxVersionAttempts bounds the retries when concurrent writers race for the same version
+const maxVersionAttempts = 5
+
+// routingHistoryRepository implements repository.RoutingHistoryRepository using MongoDB
+type routingHistoryRepository struct {
+	collection  *mongo.Collection
+	maxVersions int64
+	logger      *zap.Logger
+}
+
+// NewRoutingHistoryRepository creates a new MongoDB-based routing history repository.
+// Only the newest maxVersions snapshots of an app are kept, older ones are removed whenever a snapshot is created.
+func NewRoutingHistoryRepository(db *mongo.Database, collection string, maxVersions int, logger *zap.Logger) repository.RoutingHistoryRepository {
+	coll := db.Collection(collection)
+
+	// Versions are unique per app within a namespace
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "namespace", Value: 1},
+			{Key: "appname", Value: 1},
+			{Key: "version", Value: -1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := coll.Indexes().CreateOne(ctx, indexModel); err != nil {
+		logger.Error("Failed to create index on namespace, appname and version", zap.Error(err))
+	}
+
+	return &routingHistoryRepository{
+		collection:  coll,
+		maxVersions: int64(maxVersions),
+		logger:      logger,
+	}
+}
+
+// Create stores a snapshot with the next free version of its app
+func (r *routingHistoryRepository) Create(ctx context.Context, snapshot *domain.RoutingSnapshot) error {
+	r.logger.Debug("Creating routing snapshot in MongoDB", zap.String("appName", snapshot.AppName))
+
+	for attempt := 0; attempt < maxVersionAttempts; attempt++ {
+		latest, err := r.latestVersion(ctx, snapshot.Namespace, snapshot.AppName)
+		if err != nil {
+			return err
+		}
+
+		snapshot.Version = latest + 1
+		_, err = r.collection.InsertOne(ctx, snapshot)
+		if err == nil {
+			r.prune(ctx, snapshot)
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+
+		r.logger.Debug("Routing snapshot version taken, retrying",
+			zap.String("appName", snapshot.AppName),
+			zap.Int64("version", snapshot.Version))
+	}
+
+	return domain.ErrPreconditionFailed
+}
+
+// Get retrieves a single snapshot of an app
+func (r *routingHistoryRepository) Get(ctx context.Context, appName string, version int64) (*domain.RoutingSnapshot, error) {
+	r.logger.Debug("Getting routing snapshot from MongoDB", zap.String("appName", appName), zap.Int64("version", version))
+
+	var snapshot domain.RoutingSnapshot
+	err := r.collection.FindOne(ctx, namespaceFilter(ctx, "namespace", bson.M{"appname": appName, "version": version})).Decode(&snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// Latest retrieves the newest snapshot of an app
+func (r *routingHistoryRepository) Latest(ctx context.Context, appName string) (*domain.RoutingSnapshot, error) {
+	r.logger.Debug("Getting latest routing snapshot from MongoDB", zap.String("appName", appName))
+	return r.findLatest(ctx, bson.M{"appname": appName})
+}
+
+// LatestChange retrieves the newest snapshot of an app recording a change of the IpType
+func (r *routingHistoryRepository) LatestChange(ctx context.Context, appName string, ipType domain.ServiceIpType) (*domain.RoutingSnapshot, error) {
+	r.logger.Debug("Getting latest routing change from MongoDB", zap.String("appName", appName), zap.String("IpType", string(ipType)))
+	return r.findLatest(ctx, bson.M{"appname": appName, "change.IpType": ipType})
+}
+
+// List retrieves a page of the snapshots of an app, newest first
+func (r *routingHistoryRepository) List(ctx context.Context, appName string, query domain.RoutingHistoryQuery) ([]*domain.RoutingSnapshot, error) {
+	r.logger.Debug("Listing routing snapshots from MongoDB", zap.String("appName", appName), zap.Int64("before", query.Before))
+
+	filter := bson.M{"appname": appName}
+	if query.Before > 0 {
+		filter["version"] = bson.M{"$lt": query.Before}
+	}
+	limit := int64(query.Limit)
+	if limit == 0 {
+		limit = domain.DefaultRoutingHistoryLimit
+	}
+
+	cursor, err := r.collection.Find(ctx,
+		namespaceFilter(ctx, "namespace", filter),
+		options.Find().SetSort(bson.D{{Key: "version", Value: -1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var snapshots []*domain.RoutingSnapshot
+	for cursor.Next(ctx) {
+		var snapshot domain.RoutingSnapshot
+		if err := cursor.Decode(&snapshot); err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, &snapshot)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// findLatest retrieves the snapshot with the highest version among those matching the filter
+func (r *routingHistoryRepository) findLatest(ctx context.Context, filter bson.M) (*domain.RoutingSnapshot, error) {
+	var snapshot domain.RoutingSnapshot
+	err := r.collection.FindOne(ctx,
+		namespaceFilter(ctx, "namespace", filter),
+		options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}),
+	).Decode(&snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// prune removes the snapshots of the app of a new snapshot that exceed the version cap.
+// Failures are only logged, the snapshot is stored and the next one prunes again.
+func (r *routingHistoryRepository) prune(ctx context.Context, snapshot *domain.RoutingSnapshot) {
+	if r.maxVersions <= 0 || snapshot.Version <= r.maxVersions {
+		return
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"namespace": snapshot.Namespace,
+		"appname":   snapshot.AppName,
+		"version":   bson.M{"$lte": snapshot.Version - r.maxVersions},
+	})
+	if err != nil {
+		r.logger.Warn("Failed to prune routing snapshots", zap.String("appName", snapshot.AppName), zap.Error(err))
+		return
+	}
+	if result.DeletedCount > 0 {
+		r.logger.Debug("Pruned routing snapshots",
+			zap.String("appName", snapshot.AppName),
+			zap.Int64("deleted", result.DeletedCount))
+	}
+}
+
+// latestVersion returns the highest version of an app, 0 if it has no snapshots
+func (r *routingHistoryRepository) latestVersion(ctx context.Context, namespace, appName string) (int64, error) {
+	var latest domain.RoutingSnapshot
+	err := r.collection.FindOne(ctx,
+		bson.M{"namespace": namespace, "appname": appName},
+		options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.M{"version": 1}),
+	).Decode(&latest)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return latest.Version, nil
+}
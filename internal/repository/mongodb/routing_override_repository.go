@@ -0,0 +1,129 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// routingOverrideRepository implements repository.RoutingOverrideRepository using MongoDB
+type routingOverrideRepository struct {
+	collection *mongo.Collection
+	logger     *zap.Logger
+}
+
+// NewRoutingOverrideRepository creates a new MongoDB-based routing override repository
+func NewRoutingOverrideRepository(db *mongo.Database, collection string, logger *zap.Logger) repository.RoutingOverrideRepository {
+	coll := db.Collection(collection)
+
+	// Expired overrides are deleted by the routing service once their priorities are recomputed,
+	// a TTL index would remove them before that happens
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "appname", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "expiresat", Value: 1}},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := coll.Indexes().CreateMany(ctx, indexModels); err != nil {
+		logger.Error("Failed to create routing override indexes", zap.Error(err))
+	}
+
+	return &routingOverrideRepository{
+		collection: coll,
+		logger:     logger,
+	}
+}
+
+// Create stores an override under a new ID
+func (r *routingOverrideRepository) Create(ctx context.Context, override *domain.RoutingOverride) error {
+	r.logger.Debug("Creating routing override in MongoDB",
+		zap.String("appName", override.AppName),
+		zap.Int("instanceNumber", override.InstanceNumber))
+
+	override.ID = primitive.NewObjectID().Hex()
+	_, err := r.collection.InsertOne(ctx, override)
+	return err
+}
+
+// Get retrieves a single override of an app
+func (r *routingOverrideRepository) Get(ctx context.Context, appName, id string) (*domain.RoutingOverride, error) {
+	r.logger.Debug("Getting routing override from MongoDB", zap.String("appName", appName), zap.String("id", id))
+
+	var override domain.RoutingOverride
+	err := r.collection.FindOne(ctx, namespaceFilter(ctx, "namespace", bson.M{"appname": appName, "id": id})).Decode(&override)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &override, nil
+}
+
+// List retrieves all overrides of an app, oldest first
+func (r *routingOverrideRepository) List(ctx context.Context, appName string) ([]*domain.RoutingOverride, error) {
+	r.logger.Debug("Listing routing overrides from MongoDB", zap.String("appName", appName))
+	return r.find(ctx, namespaceFilter(ctx, "namespace", bson.M{"appname": appName}))
+}
+
+// Delete removes an override of an app
+func (r *routingOverrideRepository) Delete(ctx context.Context, appName, id string) error {
+	r.logger.Debug("Deleting routing override from MongoDB", zap.String("appName", appName), zap.String("id", id))
+
+	result, err := r.collection.DeleteOne(ctx, namespaceFilter(ctx, "namespace", bson.M{"appname": appName, "id": id}))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListExpired retrieves the overrides that expired before the given time
+func (r *routingOverrideRepository) ListExpired(ctx context.Context, before time.Time) ([]*domain.RoutingOverride, error) {
+	return r.find(ctx, namespaceFilter(ctx, "namespace", bson.M{"expiresat": bson.M{"$lte": before}}))
+}
+
+func (r *routingOverrideRepository) find(ctx context.Context, filter bson.M) ([]*domain.RoutingOverride, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	overrides := []*domain.RoutingOverride{}
+	for cursor.Next(ctx) {
+		var override domain.RoutingOverride
+		if err := cursor.Decode(&override); err != nil {
+			return nil, err
+		}
+
+		overrides = append(overrides, &override)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
@@ -2,10 +2,13 @@ package mongodb
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/smnzlnsk/routing-manager/internal/domain"
 	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
@@ -28,10 +31,48 @@ func NewRoutingRepository(db *mongo.Database, collection string, logger *zap.Log
 
 func (r *routingRepository) GetRouting(ctx context.Context, jobName string) (*domain.Job, error) {
 	r.logger.Debug("Getting routing for job", zap.String("jobName", jobName))
-	return &domain.Job{}, nil
+
+	var job domain.Job
+	err := r.collection.FindOne(ctx, bson.M{"job_name": jobName}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &job, nil
 }
 
+// UpdateRouting writes the routing priorities of all instances of the job in a single update.
+// Instances are matched by their instance number, the remaining job fields are left untouched.
 func (r *routingRepository) UpdateRouting(ctx context.Context, routing *domain.Job) error {
 	r.logger.Debug("Updating routing priorities for job", zap.String("jobName", routing.JobName))
+
+	if len(routing.ServiceInstanceList) == 0 {
+		return nil
+	}
+
+	set := bson.M{}
+	arrayFilters := make([]interface{}, 0, len(routing.ServiceInstanceList))
+	for i, instance := range routing.ServiceInstanceList {
+		identifier := fmt.Sprintf("i%d", i)
+		set[fmt.Sprintf("instance_list.$[%s].routing", identifier)] = instance.RoutingPriority
+		arrayFilters = append(arrayFilters, bson.M{identifier + ".instance_number": instance.InstanceNumber})
+	}
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"job_name": routing.JobName},
+		bson.M{"$set": set},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: arrayFilters}),
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrNotFound
+	}
+
 	return nil
 }
@@ -5,8 +5,8 @@ type Repositories struct {
 	AlertRepository AlertRepository
 
 	// The interest repository is used to store the currently active interests of the service instances.
-	// TODO: This repository can be moved to jobs/jobs database table, as it does not contain any new information.
-	// The interests can instead be taken from the interested_nodes field of each service.
+	// Depending on the configuration the interests are either kept in their own collection,
+	// or taken from the interested_nodes field of each job in the jobs collection.
 	InterestRepository InterestRepository
 
 	// The job repository is used to store the jobs of the service instances.
@@ -19,5 +19,16 @@ type Repositories struct {
 	// The routing-manager only administers the routing priorities for each service instance through this repository.
 	RoutingRepository RoutingRepository
 
+	// The routing history repository keeps a versioned snapshot of the routing priorities
+	// of an app for every applied routing change.
+	RoutingHistoryRepository RoutingHistoryRepository
+
+	// The routing override repository stores the manual priority overrides applied on top of the computed priorities.
+	RoutingOverrideRepository RoutingOverrideRepository
+
+	// The alert rule repository stores the threshold rules created through the API,
+	// rules defined in the configuration are not stored.
+	AlertRuleRepository AlertRuleRepository
+
 	// TODO: Add other repositories here
 }
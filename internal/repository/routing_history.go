@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+type RoutingHistoryRepository interface {
+	// Create stores a snapshot and assigns it the next version of its app
+	Create(ctx context.Context, snapshot *domain.RoutingSnapshot) error
+	Get(ctx context.Context, appName string, version int64) (*domain.RoutingSnapshot, error)
+	// Latest returns the newest snapshot of an app, domain.ErrNotFound if it has none
+	Latest(ctx context.Context, appName string) (*domain.RoutingSnapshot, error)
+	// LatestChange returns the newest snapshot of an app recording a change of the IpType,
+	// domain.ErrNotFound if it has none
+	LatestChange(ctx context.Context, appName string, ipType domain.ServiceIpType) (*domain.RoutingSnapshot, error)
+	// List returns a page of the snapshots of an app, newest first
+	List(ctx context.Context, appName string, query domain.RoutingHistoryQuery) ([]*domain.RoutingSnapshot, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+type RoutingOverrideRepository interface {
+	// Create stores an override and assigns it an ID
+	Create(ctx context.Context, override *domain.RoutingOverride) error
+	Get(ctx context.Context, appName, id string) (*domain.RoutingOverride, error)
+	// List returns the overrides of an app, including expired ones that were not deleted yet
+	List(ctx context.Context, appName string) ([]*domain.RoutingOverride, error)
+	Delete(ctx context.Context, appName, id string) error
+	// ListExpired returns the overrides of all apps in the namespace that expired before the given time
+	ListExpired(ctx context.Context, before time.Time) ([]*domain.RoutingOverride, error)
+}
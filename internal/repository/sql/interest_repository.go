@@ -33,15 +33,15 @@ func (r *interestRepository) GetByServiceIp(ctx context.Context, serviceIp strin
 	return nil, nil
 }
 
-func (r *interestRepository) Update(ctx context.Context, interest *domain.Interest) (*domain.Interest, error) {
+func (r *interestRepository) Update(ctx context.Context, interest *domain.Interest, expectedVersion int64) (*domain.Interest, error) {
 	return nil, nil
 }
 
-func (r *interestRepository) DeleteByAppName(ctx context.Context, appName string) error {
+func (r *interestRepository) DeleteByAppName(ctx context.Context, appName string, expectedVersion int64) error {
 	return nil
 }
 
-func (r *interestRepository) DeleteByServiceIp(ctx context.Context, serviceIp string) error {
+func (r *interestRepository) DeleteByServiceIp(ctx context.Context, serviceIp string, expectedVersion int64) error {
 	return nil
 }
 
@@ -0,0 +1,363 @@
+package rollout
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+// Params configures the rollout of the priorities of a single policy type
+type Params struct {
+	// Steps is the number of cycles a new target is spread over, 0 or 1 applies targets at once
+	Steps int
+	// MaxStep caps the largest change of an instance's priority per cycle, 0 disables the cap
+	MaxStep float64
+}
+
+func (p Params) enabled() bool {
+	return p.Steps > 1 || p.MaxStep > 0
+}
+
+// Config configures the rollouts of all policy types
+type Config struct {
+	Default Params
+	IpTypes map[domain.ServiceIpType]Params
+	// AbortHold is how long computed changes are ignored after a rollout of the app was aborted
+	AbortHold time.Duration
+}
+
+// For returns the parameters for the given policy type
+func (c Config) For(ipType domain.ServiceIpType) Params {
+	if params, ok := c.IpTypes[ipType]; ok {
+		return params
+	}
+	return c.Default
+}
+
+// Validate checks the configuration
+func (c Config) Validate() error {
+	check := func(scope string, params Params) error {
+		if params.Steps < 0 || params.MaxStep < 0 {
+			return fmt.Errorf("%s: steps and max step must not be negative", scope)
+		}
+		return nil
+	}
+
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for ipType, params := range c.IpTypes {
+		if err := check(string(ipType), params); err != nil {
+			return err
+		}
+	}
+	if c.AbortHold < 0 {
+		return fmt.Errorf("abort hold must not be negative")
+	}
+	return nil
+}
+
+// InstanceState holds the priorities of a single instance during a rollout
+type InstanceState struct {
+	InstanceID string  `json:"instanceId"`
+	Start      float64 `json:"start"`
+	Current    float64 `json:"current"`
+	Target     float64 `json:"target"`
+}
+
+// SeriesState is the rollout state of the priorities of one app and policy type
+type SeriesState struct {
+	IpType     domain.ServiceIpType `json:"IpType"`
+	Step       int                  `json:"step"`
+	Steps      int                  `json:"steps"`
+	InProgress bool                 `json:"inProgress"`
+	Aborted    bool                 `json:"aborted"`
+	AbortedAt  *time.Time           `json:"abortedAt,omitempty"`
+	Instances  []InstanceState      `json:"instances"`
+	StartedAt  time.Time            `json:"startedAt"`
+	UpdatedAt  time.Time            `json:"updatedAt"`
+}
+
+type seriesKey struct {
+	namespace string
+	appName   string
+	ipType    domain.ServiceIpType
+}
+
+type series struct {
+	start     map[string]float64
+	current   map[string]float64
+	target    map[string]float64
+	step      int
+	aborted   bool
+	abortedAt time.Time
+	startedAt time.Time
+	updatedAt time.Time
+}
+
+func (s *series) inProgress() bool {
+	return !equalPriorities(s.current, s.target)
+}
+
+// Controller spreads large priority changes over several cycles, so that no instance is overwhelmed
+// by traffic moving at once. It is stateful and keeps one series per app and policy type.
+type Controller struct {
+	config Config
+	series map[seriesKey]*series
+	mutex  sync.Mutex
+}
+
+var _ domain.Observer = &Controller{}
+
+// NewController creates a new Controller
+func NewController(config Config) *Controller {
+	return &Controller{
+		config: config,
+		series: make(map[seriesKey]*series),
+	}
+}
+
+// Advance moves the priorities of a routing change one step towards its target.
+// newTarget reports whether the change carries a new target, otherwise an ongoing rollout is continued.
+// persisted holds the priorities currently stored for the change's IpType, keyed by instance ID.
+// The priorities of the change are replaced in place, Advance returns false if there is nothing to persist.
+func (c *Controller) Advance(routingChange *domain.RoutingChange, newTarget bool, persisted map[string]float64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	params := c.config.For(routingChange.IpType)
+	key := seriesKey{routingChange.Namespace, routingChange.AppName, routingChange.IpType}
+	now := time.Now()
+
+	current, ok := c.series[key]
+	if ok && current.aborted {
+		if now.Sub(current.abortedAt) < c.config.AbortHold {
+			return false
+		}
+		delete(c.series, key)
+		ok = false
+	}
+	if !params.enabled() {
+		delete(c.series, key)
+		return newTarget
+	}
+	if !ok && !newTarget {
+		// Without a rollout in progress there is nothing to continue, a held back change is no target
+		return false
+	}
+
+	target := make(map[string]float64, len(routingChange.InstancePriorityList))
+	for _, entry := range routingChange.InstancePriorityList {
+		target[entry.InstanceID] = entry.Priority
+	}
+
+	if !ok {
+		current = &series{
+			current:   align(persisted, target),
+			target:    copyPriorities(target),
+			startedAt: now,
+		}
+		current.start = copyPriorities(current.current)
+		c.series[key] = current
+	} else if newTarget && !equalPriorities(target, current.target) {
+		// A new target starts a new rollout from wherever the previous one got to
+		current.current = align(current.current, target)
+		current.start = copyPriorities(current.current)
+		current.target = copyPriorities(target)
+		current.step = 0
+		current.startedAt = now
+	}
+
+	if !current.inProgress() {
+		if !newTarget {
+			return false
+		}
+	} else {
+		current.advance(params)
+	}
+	current.updatedAt = now
+
+	for i := range routingChange.InstancePriorityList {
+		routingChange.InstancePriorityList[i].Priority = current.current[routingChange.InstancePriorityList[i].InstanceID]
+	}
+	return true
+}
+
+// advance moves every priority an equal share of its remaining distance. The maximum step scales the
+// whole step down, rather than capping each priority, so that the priorities keep their sum.
+func (s *series) advance(params Params) {
+	stepsLeft := 1
+	if params.Steps > s.step {
+		stepsLeft = params.Steps - s.step
+	}
+
+	deltas := make(map[string]float64, len(s.target))
+	largest := 0.0
+	for instanceID, target := range s.target {
+		deltas[instanceID] = (target - s.current[instanceID]) / float64(stepsLeft)
+		largest = math.Max(largest, math.Abs(deltas[instanceID]))
+	}
+
+	scale := 1.0
+	if params.MaxStep > 0 && largest > params.MaxStep {
+		scale = params.MaxStep / largest
+	}
+
+	for instanceID, target := range s.target {
+		if stepsLeft == 1 && scale == 1 {
+			s.current[instanceID] = target
+		} else {
+			s.current[instanceID] += deltas[instanceID] * scale
+		}
+	}
+	s.step++
+}
+
+// Abort stops the rollouts of an app and holds off new ones for the configured abort hold.
+// It returns the changes restoring the priorities of the rollouts that were in progress, keyed by IpType.
+func (c *Controller) Abort(namespace, appName string) map[domain.ServiceIpType]*domain.RoutingChange {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	reverted := make(map[domain.ServiceIpType]*domain.RoutingChange)
+	for key, current := range c.series {
+		if key.namespace != namespace || key.appName != appName {
+			continue
+		}
+
+		if current.inProgress() && !current.aborted {
+			current.current = copyPriorities(current.start)
+			current.target = copyPriorities(current.start)
+			reverted[key.ipType] = toChange(key, current.start)
+		}
+		current.aborted = true
+		current.abortedAt = now
+		current.updatedAt = now
+	}
+	return reverted
+}
+
+// State returns the rollout state of all policy types of an app
+func (c *Controller) State(namespace, appName string) []SeriesState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	states := []SeriesState{}
+	for key, current := range c.series {
+		if key.namespace != namespace || key.appName != appName {
+			continue
+		}
+
+		state := SeriesState{
+			IpType:     key.ipType,
+			Step:       current.step,
+			Steps:      c.config.For(key.ipType).Steps,
+			InProgress: current.inProgress(),
+			Aborted:    current.aborted,
+			Instances:  make([]InstanceState, 0, len(current.target)),
+			StartedAt:  current.startedAt,
+			UpdatedAt:  current.updatedAt,
+		}
+		if current.aborted {
+			abortedAt := current.abortedAt
+			state.AbortedAt = &abortedAt
+		}
+		for instanceID, target := range current.target {
+			state.Instances = append(state.Instances, InstanceState{
+				InstanceID: instanceID,
+				Start:      current.start[instanceID],
+				Current:    current.current[instanceID],
+				Target:     target,
+			})
+		}
+		sort.Slice(state.Instances, func(i, j int) bool {
+			return state.Instances[i].InstanceID < state.Instances[j].InstanceID
+		})
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].IpType < states[j].IpType
+	})
+	return states
+}
+
+// Forget drops the rollout state of an app
+func (c *Controller) Forget(namespace, appName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.series {
+		if key.namespace == namespace && key.appName == appName {
+			delete(c.series, key)
+		}
+	}
+}
+
+// Update drops the rollout state of deleted interests
+func (c *Controller) Update(event domain.InterestEvent) {
+	if event.Type == domain.InterestDeleted {
+		c.Forget(event.Interest.Namespace, event.Interest.AppName)
+	}
+}
+
+// GetID returns the ID of the observer
+func (c *Controller) GetID() string {
+	return "RolloutController"
+}
+
+// align returns the priorities of the target's instances taken from from.
+// Instances missing in from start at zero, so that new instances ramp up as well.
+func align(from, target map[string]float64) map[string]float64 {
+	result := make(map[string]float64, len(target))
+	for instanceID := range target {
+		result[instanceID] = from[instanceID]
+	}
+	return result
+}
+
+func toChange(key seriesKey, priorities map[string]float64) *domain.RoutingChange {
+	change := &domain.RoutingChange{
+		Namespace: key.namespace,
+		AppName:   key.appName,
+		IpType:    key.ipType,
+	}
+	for instanceID, priority := range priorities {
+		change.InstancePriorityList = append(change.InstancePriorityList, domain.InstancePriorityEntry{
+			InstanceID: instanceID,
+			Priority:   priority,
+		})
+	}
+	sort.Slice(change.InstancePriorityList, func(i, j int) bool {
+		a, _ := strconv.Atoi(change.InstancePriorityList[i].InstanceID)
+		b, _ := strconv.Atoi(change.InstancePriorityList[j].InstanceID)
+		return a < b
+	})
+	return change
+}
+
+func equalPriorities(a, b map[string]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for instanceID, priority := range a {
+		if other, ok := b[instanceID]; !ok || other != priority {
+			return false
+		}
+	}
+	return true
+}
+
+func copyPriorities(priorities map[string]float64) map[string]float64 {
+	result := make(map[string]float64, len(priorities))
+	for instanceID, priority := range priorities {
+		result[instanceID] = priority
+	}
+	return result
+}
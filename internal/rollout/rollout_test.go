@@ -0,0 +1,216 @@
+package rollout
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+func change(priorities ...float64) *domain.RoutingChange {
+	c := &domain.RoutingChange{
+		Namespace: domain.DefaultNamespace,
+		AppName:   "web",
+		IpType:    domain.ServiceIpTypeClosest,
+	}
+	for i, priority := range priorities {
+		c.InstancePriorityList = append(c.InstancePriorityList, domain.InstancePriorityEntry{
+			InstanceID: string(rune('0' + i)),
+			Priority:   priority,
+		})
+	}
+	return c
+}
+
+func assertPriorities(t *testing.T, c *domain.RoutingChange, want ...float64) {
+	t.Helper()
+	if len(c.InstancePriorityList) != len(want) {
+		t.Fatalf("got %d priorities, want %d", len(c.InstancePriorityList), len(want))
+	}
+	for i, entry := range c.InstancePriorityList {
+		if math.Abs(entry.Priority-want[i]) > 1e-9 {
+			t.Errorf("instance %s: got priority %v, want %v", entry.InstanceID, entry.Priority, want[i])
+		}
+	}
+}
+
+func TestAdvanceHeldBackChangeWithoutRollout(t *testing.T) {
+	c := NewController(Config{Default: Params{Steps: 4}})
+
+	held := change(0, 1)
+	if c.Advance(held, false, map[string]float64{"0": 1, "1": 0}) {
+		t.Fatal("a held back change without a rollout in progress must not be persisted")
+	}
+	assertPriorities(t, held, 0, 1)
+	if states := c.State(domain.DefaultNamespace, "web"); len(states) != 0 {
+		t.Fatalf("a held back change must not start a rollout, got %+v", states)
+	}
+}
+
+func TestAdvanceDisabled(t *testing.T) {
+	c := NewController(Config{})
+
+	for _, newTarget := range []bool{true, false} {
+		target := change(0, 1)
+		if got := c.Advance(target, newTarget, map[string]float64{"0": 1, "1": 0}); got != newTarget {
+			t.Errorf("newTarget %v: got %v", newTarget, got)
+		}
+		assertPriorities(t, target, 0, 1)
+	}
+}
+
+func TestAdvanceSteps(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		// steps holds the priorities persisted after each cycle
+		steps [][]float64
+	}{
+		{
+			name:   "steps",
+			params: Params{Steps: 4},
+			steps:  [][]float64{{0.75, 0.25}, {0.5, 0.5}, {0.25, 0.75}, {0, 1}},
+		},
+		{
+			name:   "max step",
+			params: Params{MaxStep: 0.4},
+			steps:  [][]float64{{0.6, 0.4}, {0.2, 0.8}, {0, 1}},
+		},
+		{
+			name:   "max step caps steps",
+			params: Params{Steps: 2, MaxStep: 0.3},
+			steps:  [][]float64{{0.7, 0.3}, {0.4, 0.6}, {0.1, 0.9}, {0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController(Config{Default: tt.params})
+			persisted := map[string]float64{"0": 1, "1": 0}
+
+			for i, want := range tt.steps {
+				// The target arrives once, every later cycle continues the rollout
+				cycle := change(0, 1)
+				if !c.Advance(cycle, i == 0, persisted) {
+					t.Fatalf("cycle %d: rollout in progress was not persisted", i)
+				}
+				assertPriorities(t, cycle, want...)
+			}
+
+			done := change(0, 1)
+			if c.Advance(done, false, nil) {
+				t.Fatal("a finished rollout must not be persisted again")
+			}
+		})
+	}
+}
+
+func TestAdvanceKeepsSum(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    Params
+		persisted []float64
+		target    []float64
+	}{
+		{
+			name:      "max step",
+			params:    Params{MaxStep: 0.4},
+			persisted: []float64{1, 0, 0},
+			target:    []float64{0, 0.5, 0.5},
+		},
+		{
+			name:      "max step caps steps",
+			params:    Params{Steps: 3, MaxStep: 0.1},
+			persisted: []float64{0.1, 0.2, 0.3, 0.4},
+			target:    []float64{0.4, 0.05, 0.05, 0.5},
+		},
+		{
+			name:      "steps",
+			params:    Params{Steps: 3},
+			persisted: []float64{0.7, 0.2, 0.1, 0},
+			target:    []float64{0, 0.15, 0.25, 0.6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController(Config{Default: tt.params})
+			persisted := make(map[string]float64, len(tt.persisted))
+			for i, priority := range tt.persisted {
+				persisted[string(rune('0'+i))] = priority
+			}
+
+			for i := 0; ; i++ {
+				if i == 100 {
+					t.Fatal("rollout did not reach the target")
+				}
+				cycle := change(tt.target...)
+				if !c.Advance(cycle, i == 0, persisted) {
+					break
+				}
+
+				sum := 0.0
+				for _, entry := range cycle.InstancePriorityList {
+					sum += entry.Priority
+				}
+				if math.Abs(sum-1) > 1e-9 {
+					t.Fatalf("cycle %d: priorities %+v sum to %v", i, cycle.InstancePriorityList, sum)
+				}
+				if tt.params.MaxStep > 0 {
+					for _, entry := range cycle.InstancePriorityList {
+						previous := persisted[entry.InstanceID]
+						if math.Abs(entry.Priority-previous) > tt.params.MaxStep+1e-9 {
+							t.Fatalf("cycle %d: instance %s moved from %v to %v", i, entry.InstanceID, previous, entry.Priority)
+						}
+					}
+				}
+				for _, entry := range cycle.InstancePriorityList {
+					persisted[entry.InstanceID] = entry.Priority
+				}
+			}
+
+			final := change(tt.target...)
+			for _, entry := range final.InstancePriorityList {
+				if math.Abs(persisted[entry.InstanceID]-entry.Priority) > 1e-9 {
+					t.Fatalf("got priorities %v, want %v", persisted, tt.target)
+				}
+			}
+		})
+	}
+}
+
+func TestAdvanceNewTargetRestartsFromCurrent(t *testing.T) {
+	c := NewController(Config{Default: Params{Steps: 2}})
+
+	first := change(0, 1)
+	c.Advance(first, true, map[string]float64{"0": 1, "1": 0})
+	assertPriorities(t, first, 0.5, 0.5)
+
+	second := change(1, 0)
+	if !c.Advance(second, true, nil) {
+		t.Fatal("new target was not persisted")
+	}
+	assertPriorities(t, second, 0.75, 0.25)
+}
+
+func TestAbort(t *testing.T) {
+	c := NewController(Config{Default: Params{Steps: 4}, AbortHold: time.Hour})
+
+	c.Advance(change(0, 1), true, map[string]float64{"0": 1, "1": 0})
+
+	reverted := c.Abort(domain.DefaultNamespace, "web")
+	revert, ok := reverted[domain.ServiceIpTypeClosest]
+	if !ok {
+		t.Fatalf("rollout in progress was not reverted, got %+v", reverted)
+	}
+	assertPriorities(t, revert, 1, 0)
+
+	if c.Advance(change(0, 1), true, nil) {
+		t.Fatal("changes must be held off after an abort")
+	}
+	states := c.State(domain.DefaultNamespace, "web")
+	if len(states) != 1 || !states[0].Aborted || states[0].InProgress {
+		t.Fatalf("got state %+v, want an aborted rollout", states)
+	}
+}
@@ -2,30 +2,124 @@ package service
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/smnzlnsk/routing-manager/internal/domain"
 	"github.com/smnzlnsk/routing-manager/internal/repository"
 	"go.uber.org/zap"
 )
 
+// AlertConfig holds the tunables of the alert service
+type AlertConfig struct {
+	// DedupWindow is how long after its last occurrence an alert collapses duplicates, deduplication is disabled unless it is positive
+	DedupWindow time.Duration
+}
+
 type AlertService interface {
-	HandleAlert(ctx context.Context, alert *domain.Alert) error
+	// HandleAlert stores the alert, or counts it as another occurrence of a recent duplicate.
+	// Only alerts that are not duplicates are passed on to the alert observers.
+	HandleAlert(ctx context.Context, alert *domain.Alert) (*domain.Alert, error)
+	Get(ctx context.Context, id string) (*domain.Alert, error)
+	List(ctx context.Context, filter domain.AlertFilter) ([]*domain.Alert, error)
+	// Acknowledge and Resolve move an alert along its lifecycle and record who did so
+	Acknowledge(ctx context.Context, id, by string) (*domain.Alert, error)
+	Resolve(ctx context.Context, id, by string) (*domain.Alert, error)
 }
 
 type alertService struct {
-	repo   repository.AlertRepository
-	logger *zap.Logger
+	repo    repository.AlertRepository
+	subject domain.AlertSubject
+	config  AlertConfig
+	logger  *zap.Logger
 }
 
-func NewAlertService(repo repository.AlertRepository, logger *zap.Logger) AlertService {
+func NewAlertService(repo repository.AlertRepository, subject domain.AlertSubject, config AlertConfig, logger *zap.Logger) AlertService {
 	return &alertService{
-		repo:   repo,
-		logger: logger,
+		repo:    repo,
+		subject: subject,
+		config:  config,
+		logger:  logger,
 	}
 }
 
-func (s *alertService) HandleAlert(ctx context.Context, alert *domain.Alert) error {
+func (s *alertService) HandleAlert(ctx context.Context, alert *domain.Alert) (*domain.Alert, error) {
+	now := time.Now()
+	alert.Namespace = domain.NamespaceFromContext(ctx)
+	if alert.Severity == "" {
+		alert.Severity = domain.AlertSeverityWarning
+	}
+	alert.Fingerprint = alert.ComputeFingerprint()
 	s.logger.Info("Handling alert", zap.Any("alert", alert))
 
-	return nil
+	if s.config.DedupWindow > 0 {
+		duplicate, err := s.repo.RecordDuplicate(ctx, alert.Fingerprint, now.Add(-s.config.DedupWindow), now)
+		if err == nil {
+			s.logger.Debug("Collapsed duplicate alert",
+				zap.String("id", duplicate.ID),
+				zap.Int("count", duplicate.Count))
+			return duplicate, nil
+		}
+		var domainErr *domain.Error
+		if !errors.As(err, &domainErr) || domainErr.Code != domain.CodeNotFound {
+			return nil, err
+		}
+	}
+
+	alert.Count = 1
+	alert.State = domain.AlertStateFiring
+	alert.CreatedAt = now
+	alert.LastSeenAt = now
+	if err := s.repo.Create(ctx, alert); err != nil {
+		return nil, err
+	}
+
+	if s.subject != nil {
+		s.subject.Notify(domain.AlertEvent{
+			Type:  domain.AlertRaised,
+			Alert: alert,
+		})
+	}
+
+	return alert, nil
+}
+
+func (s *alertService) Get(ctx context.Context, id string) (*domain.Alert, error) {
+	s.logger.Debug("Getting alert", zap.String("id", id))
+	return s.repo.Get(ctx, id)
+}
+
+func (s *alertService) List(ctx context.Context, filter domain.AlertFilter) ([]*domain.Alert, error) {
+	s.logger.Debug("Listing alerts", zap.Any("filter", filter))
+	return s.repo.List(ctx, filter)
+}
+
+func (s *alertService) Acknowledge(ctx context.Context, id, by string) (*domain.Alert, error) {
+	return s.transition(ctx, id, domain.AlertStateAcknowledged, domain.AlertAcknowledged, by)
+}
+
+func (s *alertService) Resolve(ctx context.Context, id, by string) (*domain.Alert, error) {
+	return s.transition(ctx, id, domain.AlertStateResolved, domain.AlertResolved, by)
+}
+
+// transition moves an alert to the given state and notifies the alert observers
+func (s *alertService) transition(ctx context.Context, id string, to domain.AlertState, eventType domain.EventType, by string) (*domain.Alert, error) {
+	s.logger.Info("Transitioning alert",
+		zap.String("id", id),
+		zap.String("state", string(to)),
+		zap.String("by", by))
+
+	alert, err := s.repo.Transition(ctx, id, to, by, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.subject != nil {
+		s.subject.Notify(domain.AlertEvent{
+			Type:  eventType,
+			Alert: alert,
+		})
+	}
+
+	return alert, nil
 }
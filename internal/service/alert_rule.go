@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ConfiguredAlertRule is an alert rule defined in the configuration
+type ConfiguredAlertRule struct {
+	Namespace string
+	Rule      domain.AlertRuleRequest
+}
+
+// AlertRulesConfig holds the tunables of the alert rule engine
+type AlertRulesConfig struct {
+	// Interval between rule evaluations
+	Interval time.Duration
+	// Rules defined in the configuration, they cannot be changed through the API
+	Rules []ConfiguredAlertRule
+}
+
+// Validate checks the alert rule configuration
+func (c AlertRulesConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("alert rule interval must be positive")
+	}
+	seen := make(map[string]bool, len(c.Rules))
+	for _, configured := range c.Rules {
+		if err := domain.ValidateNamespace(configured.Namespace); err != nil {
+			return fmt.Errorf("alert rule %s: namespace %v", configured.Rule.Name, err)
+		}
+		if err := configured.Rule.Validate(); err != nil {
+			return fmt.Errorf("alert rule %s: %w", configured.Rule.Name, err)
+		}
+		key := configured.Namespace + "/" + configured.Rule.Name
+		if seen[key] {
+			return fmt.Errorf("alert rule %s is defined more than once", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+type AlertRuleService interface {
+	Create(ctx context.Context, request *domain.AlertRuleRequest) (*domain.AlertRule, error)
+	Get(ctx context.Context, id string) (*domain.AlertRule, error)
+	// List returns the rules of the namespace, both configured and created through the API
+	List(ctx context.Context) ([]*domain.AlertRule, error)
+	Delete(ctx context.Context, id string) error
+	// State returns the evaluation state of the rules of the namespace for every app they apply to
+	State(ctx context.Context) ([]domain.AlertRuleState, error)
+	// Run periodically evaluates the rules against the stored metrics until the context is done
+	Run(ctx context.Context)
+}
+
+// ruleEvaluation is the evaluation state of a rule for a single app
+type ruleEvaluation struct {
+	namespace string
+	state     domain.AlertRuleState
+}
+
+type alertRuleService struct {
+	repo            repository.AlertRuleRepository
+	alertService    AlertService
+	interestService InterestService
+	store           storage.MetricStore
+	interval        time.Duration
+	configured      []*domain.AlertRule
+	logger          *zap.Logger
+
+	mutex sync.Mutex
+	// evaluations are keyed by namespace, rule ID and service ID
+	evaluations map[string]*ruleEvaluation
+}
+
+func NewAlertRuleService(repo repository.AlertRuleRepository, alertService AlertService, interestService InterestService, store storage.MetricStore, config AlertRulesConfig, logger *zap.Logger) AlertRuleService {
+	now := time.Now()
+	configured := make([]*domain.AlertRule, 0, len(config.Rules))
+	for _, rule := range config.Rules {
+		configured = append(configured, newAlertRule(rule.Namespace, &rule.Rule, domain.AlertRuleSourceConfig, now))
+	}
+
+	return &alertRuleService{
+		repo:            repo,
+		alertService:    alertService,
+		interestService: interestService,
+		store:           store,
+		interval:        config.Interval,
+		configured:      configured,
+		logger:          logger,
+		evaluations:     make(map[string]*ruleEvaluation),
+	}
+}
+
+// newAlertRule builds a rule from its request, configured rules are identified by their name
+func newAlertRule(namespace string, request *domain.AlertRuleRequest, source domain.AlertRuleSource, now time.Time) *domain.AlertRule {
+	rule := &domain.AlertRule{
+		Namespace:  namespace,
+		Name:       request.Name,
+		Metric:     request.Metric,
+		Comparator: request.Comparator,
+		Threshold:  request.Threshold,
+		Duration:   request.Duration,
+		Severity:   request.Severity,
+		Apps:       request.Apps,
+		Source:     source,
+		CreatedAt:  now,
+	}
+	if rule.Severity == "" {
+		rule.Severity = domain.AlertSeverityWarning
+	}
+	if source == domain.AlertRuleSourceConfig {
+		rule.ID = "config-" + rule.Name
+	}
+	return rule
+}
+
+func (s *alertRuleService) Create(ctx context.Context, request *domain.AlertRuleRequest) (*domain.AlertRule, error) {
+	s.logger.Info("Creating alert rule", zap.String("name", request.Name))
+
+	namespace := domain.NamespaceFromContext(ctx)
+	for _, rule := range s.configured {
+		if rule.Namespace == namespace && rule.Name == request.Name {
+			return nil, domain.ErrAlertRuleExists
+		}
+	}
+
+	rule := newAlertRule(namespace, request, domain.AlertRuleSourceAPI, time.Now())
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *alertRuleService) Get(ctx context.Context, id string) (*domain.AlertRule, error) {
+	s.logger.Debug("Getting alert rule", zap.String("id", id))
+
+	namespace := domain.NamespaceFromContext(ctx)
+	for _, rule := range s.configured {
+		if rule.Namespace == namespace && rule.ID == id {
+			return rule, nil
+		}
+	}
+	return s.repo.Get(ctx, id)
+}
+
+func (s *alertRuleService) List(ctx context.Context) ([]*domain.AlertRule, error) {
+	s.logger.Debug("Listing alert rules")
+
+	rules, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := domain.NamespaceFromContext(ctx)
+	for _, rule := range s.configured {
+		if namespace == domain.AllNamespaces || rule.Namespace == namespace {
+			rules = append(rules, rule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Namespace != rules[j].Namespace {
+			return rules[i].Namespace < rules[j].Namespace
+		}
+		return rules[i].Name < rules[j].Name
+	})
+	return rules, nil
+}
+
+func (s *alertRuleService) Delete(ctx context.Context, id string) error {
+	s.logger.Info("Deleting alert rule", zap.String("id", id))
+
+	namespace := domain.NamespaceFromContext(ctx)
+	for _, rule := range s.configured {
+		if rule.Namespace == namespace && rule.ID == id {
+			return domain.ErrAlertRuleReadOnly
+		}
+	}
+	// Alerts of the deleted rule are resolved by the next evaluation
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *alertRuleService) State(ctx context.Context) ([]domain.AlertRuleState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	namespace := domain.NamespaceFromContext(ctx)
+	states := []domain.AlertRuleState{}
+	for _, evaluation := range s.evaluations {
+		if namespace == domain.AllNamespaces || evaluation.namespace == namespace {
+			states = append(states, evaluation.state)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Name != states[j].Name {
+			return states[i].Name < states[j].Name
+		}
+		return states[i].AppName < states[j].AppName
+	})
+	return states, nil
+}
+
+func (s *alertRuleService) Run(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.evaluate(ctx); err != nil && ctx.Err() == nil {
+					s.logger.Error("Failed to evaluate alert rules", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// evaluate evaluates every rule for every series of the rule's metric labeled with a selected app.
+// Metrics are not namespaced, a rule only applies to the apps with an interest in the rule's namespace.
+func (s *alertRuleService) evaluate(ctx context.Context) error {
+	ctx = domain.WithNamespace(ctx, domain.AllNamespaces)
+	rules, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	interests, err := s.interestService.List(ctx)
+	if err != nil {
+		return err
+	}
+	// apps are keyed by namespace and app name
+	apps := make(map[string]map[string]bool)
+	for _, interest := range interests {
+		if apps[interest.Namespace] == nil {
+			apps[interest.Namespace] = make(map[string]bool)
+		}
+		apps[interest.Namespace][interest.AppName] = true
+	}
+
+	latest, err := s.store.Latest(ctx, storage.Selector{})
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	evaluated := make(map[string]bool, len(s.evaluations))
+	for _, rule := range rules {
+		for _, series := range latest {
+			appName := series.Labels[storage.LabelApp]
+			if series.Metric != rule.Metric || appName == "" || !apps[rule.Namespace][appName] || !rule.Selects(appName) {
+				continue
+			}
+
+			key := rule.Namespace + "|" + rule.ID + "|" + storage.SeriesKey(series.Metric, series.Labels)
+			evaluated[key] = true
+			s.evaluateSeries(ctx, rule, series.Labels, key, now)
+		}
+	}
+
+	// Rules that were deleted or no longer apply resolve their alerts
+	for key, evaluation := range s.evaluations {
+		if evaluated[key] {
+			continue
+		}
+		if evaluation.state.Status == domain.AlertRuleFiring && !s.resolve(ctx, evaluation) {
+			continue
+		}
+		delete(s.evaluations, key)
+	}
+
+	return nil
+}
+
+// evaluateSeries advances the evaluation of a rule for a single series
+func (s *alertRuleService) evaluateSeries(ctx context.Context, rule *domain.AlertRule, labels storage.Labels, key string, now time.Time) {
+	evaluation, ok := s.evaluations[key]
+	if !ok {
+		evaluation = &ruleEvaluation{
+			namespace: rule.Namespace,
+			state: domain.AlertRuleState{
+				RuleID:         rule.ID,
+				Name:           rule.Name,
+				AppName:        labels[storage.LabelApp],
+				InstanceNumber: labels.Instance(),
+				Labels:         labels,
+				Status:         domain.AlertRuleInactive,
+				Since:          now,
+			},
+		}
+		s.evaluations[key] = evaluation
+	}
+	state := &evaluation.state
+	state.LastEvaluatedAt = now
+	state.Error = ""
+
+	// Without a duration only the latest value counts
+	window := rule.Window()
+	since, limit := now.Add(-window), 0
+	if window == 0 {
+		since, limit = time.Time{}, 1
+	}
+
+	history, err := s.store.History(ctx, storage.ExactSelector(rule.Metric, labels), since, limit)
+	if err != nil {
+		state.Error = err.Error()
+		return
+	}
+	var records []storage.Point
+	if series := storage.FindSeries(history, labels); series != nil {
+		records = series.Points
+	}
+
+	// Every value within the window must breach the threshold, records are ordered newest first
+	breaching := len(records) > 0
+	for _, record := range records {
+		if !rule.Comparator.Breaches(record.Value, rule.Threshold) {
+			breaching = false
+			break
+		}
+	}
+	if len(records) > 0 {
+		value := records[0].Value
+		state.Value = &value
+	}
+
+	switch {
+	case breaching && state.Status == domain.AlertRuleInactive:
+		state.Status = domain.AlertRulePending
+		state.Since = now
+		if window == 0 {
+			s.fire(ctx, rule, evaluation, now)
+		}
+	case breaching && state.Status == domain.AlertRulePending:
+		if now.Sub(state.Since) >= window {
+			s.fire(ctx, rule, evaluation, now)
+		}
+	case !breaching && state.Status == domain.AlertRulePending:
+		state.Status = domain.AlertRuleInactive
+		state.Since = now
+	case !breaching && state.Status == domain.AlertRuleFiring:
+		if s.resolve(ctx, evaluation) {
+			state.Status = domain.AlertRuleInactive
+			state.Since = now
+			state.AlertID = ""
+		}
+	}
+}
+
+// fire raises the alert of a rule for the app of the evaluation
+func (s *alertRuleService) fire(ctx context.Context, rule *domain.AlertRule, evaluation *ruleEvaluation, now time.Time) {
+	state := &evaluation.state
+
+	message := fmt.Sprintf("%s %s %g", rule.Metric, rule.Comparator, rule.Threshold)
+	if state.Value != nil {
+		message = fmt.Sprintf("%s (value %g)", message, *state.Value)
+	}
+
+	alert, err := s.alertService.HandleAlert(domain.WithNamespace(ctx, evaluation.namespace), &domain.Alert{
+		AppName:        state.AppName,
+		InstanceNumber: state.InstanceNumber,
+		Severity:       rule.Severity,
+		Source:         ruleSource(rule.Name),
+		Message:        message,
+	})
+	if err != nil {
+		s.logger.Error("Failed to raise alert of rule",
+			zap.String("rule", rule.Name),
+			zap.String("appName", state.AppName),
+			zap.Error(err))
+		state.Error = err.Error()
+		return
+	}
+
+	state.Status = domain.AlertRuleFiring
+	state.Since = now
+	state.AlertID = alert.ID
+}
+
+// resolve resolves the alert raised for the evaluation, it reports whether the alert is no longer open
+func (s *alertRuleService) resolve(ctx context.Context, evaluation *ruleEvaluation) bool {
+	state := &evaluation.state
+
+	_, err := s.alertService.Resolve(domain.WithNamespace(ctx, evaluation.namespace), state.AlertID, ruleSource(state.Name))
+	if err != nil {
+		// The alert may have been resolved by hand or removed in the meantime
+		var domainErr *domain.Error
+		if errors.As(err, &domainErr) && (domainErr.Code == domain.CodeInvalidTransition || domainErr.Code == domain.CodeNotFound) {
+			return true
+		}
+		s.logger.Error("Failed to resolve alert of rule",
+			zap.String("rule", state.Name),
+			zap.String("appName", state.AppName),
+			zap.Error(err))
+		state.Error = err.Error()
+		return false
+	}
+	return true
+}
+
+// ruleSource is the source of the alerts raised by a rule
+func ruleSource(name string) string {
+	return "rule/" + name
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"go.uber.org/zap"
+)
+
+// alertTaskObserver executes the task of an app as soon as an alert is raised for it,
+// so that its routing reacts without waiting for the next scheduled run
+type alertTaskObserver struct {
+	interestService InterestService
+	taskExecutor    domain.TaskExecutor
+	logger          *zap.Logger
+}
+
+var _ domain.AlertObserver = &alertTaskObserver{}
+
+// NewAlertTaskObserver creates an alert observer executing the task of the alert's app out of band
+func NewAlertTaskObserver(interestService InterestService, taskExecutor domain.TaskExecutor, logger *zap.Logger) domain.AlertObserver {
+	return &alertTaskObserver{
+		interestService: interestService,
+		taskExecutor:    taskExecutor,
+		logger:          logger,
+	}
+}
+
+// OnAlertEvent executes the task of the alert's app
+func (o *alertTaskObserver) OnAlertEvent(event domain.AlertEvent) {
+	if event.Type != domain.AlertRaised {
+		return
+	}
+
+	ctx := domain.WithNamespace(context.Background(), event.Alert.Namespace)
+	interest, err := o.interestService.GetByAppName(ctx, event.Alert.AppName)
+	if err != nil {
+		o.logger.Debug("No interest to execute on alert",
+			zap.String("namespace", event.Alert.Namespace),
+			zap.String("appName", event.Alert.AppName),
+			zap.Error(err))
+		return
+	}
+
+	if err := o.taskExecutor.ExecuteTask(interest); err != nil {
+		o.logger.Error("Failed to execute task on alert",
+			zap.String("namespace", event.Alert.Namespace),
+			zap.String("appName", event.Alert.AppName),
+			zap.Error(err))
+	}
+}
+
+// GetID returns the ID of the observer
+func (o *alertTaskObserver) GetID() string {
+	return "AlertTaskObserver"
+}
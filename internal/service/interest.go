@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/smnzlnsk/routing-manager/internal/domain"
@@ -14,23 +15,34 @@ type InterestService interface {
 	Create(ctx context.Context, interest *domain.Interest) (*domain.Interest, error)
 	GetByAppName(ctx context.Context, appName string) (*domain.Interest, error)
 	GetByServiceIp(ctx context.Context, serviceIp string) (*domain.Interest, error)
-	Update(ctx context.Context, interest *domain.Interest) (*domain.Interest, error)
-	DeleteByAppName(ctx context.Context, appName string) error
-	DeleteByServiceIp(ctx context.Context, serviceIp string) error
+	Update(ctx context.Context, interest *domain.Interest, expectedVersion int64) (*domain.Interest, error)
+	DeleteByAppName(ctx context.Context, appName string, expectedVersion int64) error
+	DeleteByServiceIp(ctx context.Context, serviceIp string, expectedVersion int64) error
 	List(ctx context.Context) ([]*domain.Interest, error)
 }
 
 type interestService struct {
 	repo    repository.InterestRepository
+	jobRepo repository.JobRepository
 	logger  *zap.Logger
 	subject domain.Subject
+	quotas  domain.NamespaceQuotas
+
+	// createLocks serializes the creates of each namespace, so that concurrent creates cannot exceed its quota.
+	// They do not extend to other replicas sharing the database, the quota assumes a single replica.
+	createLocks map[string]*sync.Mutex
+	locksMutex  sync.Mutex
 }
 
-func NewInterestService(repo repository.InterestRepository, subject domain.Subject, logger *zap.Logger) InterestService {
+func NewInterestService(repo repository.InterestRepository, jobRepo repository.JobRepository, subject domain.Subject, quotas domain.NamespaceQuotas, logger *zap.Logger) InterestService {
 	return &interestService{
 		repo:    repo,
+		jobRepo: jobRepo,
 		logger:  logger,
 		subject: subject,
+		quotas:  quotas,
+
+		createLocks: make(map[string]*sync.Mutex),
 	}
 }
 
@@ -53,13 +65,26 @@ func (s *interestService) Create(ctx context.Context, interest *domain.Interest)
 		return nil, domain.ErrInterestAlreadyExists
 	}
 
+	namespace := domain.NamespaceFromContext(ctx)
+	unlock := s.lockCreates(namespace)
+	defer unlock()
+	if err := s.checkInterestQuota(ctx, namespace); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	i := &domain.Interest{
+		Namespace: namespace,
 		AppName:   interest.AppName,
 		ServiceIp: interest.ServiceIp,
+		Policies:  interest.Policies,
+		Version:   1,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
+	if err := s.resolveServiceIps(ctx, i); err != nil {
+		return nil, err
+	}
 
 	s.logger.Info("Creating interest in repo", zap.Any("interest", i))
 
@@ -86,48 +111,148 @@ func (s *interestService) GetByAppName(ctx context.Context, appName string) (*do
 
 func (s *interestService) GetByServiceIp(ctx context.Context, serviceIp string) (*domain.Interest, error) {
 	s.logger.Debug("Getting interest by service IP", zap.String("serviceIp", serviceIp))
+	serviceIp, err := canonicalServiceIp(serviceIp)
+	if err != nil {
+		return nil, err
+	}
 	return s.repo.GetByServiceIp(ctx, serviceIp)
 }
 
-func (s *interestService) Update(ctx context.Context, interest *domain.Interest) (*domain.Interest, error) {
-	s.logger.Debug("Updating interest", zap.Any("interest", interest))
-	updatedInterest, err := s.repo.Update(ctx, interest)
+func (s *interestService) Update(ctx context.Context, interest *domain.Interest, expectedVersion int64) (*domain.Interest, error) {
+	s.logger.Debug("Updating interest", zap.Any("interest", interest), zap.Int64("expectedVersion", expectedVersion))
+	interest.Namespace = domain.NamespaceFromContext(ctx)
+	if err := s.resolveServiceIps(ctx, interest); err != nil {
+		return nil, err
+	}
+	updatedInterest, err := s.repo.Update(ctx, interest, expectedVersion)
 	if err != nil {
 		return nil, err
 	}
+
+	// Notify observers about the updated interest
+	if s.subject != nil {
+		s.subject.Notify(domain.InterestEvent{
+			Type:     domain.InterestUpdated,
+			Interest: updatedInterest,
+		})
+	}
+
 	return updatedInterest, nil
 }
 
-func (s *interestService) DeleteByAppName(ctx context.Context, appName string) error {
-	s.logger.Debug("Deleting interest by app name", zap.String("appName", appName))
+func (s *interestService) DeleteByAppName(ctx context.Context, appName string, expectedVersion int64) error {
+	s.logger.Debug("Deleting interest by app name", zap.String("appName", appName), zap.Int64("expectedVersion", expectedVersion))
+	if err := s.repo.DeleteByAppName(ctx, appName, expectedVersion); err != nil {
+		return err
+	}
+
 	// Notify observers about the deleted interest
 	if s.subject != nil {
 		s.subject.Notify(domain.InterestEvent{
 			Type: domain.InterestDeleted,
 			Interest: &domain.Interest{
-				AppName: appName,
+				Namespace: domain.NamespaceFromContext(ctx),
+				AppName:   appName,
 			},
 		})
 	}
 
-	return s.repo.DeleteByAppName(ctx, appName)
+	return nil
 }
 
-func (s *interestService) DeleteByServiceIp(ctx context.Context, serviceIp string) error {
-	s.logger.Debug("Deleting interest by service IP", zap.String("serviceIp", serviceIp))
+func (s *interestService) DeleteByServiceIp(ctx context.Context, serviceIp string, expectedVersion int64) error {
+	s.logger.Debug("Deleting interest by service IP", zap.String("serviceIp", serviceIp), zap.Int64("expectedVersion", expectedVersion))
+	serviceIp, err := canonicalServiceIp(serviceIp)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the interest first, the observers are keyed by app name
+	interest, err := s.repo.GetByServiceIp(ctx, serviceIp)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteByServiceIp(ctx, serviceIp, expectedVersion); err != nil {
+		return err
+	}
+
 	// Notify observers about the deleted interest
 	if s.subject != nil {
 		s.subject.Notify(domain.InterestEvent{
-			Type: domain.InterestDeleted,
-			Interest: &domain.Interest{
-				ServiceIp: serviceIp,
-			},
+			Type:     domain.InterestDeleted,
+			Interest: interest,
 		})
 	}
-	return s.repo.DeleteByServiceIp(ctx, serviceIp)
+
+	return nil
 }
 
 func (s *interestService) List(ctx context.Context) ([]*domain.Interest, error) {
 	s.logger.Debug("Listing interests")
 	return s.repo.List(ctx)
 }
+
+// lockCreates holds off other creates of the namespace until the returned function is called
+func (s *interestService) lockCreates(namespace string) func() {
+	s.locksMutex.Lock()
+	lock, ok := s.createLocks[namespace]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.createLocks[namespace] = lock
+	}
+	s.locksMutex.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// checkInterestQuota verifies that the namespace may hold another interest, the creates of the namespace must be locked
+func (s *interestService) checkInterestQuota(ctx context.Context, namespace string) error {
+	quota := s.quotas.For(namespace)
+	if quota.MaxInterests <= 0 {
+		return nil
+	}
+
+	interests, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(interests) >= quota.MaxInterests {
+		s.logger.Warn("Interest quota exceeded",
+			zap.String("namespace", namespace),
+			zap.Int("maxInterests", quota.MaxInterests))
+		return domain.ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// resolveServiceIps canonicalizes the service IP of the interest and completes its address families from the job.
+// Interests may be registered before their job is deployed, a missing job leaves the other family empty.
+func (s *interestService) resolveServiceIps(ctx context.Context, interest *domain.Interest) error {
+	serviceIp, err := canonicalServiceIp(interest.ServiceIp)
+	if err != nil {
+		return err
+	}
+	interest.ServiceIp = serviceIp
+
+	var job *domain.Job
+	if s.jobRepo != nil {
+		if job, err = s.jobRepo.GetByJobName(ctx, interest.AppName); err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+	}
+	interest.ResolveServiceIps(job)
+	return nil
+}
+
+// canonicalServiceIp returns the canonical form of a service IP taken from a request
+func canonicalServiceIp(serviceIp string) (string, error) {
+	canonical, err := domain.CanonicalServiceIp(serviceIp)
+	if err != nil {
+		return "", domain.NewValidationError("serviceIp", err.Error())
+	}
+	return canonical, nil
+}
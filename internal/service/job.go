@@ -8,8 +8,15 @@ import (
 	"go.uber.org/zap"
 )
 
+// jobEventBuffer is the number of job events buffered for slow watchers
+const jobEventBuffer = 16
+
 type JobService interface {
 	GetByJobName(ctx context.Context, jobName string) (*domain.Job, error)
+	List(ctx context.Context, filter domain.JobFilter) ([]*domain.Job, error)
+	// Watch streams the changes of all jobs until the context is done, the channel is closed afterwards.
+	// Jobs existing when the watch starts are not reported.
+	Watch(ctx context.Context) <-chan domain.JobEvent
 }
 
 type jobService struct {
@@ -28,3 +35,32 @@ func (s *jobService) GetByJobName(ctx context.Context, jobName string) (*domain.
 	s.logger.Info("Getting job by job name", zap.String("jobName", jobName))
 	return s.repo.GetByJobName(ctx, jobName)
 }
+
+func (s *jobService) List(ctx context.Context, filter domain.JobFilter) ([]*domain.Job, error) {
+	s.logger.Info("Listing jobs", zap.Any("filter", filter))
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, filter)
+}
+
+func (s *jobService) Watch(ctx context.Context) <-chan domain.JobEvent {
+	s.logger.Info("Watching jobs")
+
+	events := make(chan domain.JobEvent, jobEventBuffer)
+	go func() {
+		defer close(events)
+
+		err := s.repo.Watch(ctx, func(event domain.JobEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			s.logger.Error("Job watch stopped", zap.Error(err))
+		}
+	}()
+
+	return events
+}
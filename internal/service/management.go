@@ -42,8 +42,8 @@ func (s *Services) Restart(ctx context.Context, logger *zap.Logger) {
 			return
 		}
 
-		// Fetch all interests from the database
-		interests, err := s.InterestService.List(ctx)
+		// Fetch all interests of all namespaces from the database
+		interests, err := s.InterestService.List(domain.WithNamespace(ctx, domain.AllNamespaces))
 		if err != nil {
 			logger.Error("Failed to retrieve interests during restart", zap.Error(err))
 			return
@@ -66,6 +66,7 @@ func (s *Services) Restart(ctx context.Context, logger *zap.Logger) {
 			s.InterestSubject.Notify(event)
 
 			logger.Debug("Reinitialized interest",
+				zap.String("namespace", interest.Namespace),
 				zap.String("appName", interest.AppName),
 				zap.String("serviceIp", interest.ServiceIp))
 		}
@@ -73,3 +74,81 @@ func (s *Services) Restart(ctx context.Context, logger *zap.Logger) {
 		logger.Info("Service restart procedure completed successfully", zap.Int("interestsReinitialized", len(interests)))
 	})
 }
+
+// WatchInterests forwards interest changes made outside of the routing-manager to the interest subject.
+// It is a no-op if the interest repository cannot change externally. The watcher stops with the context.
+func (s *Services) WatchInterests(ctx context.Context, logger *zap.Logger) {
+	if s.interestWatcher == nil {
+		return
+	}
+
+	go func() {
+		logger.Info("Watching interest repository for external changes")
+		if err := s.interestWatcher.Watch(ctx, s.InterestSubject.Notify); err != nil {
+			logger.Error("Interest watcher stopped", zap.Error(err))
+		}
+	}()
+}
+
+// RunResolver keeps the address resolver index fresh until the context is done
+func (s *Services) RunResolver(ctx context.Context, logger *zap.Logger) {
+	logger.Info("Starting address resolver")
+	s.ResolverService.Run(ctx)
+}
+
+// RunReconciler periodically reconciles interests, task schedulers and jobs until the context is done.
+// It requires the task scheduler observer to be set.
+func (s *Services) RunReconciler(ctx context.Context, logger *zap.Logger) {
+	if s.TaskSchedulerObserver == nil {
+		logger.Error("Cannot run reconciler: task scheduler observer is missing")
+		return
+	}
+
+	logger.Info("Starting reconciler")
+	s.ReconcilerService.Run(ctx, s.TaskSchedulerObserver)
+}
+
+// RunAlertRules periodically evaluates the alert rules against the stored metrics until the context is done
+func (s *Services) RunAlertRules(ctx context.Context, logger *zap.Logger) {
+	logger.Info("Starting alert rule evaluation")
+	s.AlertRuleService.Run(ctx)
+}
+
+// RunMetricSnapshots periodically writes snapshots of the metrics kept in memory until the context is done.
+// It does nothing unless the metrics backend supports snapshots and a snapshot path is configured.
+func (s *Services) RunMetricSnapshots(ctx context.Context, logger *zap.Logger) {
+	s.MetricSnapshotService.Run(ctx)
+}
+
+// SnapshotMetrics writes a final snapshot of the metrics kept in memory on shutdown, if snapshots are enabled
+func (s *Services) SnapshotMetrics(ctx context.Context, logger *zap.Logger) {
+	snapshot, err := s.MetricSnapshotService.Snapshot(ctx)
+	if err != nil {
+		// Snapshots are disabled, or the failure was logged by the snapshot service
+		return
+	}
+	logger.Info("Wrote metrics snapshot", zap.String("path", snapshot.Path), zap.Int("series", snapshot.Series))
+}
+
+// overrideExpiryInterval is how often expired routing overrides are removed
+const overrideExpiryInterval = 10 * time.Second
+
+// ExpireOverrides periodically removes expired routing overrides and restores the priorities they replaced.
+// It stops with the context.
+func (s *Services) ExpireOverrides(ctx context.Context, logger *zap.Logger) {
+	go func() {
+		ticker := time.NewTicker(overrideExpiryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.RoutingService.ExpireOverrides(ctx); err != nil {
+					logger.Error("Failed to expire routing overrides", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+	"go.uber.org/zap"
+)
+
+type MetricService interface {
+	// Ingest stores a batch of samples
+	Ingest(ctx context.Context, samples domain.MetricSamples) error
+	// Latest returns the latest value of every selected series
+	Latest(ctx context.Context, selector *domain.MetricSelector) ([]domain.MetricValue, error)
+	// History returns the recorded values of every selected series, newest first
+	History(ctx context.Context, selector *domain.MetricSelector) ([]domain.MetricHistory, error)
+	// Get returns the latest value of the queried series
+	Get(ctx context.Context, query *domain.MetricQuery) (*domain.MetricValue, error)
+	// Aggregate aggregates the values of the selected series within the window
+	Aggregate(ctx context.Context, aggregation *domain.MetricAggregation) ([]domain.MetricAggregate, error)
+	// GetHistory returns the recorded values of the queried series, newest first
+	GetHistory(ctx context.Context, query *domain.MetricQuery) (*domain.MetricHistory, error)
+}
+
+type metricService struct {
+	store  storage.MetricStore
+	logger *zap.Logger
+}
+
+func NewMetricService(store storage.MetricStore, logger *zap.Logger) MetricService {
+	return &metricService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (s *metricService) Ingest(ctx context.Context, samples domain.MetricSamples) error {
+	batch := make([]storage.Sample, 0, len(samples))
+	for _, sample := range samples {
+		stored := storage.Sample{
+			Metric: metricName(sample.Metric),
+			Labels: sampleLabels(sample.AppName, sample.InstanceNumber),
+			Value:  sample.Value,
+		}
+		for name, value := range sample.Labels {
+			stored.Labels[name] = value
+		}
+		if sample.Timestamp != nil {
+			stored.Timestamp = *sample.Timestamp
+		}
+		batch = append(batch, stored)
+	}
+
+	if err := s.store.Append(ctx, batch); err != nil {
+		s.logger.Error("Failed to store metric samples", zap.Int("count", len(samples)), zap.Error(err))
+		return err
+	}
+
+	s.logger.Debug("Ingested metric samples", zap.Int("count", len(samples)))
+	return nil
+}
+
+func (s *metricService) Latest(ctx context.Context, selector *domain.MetricSelector) ([]domain.MetricValue, error) {
+	storeSelector, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.store.Latest(ctx, storeSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]domain.MetricValue, 0, len(latest))
+	for _, series := range latest {
+		if value := metricValueOf(series); value != nil {
+			values = append(values, *value)
+		}
+	}
+	return values, nil
+}
+
+func (s *metricService) History(ctx context.Context, selector *domain.MetricSelector) ([]domain.MetricHistory, error) {
+	storeSelector, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Time{}
+	if selector.Since != nil {
+		since = *selector.Since
+	}
+
+	history, err := s.store.History(ctx, storeSelector, since, selector.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	histories := make([]domain.MetricHistory, 0, len(history))
+	for _, series := range history {
+		if series.Labels[storage.LabelApp] != "" {
+			histories = append(histories, metricHistoryOf(series))
+		}
+	}
+	return histories, nil
+}
+
+func (s *metricService) Aggregate(ctx context.Context, aggregation *domain.MetricAggregation) ([]domain.MetricAggregate, error) {
+	selector, err := parseSelector(&aggregation.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.store.Aggregate(ctx, storage.AggregateQuery{
+		Selector: selector,
+		Func:     storage.AggregateFunc(aggregation.Func),
+		Since:    time.Now().Add(-aggregation.Window),
+		GroupBy:  aggregation.GroupBy,
+		Alpha:    aggregation.Alpha,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates := make([]domain.MetricAggregate, 0, len(results))
+	for _, result := range results {
+		aggregates = append(aggregates, domain.MetricAggregate{
+			Metric:  result.Metric,
+			Labels:  result.Labels,
+			Func:    aggregation.Func,
+			Value:   result.Value,
+			Samples: result.Samples,
+		})
+	}
+	return aggregates, nil
+}
+
+func (s *metricService) Get(ctx context.Context, query *domain.MetricQuery) (*domain.MetricValue, error) {
+	metric, labels := metricName(query.Metric), sampleLabels(query.AppName, query.InstanceNumber)
+
+	latest, err := s.store.Latest(ctx, storage.ExactSelector(metric, labels))
+	if err != nil {
+		return nil, err
+	}
+
+	series := storage.FindSeries(latest, labels)
+	if series == nil {
+		return nil, domain.ErrNotFound
+	}
+	return metricValueOf(*series), nil
+}
+
+func (s *metricService) GetHistory(ctx context.Context, query *domain.MetricQuery) (*domain.MetricHistory, error) {
+	metric, labels := metricName(query.Metric), sampleLabels(query.AppName, query.InstanceNumber)
+
+	// An unknown series is an error, a known one without points in the range is not
+	if _, err := s.Get(ctx, query); err != nil {
+		return nil, err
+	}
+
+	since := time.Time{}
+	if query.Since != nil {
+		since = *query.Since
+	}
+
+	history, err := s.store.History(ctx, storage.ExactSelector(metric, labels), since, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	series := storage.FindSeries(history, labels)
+	if series == nil {
+		series = &storage.Series{Metric: metric, Labels: labels}
+	}
+	result := metricHistoryOf(*series)
+	return &result, nil
+}
+
+// parseSelector translates the selector into a storage selector, invalid matchers are validation errors
+func parseSelector(selector *domain.MetricSelector) (storage.Selector, error) {
+	storeSelector := storage.Selector{Metric: selector.Metric}
+	if selector.AppName != "" {
+		storeSelector.Matchers = append(storeSelector.Matchers, &storage.Matcher{
+			Name:  storage.LabelApp,
+			Type:  storage.MatchEqual,
+			Value: selector.AppName,
+		})
+	}
+
+	verr := &domain.ValidationError{}
+	for _, m := range selector.Matchers {
+		matcher, err := storage.ParseMatcher(m)
+		if err != nil {
+			verr.Add("match", err.Error())
+			continue
+		}
+		storeSelector.Matchers = append(storeSelector.Matchers, matcher)
+	}
+	return storeSelector, verr.ErrOrNil()
+}
+
+// metricName defaults the metric of samples and queries to the performance metric
+func metricName(metric string) string {
+	if metric == "" {
+		return storage.DefaultMetric
+	}
+	return metric
+}
+
+// sampleLabels returns the labels identifying an app or one of its instances
+func sampleLabels(appName string, instance *int) storage.Labels {
+	labels := storage.Labels{storage.LabelApp: appName}
+	if instance != nil {
+		labels[storage.LabelInstance] = strconv.Itoa(*instance)
+	}
+	return labels
+}
+
+// extraLabels returns the labels of a series besides the app and the instance, nil if there are none
+func extraLabels(labels storage.Labels) map[string]string {
+	var extra map[string]string
+	for name, value := range labels {
+		if name == storage.LabelApp || name == storage.LabelInstance || value == "" {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]string)
+		}
+		extra[name] = value
+	}
+	return extra
+}
+
+// metricValueOf returns the latest value of a series, nil if the series is not labeled with an app or has no points
+func metricValueOf(series storage.Series) *domain.MetricValue {
+	if series.Labels[storage.LabelApp] == "" || len(series.Points) == 0 {
+		return nil
+	}
+	return &domain.MetricValue{
+		AppName:        series.Labels[storage.LabelApp],
+		InstanceNumber: series.Labels.Instance(),
+		Metric:         series.Metric,
+		Labels:         extraLabels(series.Labels),
+		Value:          series.Points[0].Value,
+		RecordedAt:     series.Points[0].Timestamp,
+	}
+}
+
+// metricHistoryOf returns the recorded values of a series
+func metricHistoryOf(series storage.Series) domain.MetricHistory {
+	history := domain.MetricHistory{
+		AppName:        series.Labels[storage.LabelApp],
+		InstanceNumber: series.Labels.Instance(),
+		Metric:         series.Metric,
+		Labels:         extraLabels(series.Labels),
+		Points:         make([]domain.MetricPoint, 0, len(series.Points)),
+	}
+	for _, point := range series.Points {
+		history.Points = append(history.Points, domain.MetricPoint{
+			Value:      point.Value,
+			RecordedAt: point.Timestamp,
+			Min:        point.Min,
+			Max:        point.Max,
+			Count:      point.Count,
+		})
+	}
+	return history
+}
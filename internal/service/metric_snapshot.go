@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+	"go.uber.org/zap"
+)
+
+var (
+	errSnapshotsUnsupported = domain.NewError(domain.CodeSnapshotUnavailable, "the metrics backend does not support snapshots")
+	errSnapshotPathMissing  = domain.NewError(domain.CodeSnapshotUnavailable, "no metrics snapshot path is configured")
+)
+
+// MetricSnapshotConfig configures the snapshots of a performance store keeping its metrics in memory
+type MetricSnapshotConfig struct {
+	// Store is nil if the metrics backend does not support snapshots
+	Store storage.Snapshotter
+	// Path is the snapshot file, periodic snapshots and restoring from the file are disabled if it is empty
+	Path string
+	// Interval between periodic snapshots
+	Interval time.Duration
+}
+
+type MetricSnapshotService interface {
+	// Snapshot writes the metrics to the snapshot file
+	Snapshot(ctx context.Context) (*domain.MetricSnapshot, error)
+	// Restore replaces the metrics with those of the snapshot file
+	Restore(ctx context.Context) (*domain.MetricSnapshot, error)
+	// Import replaces the metrics with those of an exported snapshot
+	Import(ctx context.Context, r io.Reader) (*domain.MetricSnapshot, error)
+	// Export writes a snapshot of the metrics to the writer
+	Export(ctx context.Context, w io.Writer) error
+	// Run periodically writes snapshots until the context is done
+	Run(ctx context.Context)
+}
+
+type metricSnapshotService struct {
+	store  storage.MetricStore
+	config MetricSnapshotConfig
+	logger *zap.Logger
+}
+
+func NewMetricSnapshotService(store storage.MetricStore, config MetricSnapshotConfig, logger *zap.Logger) MetricSnapshotService {
+	return &metricSnapshotService{
+		store:  store,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (s *metricSnapshotService) Snapshot(ctx context.Context) (*domain.MetricSnapshot, error) {
+	if err := s.fileSnapshots(); err != nil {
+		return nil, err
+	}
+
+	if err := storage.SaveSnapshot(s.config.Store, s.config.Path); err != nil {
+		s.logger.Error("Failed to write metrics snapshot", zap.String("path", s.config.Path), zap.Error(err))
+		return nil, err
+	}
+
+	return s.describe(ctx)
+}
+
+func (s *metricSnapshotService) Restore(ctx context.Context) (*domain.MetricSnapshot, error) {
+	if err := s.fileSnapshots(); err != nil {
+		return nil, err
+	}
+
+	if err := storage.LoadSnapshot(s.config.Store, s.config.Path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, domain.ErrNotFound
+		}
+		s.logger.Error("Failed to restore metrics snapshot", zap.String("path", s.config.Path), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Restored metrics snapshot", zap.String("path", s.config.Path))
+	return s.describe(ctx)
+}
+
+func (s *metricSnapshotService) Import(ctx context.Context, r io.Reader) (*domain.MetricSnapshot, error) {
+	if s.config.Store == nil {
+		return nil, errSnapshotsUnsupported
+	}
+
+	if err := s.config.Store.ReadSnapshot(r); err != nil {
+		return nil, domain.NewValidationError("snapshot", err.Error())
+	}
+
+	s.logger.Info("Imported metrics snapshot")
+	return s.describe(ctx)
+}
+
+func (s *metricSnapshotService) Export(ctx context.Context, w io.Writer) error {
+	if s.config.Store == nil {
+		return errSnapshotsUnsupported
+	}
+	return s.config.Store.WriteSnapshot(w)
+}
+
+func (s *metricSnapshotService) Run(ctx context.Context) {
+	if s.config.Store == nil || s.config.Path == "" {
+		return
+	}
+
+	s.logger.Info("Starting metric snapshots", zap.String("path", s.config.Path), zap.Duration("interval", s.config.Interval))
+	go func() {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Failures are logged by Snapshot, the next tick retries
+				_, _ = s.Snapshot(ctx)
+			}
+		}
+	}()
+}
+
+// fileSnapshots checks that snapshots can be written to and restored from the snapshot file
+func (s *metricSnapshotService) fileSnapshots() error {
+	if s.config.Store == nil {
+		return errSnapshotsUnsupported
+	}
+	if s.config.Path == "" {
+		return errSnapshotPathMissing
+	}
+	return nil
+}
+
+// describe returns the snapshot file and the number of series in the store
+func (s *metricSnapshotService) describe(ctx context.Context) (*domain.MetricSnapshot, error) {
+	latest, err := s.store.Latest(ctx, storage.Selector{})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &domain.MetricSnapshot{
+		Path:   s.config.Path,
+		Series: len(latest),
+	}
+	if s.config.Path != "" {
+		if info, err := os.Stat(s.config.Path); err == nil {
+			snapshot.Size = info.Size()
+			modifiedAt := info.ModTime()
+			snapshot.ModifiedAt = &modifiedAt
+		}
+	}
+	return snapshot, nil
+}
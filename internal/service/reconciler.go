@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"go.uber.org/zap"
+)
+
+// TaskScheduler runs the scheduled tasks of interests, identified by the interest key
+type TaskScheduler interface {
+	// ScheduledKeys lists the keys of the interests with a running scheduler
+	ScheduledKeys() []string
+	// StopScheduler stops the scheduler of an interest without deleting the interest
+	StopScheduler(key string)
+}
+
+// ReconcilerConfig holds the tunables of the reconciler
+type ReconcilerConfig struct {
+	// Interval between reconciliation runs
+	Interval time.Duration
+	// OrphanAction is applied to interests whose job is gone
+	OrphanAction domain.OrphanAction
+	// OrphanGrace is how long an interest must be without its job before the orphan action is applied
+	OrphanGrace time.Duration
+	// SchedulerAction is applied to schedulers that are out of sync with the interests
+	SchedulerAction domain.SchedulerAction
+}
+
+// Validate checks the reconciler configuration
+func (c ReconcilerConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("reconciliation interval must be positive")
+	}
+	if c.OrphanGrace < 0 {
+		return fmt.Errorf("orphan grace period must not be negative")
+	}
+	switch c.OrphanAction {
+	case domain.OrphanReport, domain.OrphanSuspend, domain.OrphanDelete:
+	default:
+		return fmt.Errorf("invalid orphan action: %s", c.OrphanAction)
+	}
+	switch c.SchedulerAction {
+	case domain.SchedulerReport, domain.SchedulerRepair:
+	default:
+		return fmt.Errorf("invalid scheduler action: %s", c.SchedulerAction)
+	}
+	return nil
+}
+
+type ReconcilerService interface {
+	// Report returns the result of the latest reconciliation run
+	Report(ctx context.Context) (*domain.ReconciliationReport, error)
+	// Run periodically reconciles interests, schedulers and jobs until the context is done
+	Run(ctx context.Context, scheduler TaskScheduler)
+}
+
+type reconcilerService struct {
+	interestService InterestService
+	jobRepo         repository.JobRepository
+	subject         domain.Subject
+	config          ReconcilerConfig
+	logger          *zap.Logger
+
+	mutex  sync.Mutex
+	report *domain.ReconciliationReport
+	// since holds when each drift was first observed, keyed by kind and interest key
+	since map[string]time.Time
+	// suspended holds the keys of interests whose scheduler was stopped because their job is gone
+	suspended map[string]bool
+}
+
+func NewReconcilerService(interestService InterestService, jobRepo repository.JobRepository, subject domain.Subject, config ReconcilerConfig, logger *zap.Logger) ReconcilerService {
+	return &reconcilerService{
+		interestService: interestService,
+		jobRepo:         jobRepo,
+		subject:         subject,
+		config:          config,
+		logger:          logger,
+		since:           make(map[string]time.Time),
+		suspended:       make(map[string]bool),
+	}
+}
+
+func (s *reconcilerService) Report(ctx context.Context) (*domain.ReconciliationReport, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.report == nil {
+		return nil, domain.ErrNotFound
+	}
+	return s.report, nil
+}
+
+func (s *reconcilerService) Run(ctx context.Context, scheduler TaskScheduler) {
+	go func() {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.reconcile(ctx, scheduler); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to reconcile interests", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// reconcile compares interests, schedulers and jobs, remediates the drift as configured and stores the report
+func (s *reconcilerService) reconcile(ctx context.Context, scheduler TaskScheduler) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := &domain.ReconciliationReport{
+		StartedAt: time.Now(),
+		Drift:     []domain.Drift{},
+	}
+
+	// Schedulers are listed first, so that interests created in the meantime are not taken for stale schedulers
+	scheduled := make(map[string]bool)
+	for _, key := range scheduler.ScheduledKeys() {
+		scheduled[key] = true
+	}
+
+	interests, err := s.interestService.List(domain.WithNamespace(ctx, domain.AllNamespaces))
+	if err != nil {
+		return err
+	}
+
+	jobs, err := s.jobRepo.List(ctx, domain.JobFilter{})
+	if err != nil {
+		return err
+	}
+	deployed := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		deployed[job.JobName] = true
+	}
+
+	report.Interests = len(interests)
+	report.Jobs = len(jobs)
+	report.Schedulers = len(scheduled)
+
+	since := make(map[string]time.Time, len(s.since))
+	observe := func(kind domain.DriftKind, namespace, appName string) domain.Drift {
+		driftKey := string(kind) + ":" + namespace + "/" + appName
+		first, ok := s.since[driftKey]
+		if !ok {
+			first = report.StartedAt
+		}
+		since[driftKey] = first
+		return domain.Drift{
+			Kind:        kind,
+			Namespace:   namespace,
+			AppName:     appName,
+			Since:       first,
+			Remediation: domain.RemediationNone,
+		}
+	}
+
+	known := make(map[string]bool, len(interests))
+	for _, interest := range interests {
+		key := interest.Key()
+		known[key] = true
+
+		if !deployed[interest.AppName] {
+			drift := observe(domain.DriftInterestWithoutJob, interest.Namespace, interest.AppName)
+			if report.StartedAt.Sub(drift.Since) >= s.config.OrphanGrace {
+				s.remediateOrphan(ctx, interest, scheduler, scheduled[key], &drift)
+			}
+			report.Drift = append(report.Drift, drift)
+			continue
+		}
+
+		if scheduled[key] {
+			continue
+		}
+
+		drift := observe(domain.DriftInterestWithoutScheduler, interest.Namespace, interest.AppName)
+		switch {
+		case s.suspended[key]:
+			// The job of a suspended interest was deployed again
+			delete(s.suspended, key)
+			s.startScheduler(interest)
+			drift.Remediation = domain.RemediationResumed
+		case s.config.SchedulerAction == domain.SchedulerRepair:
+			s.startScheduler(interest)
+			drift.Remediation = domain.RemediationRestarted
+		}
+		report.Drift = append(report.Drift, drift)
+	}
+
+	for key := range scheduled {
+		if known[key] {
+			continue
+		}
+
+		namespace, appName := splitInterestKey(key)
+		drift := observe(domain.DriftSchedulerWithoutInterest, namespace, appName)
+		if s.config.SchedulerAction == domain.SchedulerRepair {
+			scheduler.StopScheduler(key)
+			drift.Remediation = domain.RemediationStopped
+		}
+		report.Drift = append(report.Drift, drift)
+	}
+
+	// Forget suspensions of interests that were deleted in the meantime
+	for key := range s.suspended {
+		if !known[key] {
+			delete(s.suspended, key)
+		}
+	}
+	report.Suspended = make([]string, 0, len(s.suspended))
+	for key := range s.suspended {
+		report.Suspended = append(report.Suspended, key)
+	}
+	sort.Strings(report.Suspended)
+
+	sort.Slice(report.Drift, func(i, j int) bool {
+		if report.Drift[i].Namespace != report.Drift[j].Namespace {
+			return report.Drift[i].Namespace < report.Drift[j].Namespace
+		}
+		if report.Drift[i].AppName != report.Drift[j].AppName {
+			return report.Drift[i].AppName < report.Drift[j].AppName
+		}
+		return report.Drift[i].Kind < report.Drift[j].Kind
+	})
+
+	report.FinishedAt = time.Now()
+	s.since = since
+	s.report = report
+
+	if len(report.Drift) > 0 {
+		s.logger.Info("Reconciled interests, schedulers and jobs",
+			zap.Int("drift", len(report.Drift)),
+			zap.Int("suspended", len(report.Suspended)))
+	}
+	return nil
+}
+
+// remediateOrphan applies the orphan action to an interest whose job is gone
+func (s *reconcilerService) remediateOrphan(ctx context.Context, interest *domain.Interest, scheduler TaskScheduler, scheduled bool, drift *domain.Drift) {
+	key := interest.Key()
+
+	switch s.config.OrphanAction {
+	case domain.OrphanSuspend:
+		if scheduled {
+			scheduler.StopScheduler(key)
+		}
+		s.suspended[key] = true
+		drift.Remediation = domain.RemediationSuspended
+
+	case domain.OrphanDelete:
+		// Deleting with the listed version keeps interests that were updated in the meantime
+		err := s.interestService.DeleteByAppName(domain.WithNamespace(ctx, interest.Namespace), interest.AppName, interest.Version)
+		if err != nil {
+			s.logger.Error("Failed to delete orphaned interest",
+				zap.String("namespace", interest.Namespace),
+				zap.String("appName", interest.AppName),
+				zap.Error(err))
+			drift.Error = err.Error()
+			return
+		}
+		delete(s.suspended, key)
+		drift.Remediation = domain.RemediationDeleted
+	}
+}
+
+// startScheduler (re)starts the scheduler of an interest through the interest observers
+func (s *reconcilerService) startScheduler(interest *domain.Interest) {
+	s.subject.Notify(domain.InterestEvent{
+		Type:     domain.InterestCreated,
+		Interest: interest,
+	})
+}
+
+// splitInterestKey splits an interest key into namespace and app name
+func splitInterestKey(key string) (string, string) {
+	namespace, appName, found := strings.Cut(key, "/")
+	if !found {
+		return "", key
+	}
+	return namespace, appName
+}
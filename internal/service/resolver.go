@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"go.uber.org/zap"
+)
+
+// resolverResyncInterval is how often the resolver index is rebuilt from scratch,
+// in case job changes were missed by the watch
+const resolverResyncInterval = time.Minute
+
+type ResolverService interface {
+	// Resolve returns the job owning a service or instance address of either family
+	Resolve(ctx context.Context, address string) (*domain.Resolution, error)
+	// Run keeps the index fresh from the jobs collection until the context is done
+	Run(ctx context.Context)
+}
+
+type resolverService struct {
+	jobRepo repository.JobRepository
+	logger  *zap.Logger
+
+	mutex sync.RWMutex
+	// index maps canonical addresses to their resolution
+	index map[string]domain.Resolution
+	// addresses holds the indexed addresses of each job, so that they can be dropped on changes
+	addresses map[string][]string
+	ready     bool
+
+	// rebuilding counts the rebuilds in progress. The job events applied meanwhile are kept in pending
+	// and replayed onto the rebuilt index, whose jobs may have been listed before the events happened.
+	rebuilding int
+	pending    []domain.JobEvent
+}
+
+func NewResolverService(jobRepo repository.JobRepository, logger *zap.Logger) ResolverService {
+	return &resolverService{
+		jobRepo:   jobRepo,
+		logger:    logger,
+		index:     make(map[string]domain.Resolution),
+		addresses: make(map[string][]string),
+	}
+}
+
+func (s *resolverService) Resolve(ctx context.Context, address string) (*domain.Resolution, error) {
+	s.logger.Debug("Resolving address", zap.String("address", address))
+	canonical, err := domain.CanonicalServiceIp(address)
+	if err != nil {
+		return nil, domain.NewValidationError("ip", err.Error())
+	}
+
+	s.mutex.RLock()
+	ready := s.ready
+	s.mutex.RUnlock()
+
+	// Resolve synchronously until the index was built for the first time
+	if !ready {
+		if err := s.rebuild(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	resolution, ok := s.index[canonical]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &resolution, nil
+}
+
+func (s *resolverService) Run(ctx context.Context) {
+	go func() {
+		if err := s.jobRepo.Watch(ctx, s.apply); err != nil {
+			s.logger.Error("Resolver job watch stopped", zap.Error(err))
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(resolverResyncInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.rebuild(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to rebuild resolver index", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// rebuild replaces the index with the addresses of all current jobs
+func (s *resolverService) rebuild(ctx context.Context) error {
+	s.mutex.Lock()
+	s.rebuilding++
+	replayFrom := len(s.pending)
+	s.mutex.Unlock()
+
+	jobs, err := s.jobRepo.List(ctx, domain.JobFilter{})
+	if err != nil {
+		s.mutex.Lock()
+		s.finishRebuild()
+		s.mutex.Unlock()
+		return err
+	}
+
+	index := make(map[string]domain.Resolution)
+	addresses := make(map[string][]string, len(jobs))
+	for _, job := range jobs {
+		for _, resolution := range job.Resolutions() {
+			index[resolution.Address] = resolution
+			addresses[job.JobName] = append(addresses[job.JobName], resolution.Address)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.index = index
+	s.addresses = addresses
+	for _, event := range s.pending[replayFrom:] {
+		s.applyLocked(event)
+	}
+	s.finishRebuild()
+	s.ready = true
+
+	s.logger.Debug("Rebuilt resolver index", zap.Int("jobs", len(jobs)), zap.Int("addresses", len(index)))
+	return nil
+}
+
+// finishRebuild ends a rebuild, the pending events are dropped once no rebuild is left. The mutex must be held.
+func (s *resolverService) finishRebuild() {
+	s.rebuilding--
+	if s.rebuilding == 0 {
+		s.pending = nil
+	}
+}
+
+// apply updates the index with a single job change
+func (s *resolverService) apply(event domain.JobEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.applyLocked(event)
+	if s.rebuilding > 0 {
+		s.pending = append(s.pending, event)
+	}
+}
+
+// applyLocked updates the index with a single job change. The mutex must be held.
+func (s *resolverService) applyLocked(event domain.JobEvent) {
+	for _, address := range s.addresses[event.Job.JobName] {
+		// Another job may have taken over the address in the meantime
+		if s.index[address].JobName == event.Job.JobName {
+			delete(s.index, address)
+		}
+	}
+	delete(s.addresses, event.Job.JobName)
+
+	if event.Type == domain.JobDeleted {
+		return
+	}
+	for _, resolution := range event.Job.Resolutions() {
+		s.index[resolution.Address] = resolution
+		s.addresses[event.Job.JobName] = append(s.addresses[event.Job.JobName], resolution.Address)
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"go.uber.org/zap"
+)
+
+// rolloutAbortObserver aborts the gradual rollouts of an app whenever an alert is raised for it
+type rolloutAbortObserver struct {
+	routingService RoutingService
+	logger         *zap.Logger
+}
+
+var _ domain.AlertObserver = &rolloutAbortObserver{}
+
+// OnAlertEvent aborts the rollouts of the alert's app
+func (o *rolloutAbortObserver) OnAlertEvent(event domain.AlertEvent) {
+	if event.Type != domain.AlertRaised {
+		return
+	}
+
+	ctx := domain.WithNamespace(context.Background(), event.Alert.Namespace)
+	if err := o.routingService.AbortRollout(ctx, event.Alert.AppName); err != nil {
+		o.logger.Error("Failed to abort rollout on alert",
+			zap.String("namespace", event.Alert.Namespace),
+			zap.String("appName", event.Alert.AppName),
+			zap.Error(err))
+	}
+}
+
+// GetID returns the ID of the observer
+func (o *rolloutAbortObserver) GetID() string {
+	return "RolloutAbortObserver"
+}
@@ -2,35 +2,366 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/normalization"
 	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"github.com/smnzlnsk/routing-manager/internal/rollout"
+	"github.com/smnzlnsk/routing-manager/internal/smoothing"
 	"go.uber.org/zap"
 )
 
 type RoutingService interface {
 	HandleRoutingChange(ctx context.Context, routingChange *domain.RoutingChange) error
 	GetRouting(ctx context.Context, appName string) (*domain.Job, error)
+
+	// ListHistory returns a page of the routing snapshots of an app, newest first
+	ListHistory(ctx context.Context, appName string, query domain.RoutingHistoryQuery) ([]*domain.RoutingSnapshot, error)
+	GetHistory(ctx context.Context, appName string, version int64) (*domain.RoutingSnapshot, error)
+	DiffHistory(ctx context.Context, appName string, fromVersion, toVersion int64) (*domain.RoutingDiff, error)
+	// Rollback re-applies the routing priorities of a previous snapshot and records the result as a new snapshot
+	Rollback(ctx context.Context, appName string, version int64) (*domain.RoutingSnapshot, error)
+	// GetSmoothingState returns the raw, smoothed and published priorities of an app per IpType
+	GetSmoothingState(ctx context.Context, appName string) ([]smoothing.SeriesState, error)
+
+	// GetRolloutState returns the progress of the gradual rollouts of an app per IpType
+	GetRolloutState(ctx context.Context, appName string) ([]rollout.SeriesState, error)
+	// AbortRollout restores the priorities in effect before the rollouts in progress of an app and holds off new ones
+	AbortRollout(ctx context.Context, appName string) error
+
+	// CreateOverride stores a manual override and applies it to the routing priorities of the app right away
+	CreateOverride(ctx context.Context, appName string, request *domain.OverrideRequest) (*domain.RoutingOverride, error)
+	// ListOverrides returns the active overrides of an app
+	ListOverrides(ctx context.Context, appName string) ([]*domain.RoutingOverride, error)
+	// DeleteOverride removes an override and restores the computed priorities it replaced
+	DeleteOverride(ctx context.Context, appName, id string) error
+	// ExpireOverrides removes the expired overrides of all namespaces and restores the computed priorities they replaced
+	ExpireOverrides(ctx context.Context) error
 }
 
 type routingService struct {
-	repo   repository.RoutingRepository
-	logger *zap.Logger
+	repo         repository.RoutingRepository
+	historyRepo  repository.RoutingHistoryRepository
+	overrideRepo repository.RoutingOverrideRepository
+	interestRepo repository.InterestRepository
+	subject      domain.RoutingSubject
+	normalizer   *normalization.Normalizer
+	smoother     *smoothing.Smoother
+	rollout      *rollout.Controller
+	logger       *zap.Logger
 }
 
-func NewRoutingService(repo repository.RoutingRepository, logger *zap.Logger) RoutingService {
+func NewRoutingService(
+	repo repository.RoutingRepository,
+	historyRepo repository.RoutingHistoryRepository,
+	overrideRepo repository.RoutingOverrideRepository,
+	interestRepo repository.InterestRepository,
+	subject domain.RoutingSubject,
+	normalizer *normalization.Normalizer,
+	smoother *smoothing.Smoother,
+	rollout *rollout.Controller,
+	logger *zap.Logger,
+) RoutingService {
 	return &routingService{
-		repo:   repo,
-		logger: logger,
+		repo:         repo,
+		historyRepo:  historyRepo,
+		overrideRepo: overrideRepo,
+		interestRepo: interestRepo,
+		subject:      subject,
+		normalizer:   normalizer,
+		smoother:     smoother,
+		rollout:      rollout,
+		logger:       logger,
 	}
 }
 
 func (s *routingService) HandleRoutingChange(ctx context.Context, routingChange *domain.RoutingChange) error {
+	routingChange.Namespace = domain.NamespaceFromContext(ctx)
 	s.logger.Info("Handling routing change", zap.Any("routingChange", routingChange))
-	return nil
+	if err := s.authorize(ctx, routingChange.AppName); err != nil {
+		return err
+	}
+
+	job, err := s.repo.GetRouting(ctx, routingChange.AppName)
+	if err != nil {
+		return err
+	}
+
+	// Bring the policy output onto a common scale before persisting it
+	if err := s.normalizer.NormalizeChange(routingChange); err != nil {
+		return err
+	}
+
+	// Dampen the policy output, changes that did not settle yet are held back.
+	// Published changes are then rolled out gradually, an ongoing rollout advances on every change.
+	published := s.smoother.Apply(routingChange)
+	if !s.rollout.Advance(routingChange, published, persistedPriorities(job, routingChange.IpType)) {
+		s.logger.Debug("Routing change held back",
+			zap.String("appName", routingChange.AppName),
+			zap.String("IpType", string(routingChange.IpType)),
+			zap.Bool("published", published))
+		return nil
+	}
+
+	if err := applyRoutingChange(job, routingChange); err != nil {
+		return err
+	}
+
+	// Manual overrides take precedence over the computed priorities
+	overrides, err := s.activeOverrides(ctx, routingChange.AppName)
+	if err != nil {
+		return err
+	}
+	job.ApplyOverrides(routingChange.IpType, overrides, time.Now())
+
+	if err := s.repo.UpdateRouting(ctx, job); err != nil {
+		return err
+	}
+
+	_, err = s.record(ctx, &domain.RoutingSnapshot{
+		Namespace: routingChange.Namespace,
+		AppName:   routingChange.AppName,
+		Source:    domain.RoutingSourceChange,
+		Change:    routingChange,
+		Instances: job.InstanceRoutings(),
+	}, domain.RoutingApplied)
+	return err
 }
 
 func (s *routingService) GetRouting(ctx context.Context, appName string) (*domain.Job, error) {
 	s.logger.Info("Getting routing", zap.String("appName", appName))
-	return nil, nil
+	if err := s.authorize(ctx, appName); err != nil {
+		return nil, err
+	}
+
+	job, err := s.repo.GetRouting(ctx, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Overrides, err = s.activeOverrides(ctx, appName); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *routingService) ListHistory(ctx context.Context, appName string, query domain.RoutingHistoryQuery) ([]*domain.RoutingSnapshot, error) {
+	s.logger.Debug("Listing routing history", zap.String("appName", appName), zap.Int64("before", query.Before))
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, appName); err != nil {
+		return nil, err
+	}
+	return s.historyRepo.List(ctx, appName, query)
+}
+
+func (s *routingService) GetHistory(ctx context.Context, appName string, version int64) (*domain.RoutingSnapshot, error) {
+	s.logger.Debug("Getting routing snapshot", zap.String("appName", appName), zap.Int64("version", version))
+	if err := s.authorize(ctx, appName); err != nil {
+		return nil, err
+	}
+	return s.historyRepo.Get(ctx, appName, version)
+}
+
+func (s *routingService) DiffHistory(ctx context.Context, appName string, fromVersion, toVersion int64) (*domain.RoutingDiff, error) {
+	s.logger.Debug("Diffing routing snapshots",
+		zap.String("appName", appName),
+		zap.Int64("fromVersion", fromVersion),
+		zap.Int64("toVersion", toVersion))
+
+	from, err := s.GetHistory(ctx, appName, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.historyRepo.Get(ctx, appName, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.DiffRoutingSnapshots(from, to), nil
+}
+
+func (s *routingService) Rollback(ctx context.Context, appName string, version int64) (*domain.RoutingSnapshot, error) {
+	s.logger.Info("Rolling back routing", zap.String("appName", appName), zap.Int64("version", version))
+
+	target, err := s.GetHistory(ctx, appName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.repo.GetRouting(ctx, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore the priorities of all instances that still exist,
+	// instances started after the snapshot keep their current priorities
+	restored := make(map[int][]domain.PriorityEntry, len(target.Instances))
+	for _, instance := range target.Instances {
+		restored[instance.InstanceNumber] = instance.Routing
+	}
+	for i := range job.ServiceInstanceList {
+		if routing, ok := restored[job.ServiceInstanceList[i].InstanceNumber]; ok {
+			job.ServiceInstanceList[i].RoutingPriority = routing
+		}
+	}
+
+	if err := s.repo.UpdateRouting(ctx, job); err != nil {
+		return nil, err
+	}
+	// The restored priorities replace whatever the smoothing published, start over from them
+	s.smoother.Forget(domain.NamespaceFromContext(ctx), appName)
+	s.rollout.Forget(domain.NamespaceFromContext(ctx), appName)
+
+	return s.record(ctx, &domain.RoutingSnapshot{
+		Namespace:    domain.NamespaceFromContext(ctx),
+		AppName:      appName,
+		Source:       domain.RoutingSourceRollback,
+		RolledBackTo: version,
+		Instances:    job.InstanceRoutings(),
+	}, domain.RoutingRolledBack)
+}
+
+func (s *routingService) GetSmoothingState(ctx context.Context, appName string) ([]smoothing.SeriesState, error) {
+	s.logger.Debug("Getting smoothing state", zap.String("appName", appName))
+	if err := s.authorize(ctx, appName); err != nil {
+		return nil, err
+	}
+	return s.smoother.State(domain.NamespaceFromContext(ctx), appName), nil
+}
+
+func (s *routingService) GetRolloutState(ctx context.Context, appName string) ([]rollout.SeriesState, error) {
+	s.logger.Debug("Getting rollout state", zap.String("appName", appName))
+	if err := s.authorize(ctx, appName); err != nil {
+		return nil, err
+	}
+	return s.rollout.State(domain.NamespaceFromContext(ctx), appName), nil
+}
+
+func (s *routingService) AbortRollout(ctx context.Context, appName string) error {
+	s.logger.Info("Aborting rollout", zap.String("appName", appName))
+	if err := s.authorize(ctx, appName); err != nil {
+		return err
+	}
+
+	reverted := s.rollout.Abort(domain.NamespaceFromContext(ctx), appName)
+	for _, change := range reverted {
+		job, err := s.repo.GetRouting(ctx, appName)
+		if err != nil {
+			return err
+		}
+
+		// Instances stopped during the rollout are skipped
+		_ = applyRoutingChange(job, change)
+
+		overrides, err := s.activeOverrides(ctx, appName)
+		if err != nil {
+			return err
+		}
+		job.ApplyOverrides(change.IpType, overrides, time.Now())
+
+		if err := s.repo.UpdateRouting(ctx, job); err != nil {
+			return err
+		}
+
+		if _, err := s.record(ctx, &domain.RoutingSnapshot{
+			Namespace: change.Namespace,
+			AppName:   appName,
+			Source:    domain.RoutingSourceRolloutAbort,
+			Change:    change,
+			Instances: job.InstanceRoutings(),
+		}, domain.RoutingApplied); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// authorize ensures that the app belongs to the namespace of the context.
+// Routing data is shared across namespaces, a namespace only sees the apps it holds an interest in.
+func (s *routingService) authorize(ctx context.Context, appName string) error {
+	_, err := s.interestRepo.GetByAppName(ctx, appName)
+	return err
+}
+
+// record stores the snapshot of applied routing priorities and notifies the observers.
+// Priorities equal to those of the latest snapshot are not recorded again, the latest snapshot is returned instead.
+func (s *routingService) record(ctx context.Context, snapshot *domain.RoutingSnapshot, eventType domain.EventType) (*domain.RoutingSnapshot, error) {
+	latest, err := s.historyRepo.Latest(ctx, snapshot.AppName)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	if latest != nil && len(domain.DiffRoutingSnapshots(latest, snapshot).Changes) == 0 {
+		s.logger.Debug("Routing priorities unchanged, skipping snapshot",
+			zap.String("appName", snapshot.AppName),
+			zap.Int64("version", latest.Version))
+		return latest, nil
+	}
+
+	snapshot.CreatedAt = time.Now()
+	if err := s.historyRepo.Create(ctx, snapshot); err != nil {
+		s.logger.Error("Failed to record routing snapshot", zap.String("appName", snapshot.AppName), zap.Error(err))
+		return nil, err
+	}
+
+	if s.subject != nil {
+		s.subject.Notify(domain.RoutingEvent{
+			Type:     eventType,
+			Snapshot: snapshot,
+		})
+	}
+
+	return snapshot, nil
+}
+
+// applyRoutingChange sets the priorities of the change on the matching instances of the job
+func applyRoutingChange(job *domain.Job, routingChange *domain.RoutingChange) error {
+	instances := make(map[int]*domain.ServiceInstanceListEntry, len(job.ServiceInstanceList))
+	for i := range job.ServiceInstanceList {
+		instances[job.ServiceInstanceList[i].InstanceNumber] = &job.ServiceInstanceList[i]
+	}
+
+	verr := &domain.ValidationError{}
+	for i, entry := range routingChange.InstancePriorityList {
+		instanceNumber, err := strconv.Atoi(entry.InstanceID)
+		instance, ok := instances[instanceNumber]
+		if err != nil || !ok {
+			verr.Add(fmt.Sprintf("instancePriorityList[%d].instanceId", i), "does not match an instance of the job")
+			continue
+		}
+
+		instance.RoutingPriority = setPriority(instance.RoutingPriority, routingChange.IpType, entry.Priority)
+	}
+
+	return verr.ErrOrNil()
+}
+
+// persistedPriorities returns the stored priorities of the given type, keyed by instance ID
+func persistedPriorities(job *domain.Job, ipType domain.ServiceIpType) map[string]float64 {
+	priorities := make(map[string]float64, len(job.ServiceInstanceList))
+	for _, instance := range job.ServiceInstanceList {
+		for _, entry := range instance.RoutingPriority {
+			if entry.IpType == ipType {
+				priorities[strconv.Itoa(instance.InstanceNumber)] = entry.Priority
+			}
+		}
+	}
+	return priorities
+}
+
+// setPriority replaces or appends the priority of the given type
+func setPriority(routing []domain.PriorityEntry, ipType domain.ServiceIpType, priority float64) []domain.PriorityEntry {
+	for i := range routing {
+		if routing[i].IpType == ipType {
+			routing[i].Priority = priority
+			return routing
+		}
+	}
+	return append(routing, domain.PriorityEntry{IpType: ipType, Priority: priority})
 }
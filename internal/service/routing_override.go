@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"go.uber.org/zap"
+)
+
+func (s *routingService) CreateOverride(ctx context.Context, appName string, request *domain.OverrideRequest) (*domain.RoutingOverride, error) {
+	s.logger.Info("Creating routing override",
+		zap.String("appName", appName),
+		zap.Int("instanceNumber", request.InstanceNumber),
+		zap.String("action", string(request.Action)))
+	if err := s.authorize(ctx, appName); err != nil {
+		return nil, err
+	}
+
+	job, err := s.repo.GetRouting(ctx, appName)
+	if err != nil {
+		return nil, err
+	}
+	if !hasInstance(job, request.InstanceNumber) {
+		return nil, domain.NewValidationError("instanceNumber", "does not match an instance of the job")
+	}
+
+	now := time.Now()
+	override := &domain.RoutingOverride{
+		Namespace:      domain.NamespaceFromContext(ctx),
+		AppName:        appName,
+		InstanceNumber: request.InstanceNumber,
+		IpType:         request.IpType,
+		Action:         request.Action,
+		Weight:         request.Weight,
+		Reason:         request.Reason,
+		ExpiresAt:      request.Expiry(now),
+		CreatedAt:      now,
+	}
+	if err := s.overrideRepo.Create(ctx, override); err != nil {
+		return nil, err
+	}
+
+	if err := s.reapply(ctx, appName, override.IpType); err != nil {
+		return nil, err
+	}
+	return override, nil
+}
+
+func (s *routingService) ListOverrides(ctx context.Context, appName string) ([]*domain.RoutingOverride, error) {
+	s.logger.Debug("Listing routing overrides", zap.String("appName", appName))
+	if err := s.authorize(ctx, appName); err != nil {
+		return nil, err
+	}
+	return s.activeOverrides(ctx, appName)
+}
+
+func (s *routingService) DeleteOverride(ctx context.Context, appName, id string) error {
+	s.logger.Info("Deleting routing override", zap.String("appName", appName), zap.String("id", id))
+	if err := s.authorize(ctx, appName); err != nil {
+		return err
+	}
+
+	override, err := s.overrideRepo.Get(ctx, appName, id)
+	if err != nil {
+		return err
+	}
+	if err := s.overrideRepo.Delete(ctx, appName, id); err != nil {
+		return err
+	}
+
+	return s.reapply(ctx, appName, override.IpType)
+}
+
+func (s *routingService) ExpireOverrides(ctx context.Context) error {
+	expired, err := s.overrideRepo.ListExpired(domain.WithNamespace(ctx, domain.AllNamespaces), time.Now())
+	if err != nil {
+		return err
+	}
+
+	type target struct {
+		namespace string
+		appName   string
+		ipType    domain.ServiceIpType
+	}
+	targets := make(map[target]bool)
+
+	var errs []error
+	for _, override := range expired {
+		nsCtx := domain.WithNamespace(ctx, override.Namespace)
+		if err := s.overrideRepo.Delete(nsCtx, override.AppName, override.ID); err != nil && !errors.Is(err, domain.ErrNotFound) {
+			errs = append(errs, err)
+			continue
+		}
+
+		s.logger.Info("Routing override expired",
+			zap.String("namespace", override.Namespace),
+			zap.String("appName", override.AppName),
+			zap.String("id", override.ID))
+		targets[target{override.Namespace, override.AppName, override.IpType}] = true
+	}
+
+	for t := range targets {
+		if err := s.reapply(domain.WithNamespace(ctx, t.namespace), t.appName, t.ipType); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// reapply recomputes the priorities of the IpType from the last computed change and the current overrides
+func (s *routingService) reapply(ctx context.Context, appName string, ipType domain.ServiceIpType) error {
+	job, err := s.repo.GetRouting(ctx, appName)
+	if err != nil {
+		return err
+	}
+
+	// The persisted priorities may already contain overrides, start over from the computed ones
+	computed, err := s.latestChange(ctx, appName, ipType)
+	if err != nil {
+		return err
+	}
+	if computed != nil {
+		// Instances stopped since the change are skipped, their priorities are gone anyway
+		_ = applyRoutingChange(job, computed)
+	} else {
+		computed = currentChange(ctx, job, appName, ipType)
+	}
+
+	overrides, err := s.activeOverrides(ctx, appName)
+	if err != nil {
+		return err
+	}
+	job.ApplyOverrides(ipType, overrides, time.Now())
+
+	if err := s.repo.UpdateRouting(ctx, job); err != nil {
+		return err
+	}
+
+	_, err = s.record(ctx, &domain.RoutingSnapshot{
+		Namespace: domain.NamespaceFromContext(ctx),
+		AppName:   appName,
+		Source:    domain.RoutingSourceOverride,
+		Change:    computed,
+		Instances: job.InstanceRoutings(),
+	}, domain.RoutingApplied)
+	return err
+}
+
+// latestChange returns the most recent computed change of the IpType, nil if there is none
+func (s *routingService) latestChange(ctx context.Context, appName string, ipType domain.ServiceIpType) (*domain.RoutingChange, error) {
+	snapshot, err := s.historyRepo.LatestChange(ctx, appName, ipType)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return snapshot.Change, nil
+}
+
+// activeOverrides returns the overrides of an app that did not expire yet
+func (s *routingService) activeOverrides(ctx context.Context, appName string) ([]*domain.RoutingOverride, error) {
+	overrides, err := s.overrideRepo.List(ctx, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]*domain.RoutingOverride, 0, len(overrides))
+	for _, override := range overrides {
+		if override.Active(now) {
+			active = append(active, override)
+		}
+	}
+	return active, nil
+}
+
+// currentChange captures the persisted priorities of the IpType as a routing change.
+// It serves as the computed baseline of apps that never received a change.
+func currentChange(ctx context.Context, job *domain.Job, appName string, ipType domain.ServiceIpType) *domain.RoutingChange {
+	change := &domain.RoutingChange{
+		Namespace: domain.NamespaceFromContext(ctx),
+		AppName:   appName,
+		IpType:    ipType,
+	}
+	for _, instance := range job.ServiceInstanceList {
+		for _, entry := range instance.RoutingPriority {
+			if entry.IpType == ipType {
+				change.InstancePriorityList = append(change.InstancePriorityList, domain.InstancePriorityEntry{
+					InstanceID: strconv.Itoa(instance.InstanceNumber),
+					Priority:   entry.Priority,
+				})
+			}
+		}
+	}
+	return change
+}
+
+func hasInstance(job *domain.Job, instanceNumber int) bool {
+	for _, instance := range job.ServiceInstanceList {
+		if instance.InstanceNumber == instanceNumber {
+			return true
+		}
+	}
+	return false
+}
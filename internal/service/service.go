@@ -1,34 +1,107 @@
 package service
 
 import (
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+	"github.com/smnzlnsk/routing-manager/internal/normalization"
 	"github.com/smnzlnsk/routing-manager/internal/observer"
 	"github.com/smnzlnsk/routing-manager/internal/observer/implementations"
 	"github.com/smnzlnsk/routing-manager/internal/repository"
+	"github.com/smnzlnsk/routing-manager/internal/rollout"
+	"github.com/smnzlnsk/routing-manager/internal/smoothing"
+	"github.com/smnzlnsk/routing-manager/internal/storage"
 	"go.uber.org/zap"
 )
 
 // Services is a collection of all services in the application
 type Services struct {
 	AlertService          AlertService
+	AlertRuleService      AlertRuleService
+	AlertSubject          *observer.AlertSubject
 	InterestService       InterestService
 	InterestSubject       *observer.InterestSubject
+	RoutingSubject        *observer.RoutingSubject
 	TaskSchedulerObserver *implementations.TaskSchedulerObserver
 	JobService            JobService
 	RoutingService        RoutingService
+	ResolverService       ResolverService
+	ReconcilerService     ReconcilerService
+	MetricService         MetricService
+	MetricSnapshotService MetricSnapshotService
+	// PerformanceStore holds the metrics of the services, it is read by the alert rules
+	PerformanceStore storage.PerformanceStore
+	// Smoother holds the smoothing state of the routing priorities, it observes interests to drop stale state
+	Smoother *smoothing.Smoother
+	// Rollout holds the state of the gradual rollouts, it observes interests to drop stale state
+	Rollout *rollout.Controller
+
+	// interestWatcher is set if the interest repository can change outside of the routing-manager
+	interestWatcher repository.InterestWatcher
+}
+
+// Options holds the tunables of the services
+type Options struct {
+	NamespaceQuotas domain.NamespaceQuotas
+	Normalization   normalization.Config
+	Smoothing       smoothing.Config
+	Rollout         rollout.Config
+	Reconciler      ReconcilerConfig
+	Alerts          AlertConfig
+	AlertRules      AlertRulesConfig
+	MetricSnapshots MetricSnapshotConfig
 }
 
 // NewServices creates a new Services instance
-func New(repositories *repository.Repositories, logger *zap.Logger) *Services {
+func New(repositories *repository.Repositories, store storage.PerformanceStore, opts Options, logger *zap.Logger) *Services {
 	// Create the interest subject for observer pattern
 	interestSubject := observer.NewInterestSubject(logger)
+	routingSubject := observer.NewRoutingSubject(logger)
+	alertSubject := observer.NewAlertSubject(logger)
+
+	smoother := smoothing.NewSmoother(opts.Smoothing)
+	rolloutController := rollout.NewController(opts.Rollout)
+
+	interestWatcher, _ := repositories.InterestRepository.(repository.InterestWatcher)
+
+	routingService := NewRoutingService(
+		repositories.RoutingRepository,
+		repositories.RoutingHistoryRepository,
+		repositories.RoutingOverrideRepository,
+		repositories.InterestRepository,
+		routingSubject,
+		normalization.NewNormalizer(opts.Normalization),
+		smoother,
+		rolloutController,
+		logger,
+	)
+
+	// Alerts of an app abort its gradual rollouts
+	alertSubject.Register(&rolloutAbortObserver{
+		routingService: routingService,
+		logger:         logger,
+	})
+
+	alertService := NewAlertService(repositories.AlertRepository, alertSubject, opts.Alerts, logger)
+	interestService := NewInterestService(repositories.InterestRepository, repositories.JobRepository, interestSubject, opts.NamespaceQuotas, logger)
 
 	return &Services{
-		AlertService:    NewAlertService(repositories.AlertRepository, logger),
-		InterestService: NewInterestService(repositories.InterestRepository, interestSubject, logger),
-		InterestSubject: interestSubject,
+		AlertService:     alertService,
+		AlertRuleService: NewAlertRuleService(repositories.AlertRuleRepository, alertService, interestService, store, opts.AlertRules, logger),
+		AlertSubject:     alertSubject,
+		InterestService:  interestService,
+		InterestSubject:  interestSubject,
+		RoutingSubject:   routingSubject,
 		// TaskSchedulerObserver will be set separately after creation
-		JobService:     NewJobService(repositories.JobRepository, logger),
-		RoutingService: NewRoutingService(repositories.RoutingRepository, logger),
+		JobService:            NewJobService(repositories.JobRepository, logger),
+		RoutingService:        routingService,
+		ResolverService:       NewResolverService(repositories.JobRepository, logger),
+		ReconcilerService:     NewReconcilerService(interestService, repositories.JobRepository, interestSubject, opts.Reconciler, logger),
+		MetricService:         NewMetricService(store, logger),
+		MetricSnapshotService: NewMetricSnapshotService(store, opts.MetricSnapshots, logger),
+		PerformanceStore:      store,
+		Smoother:              smoother,
+		Rollout:               rolloutController,
 		// Initialize other services here with their dependencies
+
+		interestWatcher: interestWatcher,
 	}
 }
@@ -0,0 +1,322 @@
+package smoothing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+// Params configures the smoothing of a single series of routing priorities
+type Params struct {
+	// Alpha is the EWMA weight of the newest result in (0, 1], 1 disables smoothing
+	Alpha float64
+	// MinChange is the smallest change of any priority that is published
+	MinChange float64
+	// StableCycles is the number of consecutive cycles a new ranking must persist before it is published
+	StableCycles int
+}
+
+// AppConfig overrides the smoothing parameters for a single app
+type AppConfig struct {
+	Default *Params
+	IpTypes map[domain.ServiceIpType]Params
+}
+
+// Config configures the smoothing of all apps and policy types.
+// Parameters are resolved from the most specific match: app and IpType, app, IpType, default.
+type Config struct {
+	Default Params
+	IpTypes map[domain.ServiceIpType]Params
+	Apps    map[string]AppConfig
+}
+
+// For returns the parameters for the given app and policy type
+func (c Config) For(appName string, ipType domain.ServiceIpType) Params {
+	if app, ok := c.Apps[appName]; ok {
+		if params, ok := app.IpTypes[ipType]; ok {
+			return params
+		}
+		if app.Default != nil {
+			return *app.Default
+		}
+	}
+	if params, ok := c.IpTypes[ipType]; ok {
+		return params
+	}
+	return c.Default
+}
+
+// Validate checks the configuration
+func (c Config) Validate() error {
+	check := func(scope string, params Params) error {
+		if params.Alpha < 0 || params.Alpha > 1 {
+			return fmt.Errorf("%s: alpha must be within [0, 1]", scope)
+		}
+		if params.MinChange < 0 || params.StableCycles < 0 {
+			return fmt.Errorf("%s: min change and stable cycles must not be negative", scope)
+		}
+		return nil
+	}
+
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for ipType, params := range c.IpTypes {
+		if err := check(string(ipType), params); err != nil {
+			return err
+		}
+	}
+	for appName, app := range c.Apps {
+		if app.Default != nil {
+			if err := check(appName, *app.Default); err != nil {
+				return err
+			}
+		}
+		for ipType, params := range app.IpTypes {
+			if err := check(appName+"/"+string(ipType), params); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// InstanceState holds the priorities of a single instance at the stages of the smoothing
+type InstanceState struct {
+	InstanceID string  `json:"instanceId"`
+	Raw        float64 `json:"raw"`
+	Smoothed   float64 `json:"smoothed"`
+	Published  float64 `json:"published"`
+}
+
+// SeriesState is the smoothing state of the priorities of one app and policy type
+type SeriesState struct {
+	IpType    domain.ServiceIpType `json:"IpType"`
+	Instances []InstanceState      `json:"instances"`
+	// PendingCycles counts how long the pending ranking has been stable, 0 if no ranking is pending
+	PendingCycles int       `json:"pendingCycles"`
+	PublishedAt   time.Time `json:"publishedAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type seriesKey struct {
+	namespace string
+	appName   string
+	ipType    domain.ServiceIpType
+}
+
+type series struct {
+	raw         map[string]float64
+	smoothed    map[string]float64
+	published   map[string]float64
+	pending     []string
+	pendingFor  int
+	publishedAt time.Time
+	updatedAt   time.Time
+}
+
+// Smoother dampens the routing priorities computed by the policies to prevent routing flapping.
+// It is stateful and keeps one series per app and policy type.
+type Smoother struct {
+	config Config
+	series map[seriesKey]*series
+	mutex  sync.Mutex
+}
+
+var _ domain.Observer = &Smoother{}
+
+// NewSmoother creates a new Smoother
+func NewSmoother(config Config) *Smoother {
+	return &Smoother{
+		config: config,
+		series: make(map[seriesKey]*series),
+	}
+}
+
+// Apply smooths the priorities of a routing change in place.
+// It returns false if the change should not be published, the priorities are then left as received.
+func (s *Smoother) Apply(routingChange *domain.RoutingChange) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	params := s.config.For(routingChange.AppName, routingChange.IpType)
+	key := seriesKey{routingChange.Namespace, routingChange.AppName, routingChange.IpType}
+	now := time.Now()
+
+	raw := make(map[string]float64, len(routingChange.InstancePriorityList))
+	for _, entry := range routingChange.InstancePriorityList {
+		raw[entry.InstanceID] = entry.Priority
+	}
+
+	current, ok := s.series[key]
+	if !ok || !sameInstances(current.raw, raw) {
+		// Start over whenever instances come or go, the previous state no longer applies
+		current = &series{
+			raw:         raw,
+			smoothed:    copyPriorities(raw),
+			published:   copyPriorities(raw),
+			publishedAt: now,
+			updatedAt:   now,
+		}
+		s.series[key] = current
+		return true
+	}
+
+	alpha := params.Alpha
+	if alpha <= 0 {
+		alpha = 1
+	}
+	current.raw = raw
+	current.updatedAt = now
+	for instanceID, priority := range raw {
+		current.smoothed[instanceID] = alpha*priority + (1-alpha)*current.smoothed[instanceID]
+	}
+
+	if maxChange(current.published, current.smoothed) < params.MinChange {
+		current.pending, current.pendingFor = nil, 0
+		return false
+	}
+
+	candidate := ranking(current.smoothed)
+	if !equalRanking(candidate, ranking(current.published)) {
+		if equalRanking(candidate, current.pending) {
+			current.pendingFor++
+		} else {
+			current.pending, current.pendingFor = candidate, 1
+		}
+		if current.pendingFor < params.StableCycles {
+			return false
+		}
+	}
+
+	current.published = copyPriorities(current.smoothed)
+	current.publishedAt = now
+	current.pending, current.pendingFor = nil, 0
+
+	for i := range routingChange.InstancePriorityList {
+		routingChange.InstancePriorityList[i].Priority = current.published[routingChange.InstancePriorityList[i].InstanceID]
+	}
+	return true
+}
+
+// State returns the smoothing state of all policy types of an app
+func (s *Smoother) State(namespace, appName string) []SeriesState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	states := []SeriesState{}
+	for key, current := range s.series {
+		if key.namespace != namespace || key.appName != appName {
+			continue
+		}
+
+		state := SeriesState{
+			IpType:        key.ipType,
+			Instances:     make([]InstanceState, 0, len(current.raw)),
+			PendingCycles: current.pendingFor,
+			PublishedAt:   current.publishedAt,
+			UpdatedAt:     current.updatedAt,
+		}
+		for instanceID, priority := range current.raw {
+			state.Instances = append(state.Instances, InstanceState{
+				InstanceID: instanceID,
+				Raw:        priority,
+				Smoothed:   current.smoothed[instanceID],
+				Published:  current.published[instanceID],
+			})
+		}
+		sort.Slice(state.Instances, func(i, j int) bool {
+			return state.Instances[i].InstanceID < state.Instances[j].InstanceID
+		})
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].IpType < states[j].IpType
+	})
+	return states
+}
+
+// Forget drops the smoothing state of an app
+func (s *Smoother) Forget(namespace, appName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key := range s.series {
+		if key.namespace == namespace && key.appName == appName {
+			delete(s.series, key)
+		}
+	}
+}
+
+// Update drops the smoothing state of deleted interests
+func (s *Smoother) Update(event domain.InterestEvent) {
+	if event.Type == domain.InterestDeleted {
+		s.Forget(event.Interest.Namespace, event.Interest.AppName)
+	}
+}
+
+// GetID returns the ID of the observer
+func (s *Smoother) GetID() string {
+	return "Smoother"
+}
+
+// ranking orders the instances by descending priority
+func ranking(priorities map[string]float64) []string {
+	order := make([]string, 0, len(priorities))
+	for instanceID := range priorities {
+		order = append(order, instanceID)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if priorities[order[i]] != priorities[order[j]] {
+			return priorities[order[i]] > priorities[order[j]]
+		}
+		return order[i] < order[j]
+	})
+	return order
+}
+
+func equalRanking(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameInstances(a, b map[string]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for instanceID := range a {
+		if _, ok := b[instanceID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// maxChange returns the largest absolute difference between two sets of priorities
+func maxChange(from, to map[string]float64) float64 {
+	change := 0.0
+	for instanceID, priority := range to {
+		change = math.Max(change, math.Abs(priority-from[instanceID]))
+	}
+	return change
+}
+
+func copyPriorities(priorities map[string]float64) map[string]float64 {
+	result := make(map[string]float64, len(priorities))
+	for instanceID, priority := range priorities {
+		result[instanceID] = priority
+	}
+	return result
+}
@@ -0,0 +1,223 @@
+package smoothing
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/smnzlnsk/routing-manager/internal/domain"
+)
+
+func change(priorities ...float64) *domain.RoutingChange {
+	c := &domain.RoutingChange{
+		Namespace: domain.DefaultNamespace,
+		AppName:   "web",
+		IpType:    domain.ServiceIpTypeClosest,
+	}
+	for i, priority := range priorities {
+		c.InstancePriorityList = append(c.InstancePriorityList, domain.InstancePriorityEntry{
+			InstanceID: strconv.Itoa(i),
+			Priority:   priority,
+		})
+	}
+	return c
+}
+
+func assertPriorities(t *testing.T, c *domain.RoutingChange, want ...float64) {
+	t.Helper()
+	if len(c.InstancePriorityList) != len(want) {
+		t.Fatalf("got %d priorities, want %d", len(c.InstancePriorityList), len(want))
+	}
+	for i, entry := range c.InstancePriorityList {
+		if math.Abs(entry.Priority-want[i]) > 1e-9 {
+			t.Errorf("instance %s: got priority %v, want %v", entry.InstanceID, entry.Priority, want[i])
+		}
+	}
+}
+
+// cycle is a policy result passed to the smoother and the expected outcome
+type cycle struct {
+	raw       []float64
+	published bool
+	// want holds the priorities of the change after smoothing
+	want []float64
+}
+
+func run(t *testing.T, s *Smoother, cycles []cycle) {
+	t.Helper()
+	for i, c := range cycles {
+		routingChange := change(c.raw...)
+		if got := s.Apply(routingChange); got != c.published {
+			t.Fatalf("cycle %d: got published %v, want %v", i, got, c.published)
+		}
+		assertPriorities(t, routingChange, c.want...)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		cycles []cycle
+	}{
+		{
+			name:   "disabled",
+			params: Params{},
+			cycles: []cycle{
+				{raw: []float64{0.6, 0.4}, published: true, want: []float64{0.6, 0.4}},
+				{raw: []float64{0.1, 0.9}, published: true, want: []float64{0.1, 0.9}},
+				{raw: []float64{0.7, 0.3}, published: true, want: []float64{0.7, 0.3}},
+			},
+		},
+		{
+			name:   "ewma",
+			params: Params{Alpha: 0.5},
+			cycles: []cycle{
+				{raw: []float64{1, 0}, published: true, want: []float64{1, 0}},
+				{raw: []float64{0, 1}, published: true, want: []float64{0.5, 0.5}},
+				{raw: []float64{0, 1}, published: true, want: []float64{0.25, 0.75}},
+				{raw: []float64{0, 1}, published: true, want: []float64{0.125, 0.875}},
+			},
+		},
+		{
+			name:   "min change holds back small changes",
+			params: Params{Alpha: 1, MinChange: 0.1},
+			cycles: []cycle{
+				{raw: []float64{0.5, 0.5}, published: true, want: []float64{0.5, 0.5}},
+				{raw: []float64{0.55, 0.45}, published: false, want: []float64{0.55, 0.45}},
+				{raw: []float64{0.45, 0.55}, published: false, want: []float64{0.45, 0.55}},
+				{raw: []float64{0.7, 0.3}, published: true, want: []float64{0.7, 0.3}},
+			},
+		},
+		{
+			name:   "min change compares to the published priorities",
+			params: Params{Alpha: 1, MinChange: 0.1},
+			cycles: []cycle{
+				{raw: []float64{0.5, 0.5}, published: true, want: []float64{0.5, 0.5}},
+				{raw: []float64{0.56, 0.44}, published: false, want: []float64{0.56, 0.44}},
+				{raw: []float64{0.62, 0.38}, published: true, want: []float64{0.62, 0.38}},
+				{raw: []float64{0.66, 0.34}, published: false, want: []float64{0.66, 0.34}},
+			},
+		},
+		{
+			name:   "min change applies to smoothed priorities",
+			params: Params{Alpha: 0.5, MinChange: 0.2},
+			cycles: []cycle{
+				{raw: []float64{1, 0}, published: true, want: []float64{1, 0}},
+				{raw: []float64{0.7, 0.3}, published: false, want: []float64{0.7, 0.3}},
+				{raw: []float64{0.7, 0.3}, published: true, want: []float64{0.775, 0.225}},
+			},
+		},
+		{
+			name:   "stable cycles",
+			params: Params{Alpha: 1, StableCycles: 3},
+			cycles: []cycle{
+				{raw: []float64{0.6, 0.4}, published: true, want: []float64{0.6, 0.4}},
+				{raw: []float64{0.4, 0.6}, published: false, want: []float64{0.4, 0.6}},
+				{raw: []float64{0.3, 0.7}, published: false, want: []float64{0.3, 0.7}},
+				{raw: []float64{0.35, 0.65}, published: true, want: []float64{0.35, 0.65}},
+			},
+		},
+		{
+			name:   "stable cycles restart when the ranking flaps",
+			params: Params{Alpha: 1, StableCycles: 2},
+			cycles: []cycle{
+				{raw: []float64{0.6, 0.4, 0}, published: true, want: []float64{0.6, 0.4, 0}},
+				{raw: []float64{0.4, 0.6, 0}, published: false, want: []float64{0.4, 0.6, 0}},
+				{raw: []float64{0.4, 0.2, 0.4001}, published: false, want: []float64{0.4, 0.2, 0.4001}},
+				{raw: []float64{0.4, 0.6, 0}, published: false, want: []float64{0.4, 0.6, 0}},
+				{raw: []float64{0.3, 0.7, 0}, published: true, want: []float64{0.3, 0.7, 0}},
+			},
+		},
+		{
+			name:   "stable cycles keep the ranking",
+			params: Params{Alpha: 1, StableCycles: 5},
+			cycles: []cycle{
+				{raw: []float64{0.6, 0.4}, published: true, want: []float64{0.6, 0.4}},
+				{raw: []float64{0.7, 0.3}, published: true, want: []float64{0.7, 0.3}},
+			},
+		},
+		{
+			name:   "new instances start over",
+			params: Params{Alpha: 0.5, MinChange: 0.5, StableCycles: 3},
+			cycles: []cycle{
+				{raw: []float64{0.6, 0.4}, published: true, want: []float64{0.6, 0.4}},
+				{raw: []float64{0.2, 0.3, 0.5}, published: true, want: []float64{0.2, 0.3, 0.5}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run(t, NewSmoother(Config{Default: tt.params}), tt.cycles)
+		})
+	}
+}
+
+func TestState(t *testing.T) {
+	s := NewSmoother(Config{Default: Params{Alpha: 0.5, StableCycles: 2}})
+	s.Apply(change(1, 0))
+	s.Apply(change(0, 1))
+	s.Apply(change(0, 1))
+
+	states := s.State(domain.DefaultNamespace, "web")
+	if len(states) != 1 {
+		t.Fatalf("got %d states, want 1", len(states))
+	}
+	state := states[0]
+	if state.IpType != domain.ServiceIpTypeClosest || state.PendingCycles != 1 {
+		t.Fatalf("got state %+v", state)
+	}
+
+	want := []InstanceState{
+		{InstanceID: "0", Raw: 0, Smoothed: 0.25, Published: 0.5},
+		{InstanceID: "1", Raw: 1, Smoothed: 0.75, Published: 0.5},
+	}
+	for i, instance := range state.Instances {
+		if instance != want[i] {
+			t.Errorf("got instance %+v, want %+v", instance, want[i])
+		}
+	}
+
+	s.Forget(domain.DefaultNamespace, "web")
+	if states := s.State(domain.DefaultNamespace, "web"); len(states) != 0 {
+		t.Fatalf("got %d states after forgetting the app, want 0", len(states))
+	}
+}
+
+func TestConfigFor(t *testing.T) {
+	appDefault := Params{Alpha: 0.4}
+	config := Config{
+		Default: Params{Alpha: 0.1},
+		IpTypes: map[domain.ServiceIpType]Params{
+			domain.ServiceIpTypeFPS: {Alpha: 0.2},
+		},
+		Apps: map[string]AppConfig{
+			"web": {
+				Default: &appDefault,
+				IpTypes: map[domain.ServiceIpType]Params{domain.ServiceIpTypeClosest: {Alpha: 0.3}},
+			},
+			"db": {
+				IpTypes: map[domain.ServiceIpType]Params{domain.ServiceIpTypeClosest: {Alpha: 0.5}},
+			},
+		},
+	}
+
+	tests := []struct {
+		appName string
+		ipType  domain.ServiceIpType
+		alpha   float64
+	}{
+		{appName: "web", ipType: domain.ServiceIpTypeClosest, alpha: 0.3},
+		{appName: "web", ipType: domain.ServiceIpTypeFPS, alpha: 0.4},
+		{appName: "db", ipType: domain.ServiceIpTypeClosest, alpha: 0.5},
+		{appName: "db", ipType: domain.ServiceIpTypeFPS, alpha: 0.2},
+		{appName: "db", ipType: domain.ServiceIpTypeUnderutilized, alpha: 0.1},
+		{appName: "api", ipType: domain.ServiceIpTypeUnderutilized, alpha: 0.1},
+	}
+	for _, tt := range tests {
+		if got := config.For(tt.appName, tt.ipType).Alpha; got != tt.alpha {
+			t.Errorf("%s/%s: got alpha %v, want %v", tt.appName, tt.ipType, got, tt.alpha)
+		}
+	}
+}
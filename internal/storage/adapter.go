@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// performanceStore adapts a MetricStore to the float API of the PerformanceStore.
+// A series ID addresses the series of its metric labeled with nothing but the app and, if set, the instance.
+type performanceStore struct {
+	MetricStore
+}
+
+// NewPerformanceStore wraps the labeled store with the float API addressing series by their series ID
+func NewPerformanceStore(store MetricStore) PerformanceStore {
+	return &performanceStore{MetricStore: store}
+}
+
+// SeriesLabels returns the metric and the labels of the series addressed by the series ID
+func SeriesLabels(seriesID string) (metric string, labels Labels) {
+	serviceID, metric := ParseSeriesID(seriesID)
+	appName, instance := ParseServiceID(serviceID)
+	labels = Labels{LabelApp: appName}
+	if instance != nil {
+		labels[LabelInstance] = strconv.Itoa(*instance)
+	}
+	return metric, labels
+}
+
+// SeriesIDOf returns the series ID of the series of the metric with the labels.
+// It reports false if the series cannot be addressed by a series ID.
+func SeriesIDOf(metric string, labels Labels) (string, bool) {
+	appName := labels[LabelApp]
+	if appName == "" {
+		return "", false
+	}
+	instance := labels.Instance()
+	if labels[LabelInstance] != "" && instance == nil {
+		return "", false
+	}
+	expected := 1
+	if instance != nil {
+		expected = 2
+	}
+	if len(labels.Names()) != expected {
+		return "", false
+	}
+	return SeriesID(ServiceID(appName, instance), metric), true
+}
+
+// ExactSelector selects the series of the metric holding the labels, which may include series with further labels
+func ExactSelector(metric string, labels Labels) Selector {
+	selector := Selector{Metric: metric}
+	for _, name := range labels.Names() {
+		selector.Matchers = append(selector.Matchers, &Matcher{Name: name, Type: MatchEqual, Value: labels[name]})
+	}
+	return selector
+}
+
+// FindSeries returns the series with exactly the labels, nil if there is none
+func FindSeries(series []Series, labels Labels) *Series {
+	for i := range series {
+		if series[i].Labels.Equal(labels) {
+			return &series[i]
+		}
+	}
+	return nil
+}
+
+// series returns the points of the series addressed by the series ID
+func (s *performanceStore) series(ctx context.Context, seriesID string, since time.Time, limit int) (*Series, error) {
+	metric, labels := SeriesLabels(seriesID)
+
+	var matching []Series
+	var err error
+	if limit == 1 && since.IsZero() {
+		matching, err = s.Latest(ctx, ExactSelector(metric, labels))
+	} else {
+		matching, err = s.History(ctx, ExactSelector(metric, labels), since, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	series := FindSeries(matching, labels)
+	if series == nil {
+		return nil, ErrNotFound
+	}
+	return series, nil
+}
+
+// SaveMetric stores a performance metric for a service
+func (s *performanceStore) SaveMetric(ctx context.Context, serviceID string, value float64) error {
+	metric, labels := SeriesLabels(serviceID)
+	return s.Append(ctx, []Sample{{Metric: metric, Labels: labels, Value: value, Timestamp: time.Now()}})
+}
+
+// UpdateMetric updates an existing performance metric
+func (s *performanceStore) UpdateMetric(ctx context.Context, serviceID string, value float64) error {
+	if _, err := s.series(ctx, serviceID, time.Time{}, 1); err != nil {
+		return err
+	}
+	return s.SaveMetric(ctx, serviceID, value)
+}
+
+// GetMetric retrieves the latest performance metric for a service
+func (s *performanceStore) GetMetric(ctx context.Context, serviceID string) (float64, error) {
+	series, err := s.series(ctx, serviceID, time.Time{}, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(series.Points) == 0 {
+		return 0, ErrNotFound
+	}
+	return series.Points[0].Value, nil
+}
+
+// GetMetricHistory retrieves historical performance metrics for a service, newest first
+func (s *performanceStore) GetMetricHistory(ctx context.Context, serviceID string, since time.Time, limit int) ([]PerformanceRecord, error) {
+	// An unknown series is an error, a known one without points in the range is not
+	if _, err := s.series(ctx, serviceID, time.Time{}, 1); err != nil {
+		return nil, err
+	}
+	series, err := s.series(ctx, serviceID, since, limit)
+	if err != nil {
+		if err == ErrNotFound {
+			return []PerformanceRecord{}, nil
+		}
+		return nil, err
+	}
+
+	records := make([]PerformanceRecord, 0, len(series.Points))
+	for _, point := range series.Points {
+		records = append(records, PerformanceRecord{
+			ServiceID:  serviceID,
+			Value:      point.Value,
+			RecordedAt: point.Timestamp,
+			Min:        point.Min,
+			Max:        point.Max,
+			Count:      point.Count,
+		})
+	}
+	return records, nil
+}
+
+// GetAllMetrics retrieves the latest performance metrics for all series addressable by a series ID
+func (s *performanceStore) GetAllMetrics(ctx context.Context) (map[string]float64, error) {
+	latest, err := s.Latest(ctx, Selector{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64, len(latest))
+	for _, series := range latest {
+		seriesID, ok := SeriesIDOf(series.Metric, series.Labels)
+		if !ok || len(series.Points) == 0 {
+			continue
+		}
+		result[seriesID] = series.Points[0].Value
+	}
+	return result, nil
+}
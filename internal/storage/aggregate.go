@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// AggregateFunc aggregates the points of a window into a single value
+type AggregateFunc string
+
+const (
+	AggregateAvg AggregateFunc = "avg"
+	AggregateMin AggregateFunc = "min"
+	AggregateMax AggregateFunc = "max"
+	AggregateP50 AggregateFunc = "p50"
+	AggregateP90 AggregateFunc = "p90"
+	AggregateP99 AggregateFunc = "p99"
+	// AggregateRate is the change per second between the oldest and the newest point of a series,
+	// the rates of the series of a group are summed
+	AggregateRate AggregateFunc = "rate"
+	// AggregateEWMA is the exponentially weighted moving average of the points from oldest to newest
+	AggregateEWMA AggregateFunc = "ewma"
+)
+
+// DefaultEWMAAlpha is the smoothing factor of the EWMA if the query does not set one
+const DefaultEWMAAlpha = 0.5
+
+// AggregateQuery aggregates the points of the selected series within a window
+type AggregateQuery struct {
+	Selector Selector
+	Func     AggregateFunc
+	// Since is the start of the window, which ends now
+	Since time.Time
+	// GroupBy lists the labels the series are grouped by. Every series is aggregated on its own if it is empty,
+	// otherwise the points of all series of a group are aggregated together.
+	GroupBy []string
+	// Alpha is the smoothing factor of the EWMA in (0, 1], DefaultEWMAAlpha if zero
+	Alpha float64
+}
+
+// Validate checks the aggregate function, the grouping labels and the smoothing factor
+func (q AggregateQuery) Validate() error {
+	switch q.Func {
+	case AggregateAvg, AggregateMin, AggregateMax, AggregateP50, AggregateP90, AggregateP99, AggregateRate, AggregateEWMA:
+	default:
+		return fmt.Errorf("invalid aggregate function %q", q.Func)
+	}
+	for _, name := range q.GroupBy {
+		if err := ValidateLabelName(name); err != nil {
+			return err
+		}
+	}
+	if q.Alpha < 0 || q.Alpha > 1 {
+		return fmt.Errorf("alpha must be within (0, 1]")
+	}
+	return nil
+}
+
+// AggregateResult is the aggregated value of a series or a group of series
+type AggregateResult struct {
+	Metric string
+	// Labels are the labels of the series, or the grouping labels of the group
+	Labels Labels
+	Value  float64
+	// Samples is the number of samples aggregated
+	Samples int
+}
+
+// GroupLabels returns the labels of a series that identify its group, all labels if the query is not grouped
+func (q AggregateQuery) GroupLabels(labels Labels) Labels {
+	if len(q.GroupBy) == 0 {
+		return labels.Copy()
+	}
+	group := make(Labels, len(q.GroupBy))
+	for _, name := range q.GroupBy {
+		if value := labels[name]; value != "" {
+			group[name] = value
+		}
+	}
+	return group
+}
+
+// Aggregate computes the query over the series, whose points are ordered newest first.
+// Results are ordered by their series key, groups without a defined value are omitted,
+// e.g. the rate of a single point.
+func Aggregate(series []Series, query AggregateQuery) []AggregateResult {
+	type group struct {
+		metric string
+		labels Labels
+		series []Series
+	}
+	groups := make(map[string]*group)
+	keys := make([]string, 0)
+	for _, s := range series {
+		labels := query.GroupLabels(s.Labels)
+		key := SeriesKey(s.Metric, labels)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{metric: s.Metric, labels: labels}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.series = append(g.series, s)
+	}
+	sort.Strings(keys)
+
+	results := make([]AggregateResult, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		value, samples, ok := aggregateGroup(g.series, query)
+		if !ok {
+			continue
+		}
+		results = append(results, AggregateResult{
+			Metric:  g.metric,
+			Labels:  g.labels,
+			Value:   value,
+			Samples: samples,
+		})
+	}
+	return results
+}
+
+// aggregateGroup computes the aggregate function over the points of the series of a group
+func aggregateGroup(series []Series, query AggregateQuery) (float64, int, bool) {
+	if query.Func == AggregateRate {
+		sum, samples, defined := 0.0, 0, false
+		for _, s := range series {
+			samples += pointSamples(s.Points)
+			if rate, ok := rate(s.Points); ok {
+				sum += rate
+				defined = true
+			}
+		}
+		return sum, samples, defined
+	}
+
+	var points []Point
+	for _, s := range series {
+		points = append(points, s.Points...)
+	}
+	if len(points) == 0 {
+		return 0, 0, false
+	}
+	samples := pointSamples(points)
+
+	switch query.Func {
+	case AggregateAvg:
+		sum := 0.0
+		for _, p := range points {
+			sum += p.Value * float64(pointCount(p))
+		}
+		return sum / float64(samples), samples, true
+	case AggregateMin:
+		min := math.Inf(1)
+		for _, p := range points {
+			if p.Min != nil {
+				min = math.Min(min, *p.Min)
+			} else {
+				min = math.Min(min, p.Value)
+			}
+		}
+		return min, samples, true
+	case AggregateMax:
+		max := math.Inf(-1)
+		for _, p := range points {
+			if p.Max != nil {
+				max = math.Max(max, *p.Max)
+			} else {
+				max = math.Max(max, p.Value)
+			}
+		}
+		return max, samples, true
+	case AggregateP50:
+		return Percentile(points, 0.5), samples, true
+	case AggregateP90:
+		return Percentile(points, 0.9), samples, true
+	case AggregateP99:
+		return Percentile(points, 0.99), samples, true
+	case AggregateEWMA:
+		alpha := query.Alpha
+		if alpha == 0 {
+			alpha = DefaultEWMAAlpha
+		}
+		return ewma(points, alpha), samples, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// Percentile returns the q-quantile of the point values, interpolating linearly between the closest ranks.
+// Downsampled points count with their average. The points must not be empty.
+func Percentile(points []Point, q float64) float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	sort.Float64s(values)
+
+	rank := q * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	if lower >= len(values)-1 {
+		return values[len(values)-1]
+	}
+	fraction := rank - float64(lower)
+	return values[lower] + fraction*(values[lower+1]-values[lower])
+}
+
+// rate returns the change per second between the oldest and the newest of the points ordered newest first
+func rate(points []Point) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	newest, oldest := points[0], points[len(points)-1]
+	seconds := newest.Timestamp.Sub(oldest.Timestamp).Seconds()
+	if seconds <= 0 {
+		return 0, false
+	}
+	return (newest.Value - oldest.Value) / seconds, true
+}
+
+// ewma returns the exponentially weighted moving average of the points in time order
+func ewma(points []Point, alpha float64) float64 {
+	ordered := make([]Point, len(points))
+	copy(ordered, points)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+	})
+
+	average := ordered[0].Value
+	for _, p := range ordered[1:] {
+		average = alpha*p.Value + (1-alpha)*average
+	}
+	return average
+}
+
+// pointCount returns the number of samples a point stands for
+func pointCount(p Point) int {
+	if p.Count > 0 {
+		return p.Count
+	}
+	return 1
+}
+
+// pointSamples returns the number of samples the points stand for
+func pointSamples(points []Point) int {
+	samples := 0
+	for _, p := range points {
+		samples += pointCount(p)
+	}
+	return samples
+}
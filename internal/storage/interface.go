@@ -2,12 +2,70 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+var (
+	// ErrNotFound is returned when no metric is stored for a service
+	ErrNotFound = errors.New("service not found")
+	// ErrOutOfOrder is returned for samples older than the newest sample of their series
+	ErrOutOfOrder = errors.New("sample is older than the newest sample of its series")
+)
+
+// Sample is a single value of the series of a metric identified by its labels
+type Sample struct {
+	Metric string
+	Labels Labels
+	Value  float64
+	// Timestamp defaults to the time the sample is appended
+	Timestamp time.Time
+}
+
+// Point is a value of a series at a point in time.
+// Points downsampled from several samples hold their average as value.
+type Point struct {
+	Value     float64
+	Timestamp time.Time
+	// Min, Max and Count are only set on downsampled points
+	Min   *float64
+	Max   *float64
+	Count int
+}
+
+// Series holds points of the series of a metric, newest first
+type Series struct {
+	Metric string
+	Labels Labels
+	Points []Point
+}
+
+// MetricStore stores labeled, multi-dimensional metrics. It is implemented by the storage backends.
+type MetricStore interface {
+	// Append stores the samples. Samples that cannot be stored are skipped, the returned error reports them.
+	Append(ctx context.Context, samples []Sample) error
+
+	// Latest returns the newest point of every selected series
+	Latest(ctx context.Context, selector Selector) ([]Series, error)
+
+	// History returns the points of every selected series recorded since the given time, newest first.
+	// A positive limit caps the points returned per series, series without points in the range are omitted.
+	History(ctx context.Context, selector Selector, since time.Time, limit int) ([]Series, error)
+
+	// Aggregate aggregates the points of the selected series recorded since the start of the window,
+	// the results must equal those of Aggregate applied to the history of the series
+	Aggregate(ctx context.Context, query AggregateQuery) ([]AggregateResult, error)
+
+	// Close closes the storage connection
+	Close() error
+}
+
 // PerformanceStore defines the interface for storing and retrieving
-// performance metrics for services
+// performance metrics for services. The float API addresses a series by its
+// series ID and is an adapter over the labeled MetricStore, see NewPerformanceStore.
 type PerformanceStore interface {
+	MetricStore
+
 	// SaveMetric stores a performance metric for a service
 	SaveMetric(ctx context.Context, serviceID string, value float64) error
 
@@ -22,14 +80,16 @@ type PerformanceStore interface {
 
 	// GetAllMetrics retrieves the latest performance metrics for all services
 	GetAllMetrics(ctx context.Context) (map[string]float64, error)
-
-	// Close closes the storage connection
-	Close() error
 }
 
-// PerformanceRecord represents a single performance metric record with timestamp
+// PerformanceRecord represents a single performance metric record with timestamp.
+// Records downsampled from several samples hold their average as value.
 type PerformanceRecord struct {
 	ServiceID  string    `json:"service_id"`
 	Value      float64   `json:"value"`
 	RecordedAt time.Time `json:"recorded_at"`
+	// Min, Max and Count are only set on downsampled records
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count,omitempty"`
 }
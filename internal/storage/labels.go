@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Well-known labels
+const (
+	LabelApp      = "app"
+	LabelInstance = "instance"
+	LabelNode     = "node"
+	LabelIpType   = "ipType"
+)
+
+// labelNamePattern matches valid label names
+var labelNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateLabelName checks the syntax of a label name
+func ValidateLabelName(name string) error {
+	if !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("label name %q must consist of alphanumerics or '_' and must not start with a digit", name)
+	}
+	return nil
+}
+
+// Labels identify a series among the series of a metric. Labels with empty values are the same as absent labels.
+type Labels map[string]string
+
+// Copy returns a copy of the labels without empty values
+func (l Labels) Copy() Labels {
+	labels := make(Labels, len(l))
+	for name, value := range l {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// Names returns the names of the labels with non-empty values in order
+func (l Labels) Names() []string {
+	names := make([]string, 0, len(l))
+	for name, value := range l {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Equal reports whether both label sets hold the same non-empty values
+func (l Labels) Equal(other Labels) bool {
+	names := l.Names()
+	if len(names) != len(other.Names()) {
+		return false
+	}
+	for _, name := range names {
+		if l[name] != other[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// Instance returns the number of the instance label, nil if it is absent or not a number
+func (l Labels) Instance() *int {
+	n, err := strconv.Atoi(l[LabelInstance])
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// SeriesKey returns the canonical key of the series of a metric with the given labels, e.g. cpu{app="a",instance="0"}
+func SeriesKey(metric string, labels Labels) string {
+	var b strings.Builder
+	b.WriteString(metric)
+	b.WriteByte('{')
+	for i, name := range labels.Names() {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// MatchType is the comparison of a label matcher
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// Matcher selects series by the value of a label. An absent label has the empty value.
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+
+	re *regexp.Regexp
+}
+
+// NewMatcher creates a label matcher, regular expressions are anchored to match the whole value
+func NewMatcher(name string, matchType MatchType, value string) (*Matcher, error) {
+	if err := ValidateLabelName(name); err != nil {
+		return nil, err
+	}
+	m := &Matcher{Name: name, Type: matchType, Value: value}
+	switch matchType {
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value, err)
+		}
+		m.re = re
+	default:
+		return nil, fmt.Errorf("invalid match type %q", matchType)
+	}
+	return m, nil
+}
+
+// ParseMatcher parses a matcher written as name=value, name!=value, name=~regexp or name!~regexp
+func ParseMatcher(s string) (*Matcher, error) {
+	i := strings.IndexAny(s, "=!")
+	if i <= 0 {
+		return nil, fmt.Errorf("matcher %q must be of the form name=value, name!=value, name=~regexp or name!~regexp", s)
+	}
+	name, rest := s[:i], s[i:]
+	for _, matchType := range []MatchType{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual} {
+		if strings.HasPrefix(rest, string(matchType)) {
+			return NewMatcher(name, matchType, strings.TrimPrefix(rest, string(matchType)))
+		}
+	}
+	return nil, fmt.Errorf("matcher %q must be of the form name=value, name!=value, name=~regexp or name!~regexp", s)
+}
+
+// Matches reports whether the labels satisfy the matcher
+func (m *Matcher) Matches(labels Labels) bool {
+	value := labels[m.Name]
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	default:
+		return false
+	}
+}
+
+// Pattern returns the anchored regular expression of regexp matchers
+func (m *Matcher) Pattern() string {
+	if m.re == nil {
+		return ""
+	}
+	return m.re.String()
+}
+
+// String formats the matcher as parsed by ParseMatcher
+func (m *Matcher) String() string {
+	return m.Name + string(m.Type) + m.Value
+}
+
+// Selector selects the series of a metric whose labels satisfy all matchers
+type Selector struct {
+	// Metric is the name of the metric, empty selects every metric
+	Metric   string
+	Matchers []*Matcher
+}
+
+// Matches reports whether the series of the metric with the labels is selected
+func (s Selector) Matches(metric string, labels Labels) bool {
+	if s.Metric != "" && s.Metric != metric {
+		return false
+	}
+	for _, m := range s.Matchers {
+		if !m.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
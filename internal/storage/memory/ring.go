@@ -0,0 +1,99 @@
+package memory
+
+import "time"
+
+// point is a raw sample or a bucket of samples downsampled into a coarser resolution
+type point struct {
+	at    time.Time
+	count int
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// newPoint creates a point holding a single sample
+func newPoint(at time.Time, value float64) point {
+	return point{at: at, count: 1, sum: value, min: value, max: value}
+}
+
+// merge folds the samples of the other point into the point
+func (p *point) merge(other point) {
+	p.count += other.count
+	p.sum += other.sum
+	if other.min < p.min {
+		p.min = other.min
+	}
+	if other.max > p.max {
+		p.max = other.max
+	}
+}
+
+// ring is a ring buffer of points ordered from oldest to newest. It grows on demand up to its maximum size,
+// pushing into a full ring evicts the oldest point.
+type ring struct {
+	buf   []point
+	start int
+	size  int
+	max   int
+}
+
+func newRing(max int) *ring {
+	return &ring{max: max}
+}
+
+// push appends the point as the newest, it returns the evicted oldest point if the ring was full
+func (r *ring) push(p point) (point, bool) {
+	if r.size == len(r.buf) {
+		if len(r.buf) == r.max {
+			evicted := r.buf[r.start]
+			r.buf[r.start] = p
+			r.start = (r.start + 1) % len(r.buf)
+			return evicted, true
+		}
+		r.grow()
+	}
+	r.buf[(r.start+r.size)%len(r.buf)] = p
+	r.size++
+	return point{}, false
+}
+
+// grow doubles the capacity of the ring up to its maximum size
+func (r *ring) grow() {
+	capacity := 2 * len(r.buf)
+	if capacity < 8 {
+		capacity = 8
+	}
+	if capacity > r.max {
+		capacity = r.max
+	}
+	buf := make([]point, capacity)
+	for i := 0; i < r.size; i++ {
+		buf[i] = r.buf[(r.start+i)%len(r.buf)]
+	}
+	r.buf = buf
+	r.start = 0
+}
+
+// len returns the number of points in the ring
+func (r *ring) len() int {
+	return r.size
+}
+
+// newest returns the i-th newest point, 0 being the newest
+func (r *ring) newest(i int) *point {
+	return &r.buf[(r.start+r.size-1-i)%len(r.buf)]
+}
+
+// oldest returns the oldest point, the ring must not be empty
+func (r *ring) oldest() point {
+	return r.buf[r.start]
+}
+
+// pop removes the oldest point, the ring must not be empty
+func (r *ring) pop() point {
+	p := r.buf[r.start]
+	r.buf[r.start] = point{}
+	r.start = (r.start + 1) % len(r.buf)
+	r.size--
+	return p
+}
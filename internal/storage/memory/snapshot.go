@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+)
+
+// snapshotVersion is the version of the snapshot format, snapshots of other versions are rejected
+const snapshotVersion = 1
+
+// maxSnapshotBytes limits the decompressed size of snapshots read, so that a small upload cannot exhaust the memory
+var maxSnapshotBytes int64 = 1 << 30
+
+var _ storage.Snapshotter = &MemoryStore{}
+
+// snapshot is the gzip compressed JSON document holding all series of the store
+type snapshot struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Series    []seriesSnapshot `json:"series"`
+}
+
+// seriesSnapshot holds the history of a series, every ring oldest first
+type seriesSnapshot struct {
+	Metric string           `json:"metric"`
+	Labels storage.Labels   `json:"labels"`
+	Latest sampleSnapshot   `json:"latest"`
+	Raw    []sampleSnapshot `json:"raw"`
+	Tiers  []tierSnapshot   `json:"tiers,omitempty"`
+}
+
+// tierSnapshot holds the buckets of a downsampling resolution
+type tierSnapshot struct {
+	Interval time.Duration    `json:"interval"`
+	Buckets  []bucketSnapshot `json:"buckets"`
+}
+
+// sampleSnapshot is a raw sample
+type sampleSnapshot struct {
+	At    time.Time `json:"t"`
+	Value float64   `json:"v"`
+}
+
+// bucketSnapshot is a downsampled bucket
+type bucketSnapshot struct {
+	At    time.Time `json:"t"`
+	Count int       `json:"n"`
+	Sum   float64   `json:"sum"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+}
+
+// WriteSnapshot writes all series to the writer as gzip compressed JSON
+func (m *MemoryStore) WriteSnapshot(w io.Writer) error {
+	snap := m.snapshot()
+
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(snap); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadSnapshot replaces all series with those of the snapshot. The samples are fitted into the configured
+// resolutions, which may differ from those of the store that wrote the snapshot, and expire as usual.
+// The store is left unchanged if the snapshot cannot be read.
+func (m *MemoryStore) ReadSnapshot(r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	defer zr.Close()
+
+	// One byte more than allowed is read to tell an oversized snapshot from one of exactly the maximum size
+	limited := &io.LimitedReader{R: zr, N: maxSnapshotBytes + 1}
+	var snap snapshot
+	if err := json.NewDecoder(limited).Decode(&snap); err != nil {
+		if limited.N == 0 {
+			return fmt.Errorf("invalid snapshot: exceeds %d bytes decompressed", maxSnapshotBytes)
+		}
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	now := time.Now()
+	restored := make(map[string]*series, len(snap.Series))
+	for _, ss := range snap.Series {
+		if ss.Metric == "" {
+			return fmt.Errorf("invalid snapshot: series without metric")
+		}
+		if s := m.restore(ss, now); !s.empty() {
+			restored[storage.SeriesKey(s.metric, s.labels)] = s
+		}
+	}
+
+	m.mu.Lock()
+	m.series = restored
+	m.mu.Unlock()
+	return nil
+}
+
+// snapshot copies all series
+func (m *MemoryStore) snapshot() snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snap := snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now(),
+		Series:    make([]seriesSnapshot, 0, len(m.series)),
+	}
+	for _, s := range m.selected(storage.Selector{}) {
+		ss := seriesSnapshot{
+			Metric: s.metric,
+			Labels: s.labels.Copy(),
+			Latest: sampleSnapshot{At: s.latest.at, Value: s.latest.sum / float64(s.latest.count)},
+			Raw:    make([]sampleSnapshot, 0, s.raw.len()),
+			Tiers:  make([]tierSnapshot, 0, len(s.tiers)),
+		}
+		for i := s.raw.len() - 1; i >= 0; i-- {
+			p := s.raw.newest(i)
+			ss.Raw = append(ss.Raw, sampleSnapshot{At: p.at, Value: p.sum / float64(p.count)})
+		}
+		for tier, buckets := range s.tiers {
+			ts := tierSnapshot{
+				Interval: m.config.Downsampling[tier].Interval,
+				Buckets:  make([]bucketSnapshot, 0, buckets.len()),
+			}
+			for i := buckets.len() - 1; i >= 0; i-- {
+				p := buckets.newest(i)
+				ts.Buckets = append(ts.Buckets, bucketSnapshot{At: p.at, Count: p.count, Sum: p.sum, Min: p.min, Max: p.max})
+			}
+			ss.Tiers = append(ss.Tiers, ts)
+		}
+		snap.Series = append(snap.Series, ss)
+	}
+	return snap
+}
+
+// restore creates a series from its snapshot. The buckets of a resolution go to the finest configured resolution
+// at least as coarse, they are dropped if there is none. Rings are filled coarsest first, so that every ring
+// receives its points oldest first.
+func (m *MemoryStore) restore(ss seriesSnapshot, now time.Time) *series {
+	s := m.newSeries(ss.Metric, ss.Labels)
+
+	for i := len(ss.Tiers) - 1; i >= 0; i-- {
+		tier := m.tierOf(ss.Tiers[i].Interval)
+		for _, b := range ss.Tiers[i].Buckets {
+			if b.Count <= 0 {
+				continue
+			}
+			m.downsample(s, tier, point{at: b.At, count: b.Count, sum: b.Sum, min: b.Min, max: b.Max})
+		}
+	}
+	for _, sample := range ss.Raw {
+		if evicted, ok := s.raw.push(newPoint(sample.At, sample.Value)); ok {
+			m.downsample(s, 0, evicted)
+		}
+	}
+	s.latest = newPoint(ss.Latest.At, ss.Latest.Value)
+
+	m.expire(s, now)
+	return s
+}
+
+// tierOf returns the finest configured resolution whose interval is at least the given interval,
+// the number of resolutions if there is none
+func (m *MemoryStore) tierOf(interval time.Duration) int {
+	for i, resolution := range m.config.Downsampling {
+		if resolution.Interval >= interval {
+			return i
+		}
+	}
+	return len(m.config.Downsampling)
+}
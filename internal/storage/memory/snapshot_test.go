@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+)
+
+func restoreSnapshot(t *testing.T, from *MemoryStore, config Config) *MemoryStore {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := from.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newStore(t, config)
+	if err := store.ReadSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	original, start, now := tieredStore(t)
+	restored := restoreSnapshot(t, original, original.config)
+
+	assertHistory(t, restored, time.Time{}, 0, []wantPoint{
+		raw(now.Add(-30*time.Second), 3), raw(now.Add(-time.Minute), 1),
+		bucket(start.Add(time.Hour+10*time.Second), 12, 12, 12, 1),
+		bucket(start.Add(time.Hour), 15, 10, 20, 2),
+		bucket(start, 4, 2, 6, 3),
+	})
+
+	latest, err := restored.Latest(context.Background(), storage.Selector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 1 || latest[0].Metric != "cpu" || latest[0].Labels[storage.LabelApp] != "web" || latest[0].Points[0].Value != 3 {
+		t.Fatalf("got latest %+v", latest)
+	}
+}
+
+func TestSnapshotFitsResolutions(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   func(start, now time.Time) []wantPoint
+	}{
+		{
+			name: "coarser resolutions",
+			config: Config{
+				MaxSamples: 2,
+				MaxAge:     time.Hour,
+				Downsampling: []Resolution{
+					{Interval: time.Minute, MaxAge: 2 * time.Hour},
+					{Interval: 5 * time.Minute, MaxAge: 4 * time.Hour},
+				},
+			},
+			// Both tiers of the snapshot go to the minutely buckets, the oldest cascades into the 5 minute buckets
+			want: func(start, now time.Time) []wantPoint {
+				return []wantPoint{
+					raw(now.Add(-30*time.Second), 3), raw(now.Add(-time.Minute), 1),
+					bucket(start.Add(time.Hour), 14, 10, 20, 3),
+					bucket(start.Truncate(5*time.Minute), 4, 2, 6, 3),
+				}
+			},
+		},
+		{
+			name: "fewer raw samples",
+			config: Config{
+				MaxSamples: 1,
+				MaxAge:     time.Hour,
+				Downsampling: []Resolution{
+					{Interval: 10 * time.Second, MaxAge: 3 * time.Hour},
+				},
+			},
+			// The evicted raw sample is downsampled, the minutely buckets of the snapshot are dropped
+			want: func(start, now time.Time) []wantPoint {
+				return []wantPoint{
+					raw(now.Add(-30*time.Second), 3),
+					bucket(now.Add(-time.Minute).Truncate(10*time.Second), 1, 1, 1, 1),
+					bucket(start.Add(time.Hour+10*time.Second), 12, 12, 12, 1),
+					bucket(start.Add(time.Hour), 15, 10, 20, 2),
+				}
+			},
+		},
+		{
+			name:   "without resolutions",
+			config: Config{MaxSamples: 5, MaxAge: time.Hour},
+			want: func(start, now time.Time) []wantPoint {
+				return []wantPoint{raw(now.Add(-30*time.Second), 3), raw(now.Add(-time.Minute), 1)}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original, start, now := tieredStore(t)
+			restored := restoreSnapshot(t, original, tt.config)
+			assertHistory(t, restored, time.Time{}, 0, tt.want(start, now))
+		})
+	}
+}
+
+func TestReadSnapshotRejectsInvalid(t *testing.T) {
+	gzipped := func(content string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(content))
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	limit := maxSnapshotBytes
+	maxSnapshotBytes = 1 << 10
+	t.Cleanup(func() { maxSnapshotBytes = limit })
+
+	tests := []struct {
+		name     string
+		snapshot []byte
+		err      string
+	}{
+		{name: "not compressed", snapshot: []byte(`{"version": 1}`), err: "invalid snapshot"},
+		{name: "malformed", snapshot: gzipped(`{"version": 1, "series": [`), err: "invalid snapshot"},
+		{name: "version", snapshot: gzipped(`{"version": 2}`), err: "unsupported snapshot version 2"},
+		{name: "series without metric", snapshot: gzipped(`{"version": 1, "series": [{}]}`), err: "series without metric"},
+		{
+			name:     "too large",
+			snapshot: gzipped(`{"version": 1, "series": [` + strings.Repeat(`{"metric": "cpu"},`, 1000) + `{"metric": "cpu"}]}`),
+			err:      "exceeds 1024 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _, _ := tieredStore(t)
+
+			err := store.ReadSnapshot(bytes.NewReader(tt.snapshot))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+
+			// The store keeps its series
+			latest, err := store.Latest(context.Background(), storage.Selector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(latest) != 1 {
+				t.Fatalf("got %d series after a failed restore, want 1", len(latest))
+			}
+		})
+	}
+}
@@ -2,7 +2,7 @@ package memory
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"sort"
 	"sync"
 	"time"
@@ -10,136 +10,326 @@ import (
 	"github.com/smnzlnsk/routing-manager/internal/storage"
 )
 
-var (
-	// ErrServiceNotFound is returned when a service is not found in the store
-	ErrServiceNotFound = errors.New("service not found")
-)
+// expiryInterval is how often samples that exceeded their maximum age are downsampled or removed
+const expiryInterval = 30 * time.Second
 
-// MemoryStore implements the storage.PerformanceStore interface using in-memory storage
-type MemoryStore struct {
-	mu sync.RWMutex
-	// Map of service ID to its latest performance metric
-	latestMetrics map[string]float64
-	// Map of service ID to its historical performance metrics
-	historicalMetrics map[string][]storage.PerformanceRecord
+// Resolution is a coarser resolution older samples are downsampled into
+type Resolution struct {
+	// Interval is the width of the buckets samples are aggregated into
+	Interval time.Duration
+	// MaxAge is how long buckets are kept before they are downsampled further or removed
+	MaxAge time.Duration
 }
 
-// NewMemoryStore creates a new in-memory performance store
-func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{
-		latestMetrics:     make(map[string]float64),
-		historicalMetrics: make(map[string][]storage.PerformanceRecord),
+// Config bounds the history kept per series
+type Config struct {
+	// MaxSamples caps the number of raw samples kept per series
+	MaxSamples int
+	// MaxAge is how long raw samples are kept
+	MaxAge time.Duration
+	// Downsampling lists the resolutions samples are downsampled into once they exceed MaxSamples or MaxAge,
+	// from finest to coarsest. Without resolutions older samples are removed.
+	Downsampling []Resolution
+}
+
+// DefaultConfig keeps an hour of raw samples at 1Hz and a day of minutely buckets
+func DefaultConfig() Config {
+	return Config{
+		MaxSamples: 3600,
+		MaxAge:     time.Hour,
+		Downsampling: []Resolution{
+			{Interval: time.Minute, MaxAge: 24 * time.Hour},
+		},
 	}
 }
 
-// SaveMetric stores a performance metric for a service
-func (m *MemoryStore) SaveMetric(ctx context.Context, serviceID string, value float64) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// Validate checks the limits of the store
+func (c Config) Validate() error {
+	if c.MaxSamples <= 0 {
+		return fmt.Errorf("max samples must be positive")
+	}
+	if c.MaxAge <= 0 {
+		return fmt.Errorf("max age must be positive")
+	}
+	interval, maxAge := time.Duration(0), c.MaxAge
+	for i, resolution := range c.Downsampling {
+		if resolution.Interval <= interval {
+			return fmt.Errorf("downsampling resolution %d: interval must be greater than %s", i, interval)
+		}
+		if resolution.MaxAge <= maxAge {
+			return fmt.Errorf("downsampling resolution %d: max age must be greater than %s", i, maxAge)
+		}
+		interval, maxAge = resolution.Interval, resolution.MaxAge
+	}
+	return nil
+}
 
-	// Store the latest metric
-	m.latestMetrics[serviceID] = value
+// series holds the history of a single series, raw samples first and downsampled buckets in coarser tiers
+type series struct {
+	metric string
+	labels storage.Labels
+	latest point
+	raw    *ring
+	tiers  []*ring
+}
 
-	// Store in historical metrics
-	record := storage.PerformanceRecord{
-		ServiceID:  serviceID,
-		Value:      value,
-		RecordedAt: time.Now(),
-	}
+// MemoryStore implements the storage.MetricStore interface using in-memory storage.
+// Every series keeps its raw samples in a bounded ring buffer, older samples are downsampled
+// into coarser buckets holding their minimum, maximum and average.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	config Config
+	// Map of series key to its history
+	series map[string]*series
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
 
-	if _, exists := m.historicalMetrics[serviceID]; !exists {
-		m.historicalMetrics[serviceID] = []storage.PerformanceRecord{}
+var _ storage.MetricStore = &MemoryStore{}
+
+// NewMemoryStore creates a new in-memory metric store, it expires old samples in the background until it is closed
+func NewMemoryStore(config Config) *MemoryStore {
+	m := &MemoryStore{
+		config: config,
+		series: make(map[string]*series),
+		stop:   make(chan struct{}),
 	}
 
-	m.historicalMetrics[serviceID] = append(m.historicalMetrics[serviceID], record)
+	go m.expireLoop()
 
-	return nil
+	return m
 }
 
-// UpdateMetric updates an existing performance metric
-func (m *MemoryStore) UpdateMetric(ctx context.Context, serviceID string, value float64) error {
+// Append stores the samples. Samples older than the newest sample of their series are skipped.
+func (m *MemoryStore) Append(ctx context.Context, samples []storage.Sample) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Check if the service exists
-	if _, exists := m.latestMetrics[serviceID]; !exists {
-		return ErrServiceNotFound
+	now := time.Now()
+	skipped := 0
+	for _, sample := range samples {
+		timestamp := sample.Timestamp
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+		if !m.record(sample.Metric, sample.Labels, sample.Value, timestamp, now) {
+			skipped++
+		}
 	}
 
-	// Update the latest metric
-	m.latestMetrics[serviceID] = value
+	if skipped > 0 {
+		return fmt.Errorf("skipped %d of %d samples: %w", skipped, len(samples), storage.ErrOutOfOrder)
+	}
+	return nil
+}
 
-	// Add to historical metrics
-	record := storage.PerformanceRecord{
-		ServiceID:  serviceID,
-		Value:      value,
-		RecordedAt: time.Now(),
+// record appends a sample to its series, creating the series if needed.
+// It reports false if the sample is older than the newest sample of the series.
+func (m *MemoryStore) record(metric string, labels storage.Labels, value float64, timestamp, now time.Time) bool {
+	key := storage.SeriesKey(metric, labels)
+	s, exists := m.series[key]
+	if !exists {
+		s = m.newSeries(metric, labels)
+		m.series[key] = s
+	} else if timestamp.Before(s.latest.at) {
+		return false
 	}
 
-	m.historicalMetrics[serviceID] = append(m.historicalMetrics[serviceID], record)
+	s.latest = newPoint(timestamp, value)
+	if evicted, ok := s.raw.push(s.latest); ok {
+		m.downsample(s, 0, evicted)
+	}
+	m.expire(s, now)
+	return true
+}
 
-	return nil
+// newSeries creates an empty series with the rings of the configured resolutions
+func (m *MemoryStore) newSeries(metric string, labels storage.Labels) *series {
+	s := &series{
+		metric: metric,
+		labels: labels.Copy(),
+		raw:    newRing(m.config.MaxSamples),
+		tiers:  make([]*ring, len(m.config.Downsampling)),
+	}
+	for i, resolution := range m.config.Downsampling {
+		// Buckets are removed by age, the bound only guards against samples from the future
+		s.tiers[i] = newRing(int(resolution.MaxAge/resolution.Interval) + 2)
+	}
+	return s
 }
 
-// GetMetric retrieves the latest performance metric for a service
-func (m *MemoryStore) GetMetric(ctx context.Context, serviceID string) (float64, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// downsample folds a point evicted from the previous tier into its bucket of the given tier,
+// points beyond the coarsest tier are dropped
+func (m *MemoryStore) downsample(s *series, tier int, p point) {
+	if tier >= len(s.tiers) {
+		return
+	}
 
-	value, exists := m.latestMetrics[serviceID]
-	if !exists {
-		return 0, ErrServiceNotFound
+	buckets := s.tiers[tier]
+	p.at = p.at.Truncate(m.config.Downsampling[tier].Interval)
+	if buckets.len() > 0 {
+		if newest := buckets.newest(0); !p.at.After(newest.at) {
+			newest.merge(p)
+			return
+		}
 	}
+	if evicted, ok := buckets.push(p); ok {
+		m.downsample(s, tier+1, evicted)
+	}
+}
 
-	return value, nil
+// expire moves the points that exceeded the maximum age of their tier to the next tier
+func (m *MemoryStore) expire(s *series, now time.Time) {
+	cutoff := now.Add(-m.config.MaxAge)
+	for s.raw.len() > 0 && s.raw.oldest().at.Before(cutoff) {
+		m.downsample(s, 0, s.raw.pop())
+	}
+	for i, buckets := range s.tiers {
+		cutoff := now.Add(-m.config.Downsampling[i].MaxAge)
+		for buckets.len() > 0 && buckets.oldest().at.Before(cutoff) {
+			m.downsample(s, i+1, buckets.pop())
+		}
+	}
 }
 
-// GetMetricHistory retrieves historical performance metrics for a service
-func (m *MemoryStore) GetMetricHistory(ctx context.Context, serviceID string, since time.Time, limit int) ([]storage.PerformanceRecord, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// expireLoop periodically expires the samples of all series and removes series without samples
+func (m *MemoryStore) expireLoop() {
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
 
-	records, exists := m.historicalMetrics[serviceID]
-	if !exists {
-		return nil, ErrServiceNotFound
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for key, s := range m.series {
+				m.expire(s, now)
+				if s.empty() {
+					delete(m.series, key)
+				}
+			}
+			m.mu.Unlock()
+		}
 	}
+}
 
-	// Filter records by time
-	var filteredRecords []storage.PerformanceRecord
-	for _, record := range records {
-		if record.RecordedAt.After(since) || record.RecordedAt.Equal(since) {
-			filteredRecords = append(filteredRecords, record)
+// empty reports whether the series holds no samples
+func (s *series) empty() bool {
+	if s.raw.len() > 0 {
+		return false
+	}
+	for _, buckets := range s.tiers {
+		if buckets.len() > 0 {
+			return false
 		}
 	}
+	return true
+}
 
-	// Sort by time (newest first)
-	sort.Slice(filteredRecords, func(i, j int) bool {
-		return filteredRecords[i].RecordedAt.After(filteredRecords[j].RecordedAt)
-	})
+// selected returns the selected series ordered by their key
+func (m *MemoryStore) selected(selector storage.Selector) []*series {
+	keys := make([]string, 0)
+	for key, s := range m.series {
+		if selector.Matches(s.metric, s.labels) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
 
-	// Apply limit if specified
-	if limit > 0 && len(filteredRecords) > limit {
-		filteredRecords = filteredRecords[:limit]
+	selected := make([]*series, 0, len(keys))
+	for _, key := range keys {
+		selected = append(selected, m.series[key])
 	}
+	return selected
+}
 
-	return filteredRecords, nil
+// Latest returns the newest sample of every selected series
+func (m *MemoryStore) Latest(ctx context.Context, selector storage.Selector) ([]storage.Series, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	selected := m.selected(selector)
+	result := make([]storage.Series, 0, len(selected))
+	for _, s := range selected {
+		result = append(result, storage.Series{
+			Metric: s.metric,
+			Labels: s.labels.Copy(),
+			Points: []storage.Point{s.latest.toPoint(false)},
+		})
+	}
+
+	return result, nil
 }
 
-// GetAllMetrics retrieves the latest performance metrics for all services
-func (m *MemoryStore) GetAllMetrics(ctx context.Context) (map[string]float64, error) {
+// History returns the points of every selected series recorded since the given time, newest first.
+// Downsampled buckets follow the raw samples, they are recorded at the start of the bucket.
+func (m *MemoryStore) History(ctx context.Context, selector storage.Selector, since time.Time, limit int) ([]storage.Series, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Create a copy of the metrics map to avoid concurrent access issues
-	result := make(map[string]float64, len(m.latestMetrics))
-	for serviceID, value := range m.latestMetrics {
-		result[serviceID] = value
+	var result []storage.Series
+	for _, s := range m.selected(selector) {
+		if points := s.history(since, limit); len(points) > 0 {
+			result = append(result, storage.Series{
+				Metric: s.metric,
+				Labels: s.labels.Copy(),
+				Points: points,
+			})
+		}
 	}
 
 	return result, nil
 }
 
-// Close closes the storage connection (no-op for in-memory store)
+// Aggregate aggregates the points of the selected series recorded since the start of the window
+func (m *MemoryStore) Aggregate(ctx context.Context, query storage.AggregateQuery) ([]storage.AggregateResult, error) {
+	history, err := m.History(ctx, query.Selector, query.Since, 0)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Aggregate(history, query), nil
+}
+
+// history returns the points of the series recorded since the given time, newest first
+func (s *series) history(since time.Time, limit int) []storage.Point {
+	var points []storage.Point
+	// Points are ordered newest first from the raw samples through the coarsest tier,
+	// so the walk stops at the first point before since
+	tiers := append([]*ring{s.raw}, s.tiers...)
+	for tier, buckets := range tiers {
+		for i := 0; i < buckets.len(); i++ {
+			if limit > 0 && len(points) >= limit {
+				return points
+			}
+			p := buckets.newest(i)
+			if p.at.Before(since) {
+				return points
+			}
+			points = append(points, p.toPoint(tier > 0))
+		}
+	}
+	return points
+}
+
+// toPoint converts the point into a storage point, downsampled points carry their aggregates
+func (p *point) toPoint(downsampled bool) storage.Point {
+	converted := storage.Point{
+		Value:     p.sum / float64(p.count),
+		Timestamp: p.at,
+	}
+	if downsampled {
+		min, max := p.min, p.max
+		converted.Min, converted.Max, converted.Count = &min, &max, p.count
+	}
+	return converted
+}
+
+// Close stops the background expiry of the store
 func (m *MemoryStore) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
 	return nil
 }
@@ -0,0 +1,285 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+	"github.com/smnzlnsk/routing-manager/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.MetricStore {
+		return NewMemoryStore(DefaultConfig())
+	})
+}
+
+// wantPoint is an expected point, downsampled points carry the number of samples they hold
+type wantPoint struct {
+	at       time.Time
+	value    float64
+	min, max float64
+	count    int
+}
+
+func raw(at time.Time, value float64) wantPoint {
+	return wantPoint{at: at, value: value}
+}
+
+func bucket(at time.Time, value, min, max float64, count int) wantPoint {
+	return wantPoint{at: at, value: value, min: min, max: max, count: count}
+}
+
+func newStore(t *testing.T, config Config, samples ...storage.Sample) *MemoryStore {
+	t.Helper()
+
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryStore(config)
+	t.Cleanup(func() { store.Close() })
+
+	for i := range samples {
+		samples[i].Metric = "cpu"
+		samples[i].Labels = storage.Labels{storage.LabelApp: "web"}
+	}
+	if err := store.Append(context.Background(), samples); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func assertHistory(t *testing.T, store *MemoryStore, since time.Time, limit int, want []wantPoint) {
+	t.Helper()
+
+	history, err := store.History(context.Background(), storage.Selector{}, since, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var points []storage.Point
+	if len(history) == 1 {
+		points = history[0].Points
+	} else if len(history) > 1 {
+		t.Fatalf("got %d series, want 1", len(history))
+	}
+
+	if len(points) != len(want) {
+		t.Fatalf("got %d points %+v, want %d", len(points), points, len(want))
+	}
+	for i, p := range points {
+		w := want[i]
+		if !p.Timestamp.Equal(w.at) || math.Abs(p.Value-w.value) > 1e-9 {
+			t.Errorf("point %d: got %v at %s, want %v at %s", i, p.Value, p.Timestamp, w.value, w.at)
+		}
+		if w.count == 0 {
+			if p.Min != nil || p.Max != nil || p.Count != 0 {
+				t.Errorf("point %d: raw sample carries aggregates %+v", i, p)
+			}
+			continue
+		}
+		if p.Min == nil || p.Max == nil || *p.Min != w.min || *p.Max != w.max || p.Count != w.count {
+			t.Errorf("point %d: got %+v, want min %v, max %v and count %d", i, p, w.min, w.max, w.count)
+		}
+	}
+}
+
+func TestHistoryEvictsByCount(t *testing.T) {
+	// Raw samples are kept for a day, only the sample count evicts them into the 10s buckets
+	config := Config{
+		MaxSamples: 3,
+		MaxAge:     24 * time.Hour,
+		Downsampling: []Resolution{
+			{Interval: 10 * time.Second, MaxAge: 48 * time.Hour},
+			{Interval: time.Minute, MaxAge: 72 * time.Hour},
+		},
+	}
+	base := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	at := func(seconds int) time.Time {
+		return base.Add(time.Duration(seconds) * time.Second)
+	}
+
+	store := newStore(t, config,
+		storage.Sample{Value: 1, Timestamp: at(0)},
+		storage.Sample{Value: 3, Timestamp: at(5)},
+		storage.Sample{Value: 5, Timestamp: at(10)},
+		storage.Sample{Value: 7, Timestamp: at(12)},
+		storage.Sample{Value: 9, Timestamp: at(20)},
+		storage.Sample{Value: 11, Timestamp: at(25)},
+	)
+
+	tests := []struct {
+		name  string
+		since time.Time
+		limit int
+		want  []wantPoint
+	}{
+		{
+			name: "all",
+			want: []wantPoint{
+				raw(at(25), 11), raw(at(20), 9), raw(at(12), 7),
+				bucket(at(10), 5, 5, 5, 1),
+				bucket(at(0), 2, 1, 3, 2),
+			},
+		},
+		{
+			name:  "limit",
+			limit: 4,
+			want:  []wantPoint{raw(at(25), 11), raw(at(20), 9), raw(at(12), 7), bucket(at(10), 5, 5, 5, 1)},
+		},
+		{
+			name:  "since within raw samples",
+			since: at(13),
+			want:  []wantPoint{raw(at(25), 11), raw(at(20), 9)},
+		},
+		{
+			name:  "since excludes bucket",
+			since: at(1),
+			want:  []wantPoint{raw(at(25), 11), raw(at(20), 9), raw(at(12), 7), bucket(at(10), 5, 5, 5, 1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertHistory(t, store, tt.since, tt.limit, tt.want)
+		})
+	}
+}
+
+// tieredStore holds samples in every tier: raw samples of the last hour, 10s buckets of the last two hours
+// and minutely buckets of the last three hours. It returns the start of the oldest minutely bucket
+// and the time the raw samples were recorded relative to.
+func tieredStore(t *testing.T) (*MemoryStore, time.Time, time.Time) {
+	config := Config{
+		MaxSamples: 3,
+		MaxAge:     time.Hour,
+		Downsampling: []Resolution{
+			{Interval: 10 * time.Second, MaxAge: 2 * time.Hour},
+			{Interval: time.Minute, MaxAge: 3 * time.Hour},
+		},
+	}
+	now := time.Now()
+	start := now.Truncate(time.Minute).Add(-150 * time.Minute)
+
+	store := newStore(t, config,
+		// Older than the coarsest tier, dropped
+		storage.Sample{Value: 100, Timestamp: start.Add(-time.Hour)},
+		// Downsampled into 10s buckets and cascaded into one minutely bucket
+		storage.Sample{Value: 2, Timestamp: start},
+		storage.Sample{Value: 4, Timestamp: start.Add(5 * time.Second)},
+		storage.Sample{Value: 6, Timestamp: start.Add(20 * time.Second)},
+		// Downsampled into 10s buckets by age
+		storage.Sample{Value: 10, Timestamp: start.Add(time.Hour)},
+		storage.Sample{Value: 20, Timestamp: start.Add(time.Hour + 3*time.Second)},
+		storage.Sample{Value: 12, Timestamp: start.Add(time.Hour + 10*time.Second)},
+		// Raw samples
+		storage.Sample{Value: 1, Timestamp: now.Add(-time.Minute)},
+		storage.Sample{Value: 3, Timestamp: now.Add(-30 * time.Second)},
+	)
+	return store, start, now
+}
+
+func TestHistoryCascadesTiers(t *testing.T) {
+	store, start, now := tieredStore(t)
+	recent := []wantPoint{raw(now.Add(-30*time.Second), 3), raw(now.Add(-time.Minute), 1)}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		limit int
+		want  []wantPoint
+	}{
+		{
+			name: "all",
+			want: []wantPoint{
+				recent[0], recent[1],
+				bucket(start.Add(time.Hour+10*time.Second), 12, 12, 12, 1),
+				bucket(start.Add(time.Hour), 15, 10, 20, 2),
+				bucket(start, 4, 2, 6, 3),
+			},
+		},
+		{
+			name:  "limit across tiers",
+			limit: 3,
+			want: []wantPoint{
+				recent[0], recent[1],
+				bucket(start.Add(time.Hour+10*time.Second), 12, 12, 12, 1),
+			},
+		},
+		{
+			name:  "since cuts off the coarsest tier",
+			since: start.Add(time.Minute),
+			want: []wantPoint{
+				recent[0], recent[1],
+				bucket(start.Add(time.Hour+10*time.Second), 12, 12, 12, 1),
+				bucket(start.Add(time.Hour), 15, 10, 20, 2),
+			},
+		},
+		{
+			name:  "since cuts off the 10s tier",
+			since: start.Add(time.Hour + 5*time.Second),
+			want: []wantPoint{
+				recent[0], recent[1],
+				bucket(start.Add(time.Hour+10*time.Second), 12, 12, 12, 1),
+			},
+		},
+		{
+			name:  "since cuts off the raw samples",
+			since: recent[1].at.Add(time.Second),
+			want:  []wantPoint{recent[0]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertHistory(t, store, tt.since, tt.limit, tt.want)
+		})
+	}
+}
+
+func TestAggregateWeighsBuckets(t *testing.T) {
+	store, _, _ := tieredStore(t)
+
+	// The dropped sample is gone, the others count once each whatever tier they are in
+	samples := []float64{2, 4, 6, 10, 20, 12, 1, 3}
+	sum := 0.0
+	for _, value := range samples {
+		sum += value
+	}
+
+	tests := []struct {
+		fn   storage.AggregateFunc
+		want float64
+	}{
+		{fn: storage.AggregateAvg, want: sum / float64(len(samples))},
+		{fn: storage.AggregateMin, want: 1},
+		{fn: storage.AggregateMax, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.fn), func(t *testing.T) {
+			results, err := store.Aggregate(context.Background(), storage.AggregateQuery{Func: tt.fn})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			if math.Abs(results[0].Value-tt.want) > 1e-9 || results[0].Samples != len(samples) {
+				t.Fatalf("got %v of %d samples, want %v of %d", results[0].Value, results[0].Samples, tt.want, len(samples))
+			}
+		})
+	}
+}
+
+func TestLatestSurvivesEviction(t *testing.T) {
+	store, _, _ := tieredStore(t)
+
+	latest, err := store.Latest(context.Background(), storage.Selector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 1 || len(latest[0].Points) != 1 || latest[0].Points[0].Value != 3 {
+		t.Fatalf("got latest %+v", latest)
+	}
+}
@@ -0,0 +1,412 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// codeNamespaceExists is the MongoDB error code of creating a collection that already exists
+const codeNamespaceExists = 48
+
+// seriesMeta identifies the series of a sample, it is the meta field of the time-series collection
+type seriesMeta struct {
+	// Key is the canonical key of the series, see storage.SeriesKey
+	Key    string `bson:"key"`
+	Metric string `bson:"metric"`
+	// Labels are stored ordered by name, so that every sample of a series has the same meta field
+	Labels bson.D `bson:"labels"`
+}
+
+// sample is a single value of a series as stored in the time-series collection
+type sample struct {
+	Series     seriesMeta `bson:"series"`
+	Value      float64    `bson:"value"`
+	RecordedAt time.Time  `bson:"recordedat"`
+}
+
+// seriesDoc is a series as grouped by the aggregation pipelines
+type seriesDoc struct {
+	Key    string         `bson:"_id"`
+	Metric string         `bson:"metric"`
+	Labels storage.Labels `bson:"labels"`
+	Points []pointDoc     `bson:"points"`
+}
+
+// pointDoc is a value of a series as grouped by the aggregation pipelines
+type pointDoc struct {
+	Value      float64   `bson:"value"`
+	RecordedAt time.Time `bson:"recordedat"`
+}
+
+// MongoStore implements the storage.MetricStore interface using a MongoDB time-series collection.
+// Samples older than the retention period are removed by MongoDB.
+type MongoStore struct {
+	collection *mongo.Collection
+	logger     *zap.Logger
+}
+
+var _ storage.MetricStore = &MongoStore{}
+
+// NewMongoStore creates a performance store backed by the time-series collection, which is created if it does not exist
+func NewMongoStore(db *mongo.Database, collection string, retention time.Duration, logger *zap.Logger) (*MongoStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := ensureTimeSeries(ctx, db, collection, retention); err != nil {
+		return nil, err
+	}
+
+	coll := db.Collection(collection)
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "series.key", Value: 1}, {Key: "recordedat", Value: -1}},
+	})
+	if err != nil {
+		logger.Error("Failed to create metric indexes", zap.Error(err))
+	}
+
+	return &MongoStore{
+		collection: coll,
+		logger:     logger,
+	}, nil
+}
+
+// ensureTimeSeries creates the time-series collection, or adapts its expiry to a changed retention period
+func ensureTimeSeries(ctx context.Context, db *mongo.Database, collection string, retention time.Duration) error {
+	expireAfter := int64(retention / time.Second)
+	err := db.CreateCollection(ctx, collection, options.CreateCollection().
+		SetTimeSeriesOptions(options.TimeSeries().
+			SetTimeField("recordedat").
+			SetMetaField("series").
+			SetGranularity("seconds")).
+		SetExpireAfterSeconds(expireAfter))
+	if err == nil {
+		return nil
+	}
+
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Code != codeNamespaceExists {
+		return fmt.Errorf("failed to create metrics collection: %w", err)
+	}
+
+	err = db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "expireAfterSeconds", Value: expireAfter},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to set retention of metrics collection: %w", err)
+	}
+	return nil
+}
+
+// Append stores the samples, samples are accepted in any order
+func (m *MongoStore) Append(ctx context.Context, samples []storage.Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, 0, len(samples))
+	for _, s := range samples {
+		recordedAt := s.Timestamp
+		if recordedAt.IsZero() {
+			recordedAt = now
+		}
+		labels := bson.D{}
+		for _, name := range s.Labels.Names() {
+			labels = append(labels, bson.E{Key: name, Value: s.Labels[name]})
+		}
+		docs = append(docs, sample{
+			Series: seriesMeta{
+				Key:    storage.SeriesKey(s.Metric, s.Labels),
+				Metric: s.Metric,
+				Labels: labels,
+			},
+			Value:      s.Value,
+			RecordedAt: recordedAt,
+		})
+	}
+
+	_, err := m.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// Latest returns the newest sample of every selected series
+func (m *MongoStore) Latest(ctx context.Context, selector storage.Selector) ([]storage.Series, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: selectorFilter(selector)}},
+		{{Key: "$sort", Value: bson.D{{Key: "series.key", Value: 1}, {Key: "recordedat", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$series.key"},
+			{Key: "metric", Value: bson.M{"$first": "$series.metric"}},
+			{Key: "labels", Value: bson.M{"$first": "$series.labels"}},
+			{Key: "value", Value: bson.M{"$first": "$value"}},
+			{Key: "recordedat", Value: bson.M{"$first": "$recordedat"}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "metric", Value: 1},
+			{Key: "labels", Value: 1},
+			{Key: "points", Value: bson.A{bson.M{"value": "$value", "recordedat": "$recordedat"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	return m.aggregate(ctx, pipeline, selector)
+}
+
+// History returns the points of every selected series recorded since the given time, newest first.
+// Limited histories keep only the newest points of every series while grouping, full histories
+// are read sample by sample, so that no series has to fit into a single document.
+func (m *MongoStore) History(ctx context.Context, selector storage.Selector, since time.Time, limit int) ([]storage.Series, error) {
+	filter := selectorFilter(selector)
+	filter["recordedat"] = bson.M{"$gte": since}
+
+	if limit <= 0 {
+		return m.find(ctx, filter, selector)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$series.key"},
+			{Key: "metric", Value: bson.M{"$first": "$series.metric"}},
+			{Key: "labels", Value: bson.M{"$first": "$series.labels"}},
+			{Key: "points", Value: bson.M{"$topN": bson.M{
+				"n":      limit,
+				"sortBy": bson.D{{Key: "recordedat", Value: -1}},
+				"output": bson.M{"value": "$value", "recordedat": "$recordedat"},
+			}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	return m.aggregate(ctx, pipeline, selector)
+}
+
+// Aggregate aggregates the points of the selected series recorded since the start of the window.
+// Averages, minimums and maximums are computed by MongoDB, the other functions need the ordered points
+// and are computed from the history of the series.
+func (m *MongoStore) Aggregate(ctx context.Context, query storage.AggregateQuery) ([]storage.AggregateResult, error) {
+	operator := ""
+	switch query.Func {
+	case storage.AggregateAvg:
+		operator = "$avg"
+	case storage.AggregateMin:
+		operator = "$min"
+	case storage.AggregateMax:
+		operator = "$max"
+	}
+	// Regular expressions are applied again to the grouped series, which is not possible once aggregated
+	for _, matcher := range query.Selector.Matchers {
+		if matcher.Type == storage.MatchRegexp || matcher.Type == storage.MatchNotRegexp {
+			operator = ""
+		}
+	}
+	if operator == "" {
+		history, err := m.History(ctx, query.Selector, query.Since, 0)
+		if err != nil {
+			return nil, err
+		}
+		return storage.Aggregate(history, query), nil
+	}
+
+	filter := selectorFilter(query.Selector)
+	filter["recordedat"] = bson.M{"$gte": query.Since}
+
+	var groupID interface{} = "$series.key"
+	if len(query.GroupBy) > 0 {
+		id := bson.D{{Key: "metric", Value: "$series.metric"}}
+		for _, name := range query.GroupBy {
+			id = append(id, bson.E{Key: "label_" + name, Value: "$series.labels." + name})
+		}
+		groupID = id
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: groupID},
+			{Key: "metric", Value: bson.M{"$first": "$series.metric"}},
+			{Key: "labels", Value: bson.M{"$first": "$series.labels"}},
+			{Key: "value", Value: bson.M{operator: "$value"}},
+			{Key: "samples", Value: bson.M{"$sum": 1}},
+		}}},
+	}
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		Metric  string         `bson:"metric"`
+		Labels  storage.Labels `bson:"labels"`
+		Value   float64        `bson:"value"`
+		Samples int            `bson:"samples"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	results := make([]storage.AggregateResult, 0, len(docs))
+	for _, doc := range docs {
+		results = append(results, storage.AggregateResult{
+			Metric:  doc.Metric,
+			Labels:  query.GroupLabels(doc.Labels),
+			Value:   doc.Value,
+			Samples: doc.Samples,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return storage.SeriesKey(results[i].Metric, results[i].Labels) < storage.SeriesKey(results[j].Metric, results[j].Labels)
+	})
+	return results, nil
+}
+
+// aggregate runs a pipeline grouping samples into series. The selector is applied again to the results,
+// as MongoDB and Go regular expressions differ in details.
+func (m *MongoStore) aggregate(ctx context.Context, pipeline mongo.Pipeline, selector storage.Selector) ([]storage.Series, error) {
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []seriesDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	result := make([]storage.Series, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Labels == nil {
+			doc.Labels = storage.Labels{}
+		}
+		if !selector.Matches(doc.Metric, doc.Labels) {
+			continue
+		}
+		series := storage.Series{
+			Metric: doc.Metric,
+			Labels: doc.Labels,
+			Points: make([]storage.Point, 0, len(doc.Points)),
+		}
+		for _, p := range doc.Points {
+			series.Points = append(series.Points, storage.Point{Value: p.Value, Timestamp: p.RecordedAt})
+		}
+		result = append(result, series)
+	}
+	return result, nil
+}
+
+// find reads the samples matching the filter ordered by series and newest first, and groups them into series.
+// The selector is applied again to the results, as MongoDB and Go regular expressions differ in details.
+func (m *MongoStore) find(ctx context.Context, filter bson.M, selector storage.Selector) ([]storage.Series, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "series.key", Value: 1}, {Key: "recordedat", Value: -1}}).
+		SetAllowDiskUse(true)
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := []storage.Series{}
+	started, key, skip := false, "", false
+	for cursor.Next(ctx) {
+		var doc struct {
+			Series struct {
+				Key    string         `bson:"key"`
+				Metric string         `bson:"metric"`
+				Labels storage.Labels `bson:"labels"`
+			} `bson:"series"`
+			Value      float64   `bson:"value"`
+			RecordedAt time.Time `bson:"recordedat"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		if !started || doc.Series.Key != key {
+			started, key = true, doc.Series.Key
+			if doc.Series.Labels == nil {
+				doc.Series.Labels = storage.Labels{}
+			}
+			skip = !selector.Matches(doc.Series.Metric, doc.Series.Labels)
+			if !skip {
+				result = append(result, storage.Series{Metric: doc.Series.Metric, Labels: doc.Series.Labels})
+			}
+		}
+		if skip {
+			continue
+		}
+		series := &result[len(result)-1]
+		series.Points = append(series.Points, storage.Point{Value: doc.Value, Timestamp: doc.RecordedAt})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// selectorFilter translates the selector into a filter on the meta field, labels are never stored with empty values
+func selectorFilter(selector storage.Selector) bson.M {
+	filter := bson.M{}
+	if selector.Metric != "" {
+		filter["series.metric"] = selector.Metric
+	}
+
+	clauses := bson.A{}
+	for _, matcher := range selector.Matchers {
+		field := "series.labels." + matcher.Name
+		// An absent label has the empty value
+		matchesEmpty := matcher.Matches(storage.Labels{})
+		switch matcher.Type {
+		case storage.MatchEqual, storage.MatchNotEqual:
+			if matchesEmpty && matcher.Value != "" {
+				clauses = append(clauses, bson.M{field: bson.M{"$ne": matcher.Value}})
+			} else if matchesEmpty {
+				clauses = append(clauses, bson.M{field: bson.M{"$exists": false}})
+			} else if matcher.Value == "" {
+				clauses = append(clauses, bson.M{field: bson.M{"$exists": true}})
+			} else {
+				clauses = append(clauses, bson.M{field: matcher.Value})
+			}
+		case storage.MatchRegexp:
+			regex := primitive.Regex{Pattern: matcher.Pattern()}
+			if matchesEmpty {
+				clauses = append(clauses, bson.M{"$or": bson.A{
+					bson.M{field: regex},
+					bson.M{field: bson.M{"$exists": false}},
+				}})
+			} else {
+				clauses = append(clauses, bson.M{field: regex})
+			}
+		case storage.MatchNotRegexp:
+			regex := primitive.Regex{Pattern: matcher.Pattern()}
+			if matchesEmpty {
+				clauses = append(clauses, bson.M{field: bson.M{"$exists": true, "$not": regex}})
+			} else {
+				clauses = append(clauses, bson.M{field: bson.M{"$not": regex}})
+			}
+		}
+	}
+	if len(clauses) > 0 {
+		filter["$and"] = clauses
+	}
+	return filter
+}
+
+// Close is a no-op, the MongoDB client is owned by the caller
+func (m *MongoStore) Close() error {
+	return nil
+}
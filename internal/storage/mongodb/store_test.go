@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+	"github.com/smnzlnsk/routing-manager/internal/storage/storagetest"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// testURIEnv names the environment variable holding the URI of the MongoDB deployment the tests run against,
+// the tests are skipped if it is not set. Time-series collections require MongoDB 5.0 or later.
+const testURIEnv = "MONGODB_TEST_URI"
+
+func TestConformance(t *testing.T) {
+	uri := os.Getenv(testURIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set", testURIEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	// Every run uses a database of its own, which is dropped afterwards
+	db := client.Database(fmt.Sprintf("storagetest_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.Drop(ctx); err != nil {
+			t.Errorf("Drop: %v", err)
+		}
+		_ = client.Disconnect(ctx)
+	})
+
+	collections := 0
+	storagetest.Run(t, func(t *testing.T) storage.MetricStore {
+		collections++
+		store, err := NewMongoStore(db, fmt.Sprintf("metrics_%d", collections), time.Hour, zap.NewNop())
+		if err != nil {
+			t.Fatalf("NewMongoStore: %v", err)
+		}
+		return store
+	})
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultMetric is the metric of series stored under a bare service ID
+const DefaultMetric = "performance"
+
+// SeriesID returns the ID under which a metric of a service is stored
+func SeriesID(serviceID, metric string) string {
+	if metric == "" || metric == DefaultMetric {
+		return serviceID
+	}
+	return serviceID + "/" + metric
+}
+
+// ParseSeriesID splits a series ID into the service ID and the metric
+func ParseSeriesID(seriesID string) (serviceID, metric string) {
+	serviceID, metric, found := strings.Cut(seriesID, "/")
+	if !found {
+		return seriesID, DefaultMetric
+	}
+	return serviceID, metric
+}
+
+// ServiceID returns the service ID of an app, or of a single instance of the app if the instance is set
+func ServiceID(appName string, instance *int) string {
+	if instance == nil {
+		return appName
+	}
+	return appName + ":" + strconv.Itoa(*instance)
+}
+
+// ParseServiceID splits a service ID into the app name and the instance number, which is nil for the whole app
+func ParseServiceID(serviceID string) (appName string, instance *int) {
+	appName, number, found := strings.Cut(serviceID, ":")
+	if !found {
+		return serviceID, nil
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return serviceID, nil
+	}
+	return appName, &n
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Snapshotter is implemented by stores keeping their metrics in memory, snapshots carry them over restarts
+type Snapshotter interface {
+	// WriteSnapshot writes all series to the writer
+	WriteSnapshot(w io.Writer) error
+	// ReadSnapshot replaces all series with those of a snapshot written by WriteSnapshot
+	ReadSnapshot(r io.Reader) error
+}
+
+// SaveSnapshot writes a snapshot of the store to the file at the path. The file is replaced atomically,
+// a failed snapshot leaves the previous one intact.
+func SaveSnapshot(store Snapshotter, path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := store.WriteSnapshot(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// LoadSnapshot restores the store from the snapshot file at the path
+func LoadSnapshot(store Snapshotter, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return store.ReadSnapshot(file)
+}
@@ -0,0 +1,438 @@
+// Package storagetest provides a conformance suite for the implementations of storage.MetricStore.
+// Every backend must produce identical results for the same samples, run the suite from the tests
+// of a backend with a constructor returning an empty store.
+package storagetest
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/smnzlnsk/routing-manager/internal/storage"
+)
+
+// tolerance is the maximum difference of aggregated values, backends may sum in a different order
+const tolerance = 1e-9
+
+// fixture is a sample of the series appended by every test, relative to the start of the test
+type fixture struct {
+	metric string
+	labels storage.Labels
+	value  float64
+	age    time.Duration
+}
+
+// Series of the fixture, the series of db has no instance label
+var (
+	webZero = storage.Labels{storage.LabelApp: "web", storage.LabelInstance: "0", storage.LabelNode: "n1"}
+	webOne  = storage.Labels{storage.LabelApp: "web", storage.LabelInstance: "1", storage.LabelNode: "n2"}
+	db      = storage.Labels{storage.LabelApp: "db", storage.LabelNode: "n1"}
+	memory  = storage.Labels{storage.LabelApp: "web", storage.LabelInstance: "0"}
+)
+
+// fixtures are ordered oldest first, no two samples share a timestamp
+var fixtures = []fixture{
+	{"cpu", webZero, 1, 50 * time.Minute},
+	{"cpu", webOne, 10, 45 * time.Minute},
+	{"cpu", webZero, 2, 40 * time.Minute},
+	{"cpu", webOne, 20, 35 * time.Minute},
+	{"cpu", webZero, 3, 30 * time.Minute},
+	{"cpu", webOne, 30, 25 * time.Minute},
+	{"cpu", webZero, 4, 20 * time.Minute},
+	{"cpu", db, 7, 15 * time.Minute},
+	{"cpu", webZero, 5, 10 * time.Minute},
+	{"cpu", db, 9, 5 * time.Minute},
+	{"memory", memory, 100, 4 * time.Minute},
+}
+
+// Run runs the conformance suite, newStore must return an empty store which is closed by the suite
+func Run(t *testing.T, newStore func(t *testing.T) storage.MetricStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store storage.MetricStore, now time.Time)
+	}{
+		{"Latest", testLatest},
+		{"History", testHistory},
+		{"Matchers", testMatchers},
+		{"Aggregate", testAggregate},
+		{"AggregateWindow", testAggregateWindow},
+		{"AggregateGroupBy", testAggregateGroupBy},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+
+			now := time.Now().Truncate(time.Millisecond)
+			samples := make([]storage.Sample, 0, len(fixtures))
+			for _, f := range fixtures {
+				samples = append(samples, storage.Sample{
+					Metric:    f.metric,
+					Labels:    f.labels.Copy(),
+					Value:     f.value,
+					Timestamp: now.Add(-f.age),
+				})
+			}
+			if err := store.Append(context.Background(), samples); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+
+			test.run(t, store, now)
+		})
+	}
+}
+
+func testLatest(t *testing.T, store storage.MetricStore, now time.Time) {
+	series, err := store.Latest(context.Background(), storage.Selector{Metric: "cpu"})
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+
+	expected := []struct {
+		labels storage.Labels
+		value  float64
+		age    time.Duration
+	}{
+		{db, 9, 5 * time.Minute},
+		{webZero, 5, 10 * time.Minute},
+		{webOne, 30, 25 * time.Minute},
+	}
+	if len(series) != len(expected) {
+		t.Fatalf("Latest returned %d series, expected %d", len(series), len(expected))
+	}
+	for i, e := range expected {
+		s := series[i]
+		if s.Metric != "cpu" || !s.Labels.Equal(e.labels) {
+			t.Errorf("series %d is %s, expected %s", i, storage.SeriesKey(s.Metric, s.Labels), storage.SeriesKey("cpu", e.labels))
+			continue
+		}
+		if len(s.Points) != 1 {
+			t.Errorf("series %s has %d points, expected 1", storage.SeriesKey(s.Metric, s.Labels), len(s.Points))
+			continue
+		}
+		if s.Points[0].Value != e.value || !s.Points[0].Timestamp.Equal(now.Add(-e.age)) {
+			t.Errorf("series %s has latest point %v at %v, expected %v at %v",
+				storage.SeriesKey(s.Metric, s.Labels), s.Points[0].Value, s.Points[0].Timestamp, e.value, now.Add(-e.age))
+		}
+	}
+
+	all, err := store.Latest(context.Background(), storage.Selector{})
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Latest of every metric returned %d series, expected 4", len(all))
+	}
+}
+
+func testHistory(t *testing.T, store storage.MetricStore, now time.Time) {
+	selector := storage.ExactSelector("cpu", webZero)
+
+	tests := []struct {
+		name   string
+		since  time.Duration
+		limit  int
+		values []float64
+	}{
+		{"all", time.Hour, 0, []float64{5, 4, 3, 2, 1}},
+		{"since", 35 * time.Minute, 0, []float64{5, 4, 3}},
+		{"limit", time.Hour, 2, []float64{5, 4}},
+		{"since and limit", 35 * time.Minute, 10, []float64{5, 4, 3}},
+	}
+	for _, test := range tests {
+		series, err := store.History(context.Background(), selector, now.Add(-test.since), test.limit)
+		if err != nil {
+			t.Fatalf("History %s: %v", test.name, err)
+		}
+		if len(series) != 1 {
+			t.Errorf("History %s returned %d series, expected 1", test.name, len(series))
+			continue
+		}
+		assertValues(t, "History "+test.name, series[0].Points, test.values)
+	}
+
+	series, err := store.History(context.Background(), selector, now.Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(series) != 0 {
+		t.Errorf("History without points in the range returned %d series, expected none", len(series))
+	}
+}
+
+func testMatchers(t *testing.T, store storage.MetricStore, now time.Time) {
+	tests := []struct {
+		matchers []string
+		expected []storage.Labels
+	}{
+		{[]string{`app=web`}, []storage.Labels{webZero, webOne}},
+		{[]string{`app!=web`}, []storage.Labels{db}},
+		{[]string{`instance=`}, []storage.Labels{db}},
+		{[]string{`instance!=`}, []storage.Labels{webZero, webOne}},
+		{[]string{`instance!=0`}, []storage.Labels{db, webOne}},
+		{[]string{`app=~w.*`}, []storage.Labels{webZero, webOne}},
+		{[]string{`app=~w`}, nil},
+		{[]string{`instance=~1|`}, []storage.Labels{db, webOne}},
+		{[]string{`node!~n1`}, []storage.Labels{webOne}},
+		{[]string{`app=web`, `node=n1`}, []storage.Labels{webZero}},
+	}
+	for _, test := range tests {
+		selector := storage.Selector{Metric: "cpu"}
+		for _, s := range test.matchers {
+			matcher, err := storage.ParseMatcher(s)
+			if err != nil {
+				t.Fatalf("ParseMatcher %s: %v", s, err)
+			}
+			selector.Matchers = append(selector.Matchers, matcher)
+		}
+
+		latest, err := store.Latest(context.Background(), selector)
+		if err != nil {
+			t.Fatalf("Latest %v: %v", test.matchers, err)
+		}
+		assertSeries(t, "Latest", test.matchers, latest, test.expected)
+
+		history, err := store.History(context.Background(), selector, now.Add(-time.Hour), 0)
+		if err != nil {
+			t.Fatalf("History %v: %v", test.matchers, err)
+		}
+		assertSeries(t, "History", test.matchers, history, test.expected)
+	}
+}
+
+// aggregateCase is the expected result of every aggregate function for a series or group
+type aggregateCase struct {
+	labels  storage.Labels
+	samples int
+	values  map[storage.AggregateFunc]float64
+}
+
+func testAggregate(t *testing.T, store storage.MetricStore, now time.Time) {
+	expected := []aggregateCase{
+		{db, 2, map[storage.AggregateFunc]float64{
+			storage.AggregateAvg:  8,
+			storage.AggregateMin:  7,
+			storage.AggregateMax:  9,
+			storage.AggregateP50:  8,
+			storage.AggregateP90:  8.8,
+			storage.AggregateP99:  8.98,
+			storage.AggregateRate: 2.0 / 600,
+			storage.AggregateEWMA: 8,
+		}},
+		{webZero, 5, map[storage.AggregateFunc]float64{
+			storage.AggregateAvg:  3,
+			storage.AggregateMin:  1,
+			storage.AggregateMax:  5,
+			storage.AggregateP50:  3,
+			storage.AggregateP90:  4.6,
+			storage.AggregateP99:  4.96,
+			storage.AggregateRate: 4.0 / 2400,
+			storage.AggregateEWMA: 4.0625,
+		}},
+		{webOne, 3, map[storage.AggregateFunc]float64{
+			storage.AggregateAvg:  20,
+			storage.AggregateMin:  10,
+			storage.AggregateMax:  30,
+			storage.AggregateP50:  20,
+			storage.AggregateP90:  28,
+			storage.AggregateP99:  29.8,
+			storage.AggregateRate: 20.0 / 1200,
+			storage.AggregateEWMA: 22.5,
+		}},
+	}
+	assertAggregates(t, store, storage.AggregateQuery{
+		Selector: storage.Selector{Metric: "cpu"},
+		Since:    now.Add(-time.Hour),
+	}, expected)
+
+	// The smoothing factor weighs the newest point
+	results, err := store.Aggregate(context.Background(), storage.AggregateQuery{
+		Selector: storage.ExactSelector("cpu", db),
+		Func:     storage.AggregateEWMA,
+		Since:    now.Add(-time.Hour),
+		Alpha:    0.25,
+	})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if len(results) != 1 || !equal(results[0].Value, 7.5) {
+		t.Errorf("Aggregate ewma with alpha 0.25 returned %+v, expected 7.5", results)
+	}
+}
+
+func testAggregateWindow(t *testing.T, store storage.MetricStore, now time.Time) {
+	// The series of web instance 1 has no points in the window and is omitted,
+	// the rate of a single point is not defined
+	expected := []aggregateCase{
+		{db, 2, map[storage.AggregateFunc]float64{
+			storage.AggregateAvg:  8,
+			storage.AggregateMax:  9,
+			storage.AggregateRate: 2.0 / 600,
+		}},
+		{webZero, 2, map[storage.AggregateFunc]float64{
+			storage.AggregateAvg:  4.5,
+			storage.AggregateMax:  5,
+			storage.AggregateRate: 1.0 / 600,
+		}},
+	}
+	assertAggregates(t, store, storage.AggregateQuery{
+		Selector: storage.Selector{Metric: "cpu"},
+		Since:    now.Add(-22 * time.Minute),
+	}, expected)
+
+	results, err := store.Aggregate(context.Background(), storage.AggregateQuery{
+		Selector: storage.Selector{Metric: "memory"},
+		Func:     storage.AggregateRate,
+		Since:    now.Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Aggregate rate of a single point returned %+v, expected none", results)
+	}
+}
+
+func testAggregateGroupBy(t *testing.T, store storage.MetricStore, now time.Time) {
+	// The points of the web instances are aggregated together, their rates are summed
+	expected := []aggregateCase{
+		{storage.Labels{storage.LabelApp: "db"}, 2, map[storage.AggregateFunc]float64{
+			storage.AggregateAvg:  8,
+			storage.AggregateMin:  7,
+			storage.AggregateMax:  9,
+			storage.AggregateP50:  8,
+			storage.AggregateP90:  8.8,
+			storage.AggregateP99:  8.98,
+			storage.AggregateRate: 2.0 / 600,
+			storage.AggregateEWMA: 8,
+		}},
+		{storage.Labels{storage.LabelApp: "web"}, 8, map[storage.AggregateFunc]float64{
+			storage.AggregateAvg:  9.375,
+			storage.AggregateMin:  1,
+			storage.AggregateMax:  30,
+			storage.AggregateP50:  4.5,
+			storage.AggregateP90:  23,
+			storage.AggregateP99:  29.3,
+			storage.AggregateRate: 4.0/2400 + 20.0/1200,
+			storage.AggregateEWMA: 8.1796875,
+		}},
+	}
+	assertAggregates(t, store, storage.AggregateQuery{
+		Selector: storage.Selector{Metric: "cpu"},
+		Since:    now.Add(-time.Hour),
+		GroupBy:  []string{storage.LabelApp},
+	}, expected)
+
+	// Series without the grouping label form a group without labels, which is ordered last by its key
+	expected = []aggregateCase{
+		{storage.Labels{storage.LabelInstance: "0"}, 5, map[storage.AggregateFunc]float64{storage.AggregateAvg: 3}},
+		{storage.Labels{storage.LabelInstance: "1"}, 3, map[storage.AggregateFunc]float64{storage.AggregateAvg: 20}},
+		{storage.Labels{}, 2, map[storage.AggregateFunc]float64{storage.AggregateAvg: 8}},
+	}
+	assertAggregates(t, store, storage.AggregateQuery{
+		Selector: storage.Selector{Metric: "cpu"},
+		Since:    now.Add(-time.Hour),
+		GroupBy:  []string{storage.LabelInstance},
+	}, expected)
+
+	// Grouping applies after the selector, including regular expressions
+	matcher, err := storage.NewMatcher(storage.LabelNode, storage.MatchRegexp, "n1")
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	expected = []aggregateCase{
+		{storage.Labels{storage.LabelNode: "n1"}, 7, map[storage.AggregateFunc]float64{
+			storage.AggregateAvg: 31.0 / 7,
+			storage.AggregateMax: 9,
+		}},
+	}
+	assertAggregates(t, store, storage.AggregateQuery{
+		Selector: storage.Selector{Metric: "cpu", Matchers: []*storage.Matcher{matcher}},
+		Since:    now.Add(-time.Hour),
+		GroupBy:  []string{storage.LabelNode},
+	}, expected)
+}
+
+// assertAggregates runs the query with every function of the expected cases,
+// a case without a value for a function is expected to be omitted from its results
+func assertAggregates(t *testing.T, store storage.MetricStore, query storage.AggregateQuery, expected []aggregateCase) {
+	t.Helper()
+
+	funcs := make(map[storage.AggregateFunc]bool)
+	for _, e := range expected {
+		for fn := range e.values {
+			funcs[fn] = true
+		}
+	}
+
+	for fn := range funcs {
+		query.Func = fn
+		results, err := store.Aggregate(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Aggregate %s: %v", fn, err)
+		}
+
+		var cases []aggregateCase
+		for _, e := range expected {
+			if _, ok := e.values[fn]; ok {
+				cases = append(cases, e)
+			}
+		}
+		if len(results) != len(cases) {
+			t.Errorf("Aggregate %s returned %d results, expected %d: %+v", fn, len(results), len(cases), results)
+			continue
+		}
+		for i, e := range cases {
+			r := results[i]
+			if r.Metric != query.Selector.Metric || !r.Labels.Equal(e.labels) {
+				t.Errorf("Aggregate %s result %d is %s, expected %s",
+					fn, i, storage.SeriesKey(r.Metric, r.Labels), storage.SeriesKey(query.Selector.Metric, e.labels))
+				continue
+			}
+			if !equal(r.Value, e.values[fn]) {
+				t.Errorf("Aggregate %s of %s is %v, expected %v", fn, storage.SeriesKey(r.Metric, r.Labels), r.Value, e.values[fn])
+			}
+			if r.Samples != e.samples {
+				t.Errorf("Aggregate %s of %s aggregated %d samples, expected %d", fn, storage.SeriesKey(r.Metric, r.Labels), r.Samples, e.samples)
+			}
+		}
+	}
+}
+
+// assertSeries checks that the series have the expected labels in order
+func assertSeries(t *testing.T, method string, matchers []string, series []storage.Series, expected []storage.Labels) {
+	t.Helper()
+
+	if len(series) != len(expected) {
+		t.Errorf("%s %v returned %d series, expected %d", method, matchers, len(series), len(expected))
+		return
+	}
+	for i, labels := range expected {
+		if !series[i].Labels.Equal(labels) {
+			t.Errorf("%s %v series %d is %s, expected %s",
+				method, matchers, i, storage.SeriesKey(series[i].Metric, series[i].Labels), storage.SeriesKey("cpu", labels))
+		}
+	}
+}
+
+// assertValues checks the values of the points in order
+func assertValues(t *testing.T, name string, points []storage.Point, values []float64) {
+	t.Helper()
+
+	if len(points) != len(values) {
+		t.Errorf("%s returned %d points, expected %d", name, len(points), len(values))
+		return
+	}
+	for i, value := range values {
+		if points[i].Value != value {
+			t.Errorf("%s point %d is %v, expected %v", name, i, points[i].Value, value)
+		}
+	}
+}
+
+func equal(a, b float64) bool {
+	return math.Abs(a-b) <= tolerance
+}